@@ -0,0 +1,350 @@
+package gorbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// AuditOperation 审计记录的操作类型
+type AuditOperation string
+
+const (
+	AuditAddItem              AuditOperation = "item.add"
+	AuditUpdateItem           AuditOperation = "item.update"
+	AuditRemoveItem           AuditOperation = "item.remove"
+	AuditAddRule              AuditOperation = "rule.add"
+	AuditUpdateRule           AuditOperation = "rule.update"
+	AuditRemoveRule           AuditOperation = "rule.remove"
+	AuditAddChild             AuditOperation = "child.add"
+	AuditRemoveChild          AuditOperation = "child.remove"
+	AuditRemoveChildren       AuditOperation = "child.remove_all"
+	AuditAssign               AuditOperation = "assignment.add"
+	AuditRevoke               AuditOperation = "assignment.remove"
+	AuditRevokeAll            AuditOperation = "assignment.remove_user"
+	AuditRemoveAll            AuditOperation = "all.remove"
+	AuditRemoveAllRoles       AuditOperation = "role.remove_all"
+	AuditRemoveAllPermissions AuditOperation = "permission.remove_all"
+	AuditRemoveAllRules       AuditOperation = "rule.remove_all"
+	AuditRemoveAllAssignments AuditOperation = "assignment.remove_all"
+	AuditSetDefaultRoles      AuditOperation = "default_roles.set"
+	AuditApplyPolicy          AuditOperation = "policy.apply"
+	AuditImportSnapshot       AuditOperation = "snapshot.import"
+	AuditImportYii2           AuditOperation = "yii2.import"
+	AuditLintFix              AuditOperation = "lint.fix"
+)
+
+// AuditEntry 一次变更的审计记录
+type AuditEntry struct {
+	// Id 由 SQLAuditSink 查询时填充
+	Id        int64          `json:"id,omitempty"`
+	Time      time.Time      `json:"time"`
+	Actor     string         `json:"actor,omitempty"`
+	Operation AuditOperation `json:"operation"`
+	// Item 变更的 item 或规则名，继承关系中为父级
+	Item  string `json:"item,omitempty"`
+	Child string `json:"child,omitempty"`
+	// UserId 分配相关操作的用户 ID
+	UserId interface{} `json:"user_id,omitempty"`
+	// Before 与 After 变更前后的状态（JSON），新增时没有 Before，删除时没有 After
+	Before   json.RawMessage   `json:"before,omitempty"`
+	After    json.RawMessage   `json:"after,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// AuditSink 审计记录的写入目标，需并发安全
+type AuditSink interface {
+	Write(entry *AuditEntry) error
+}
+
+// AuditQuerier 支持查询的 AuditSink
+type AuditQuerier interface {
+	Query(filter AuditFilter) ([]*AuditEntry, error)
+}
+
+// AuditFilter 审计查询条件，零值字段不参与过滤，结果按时间倒序
+type AuditFilter struct {
+	Actor string
+	// Item 匹配 Item 或 Child
+	Item      string
+	UserId    interface{}
+	Operation AuditOperation
+	Since     time.Time
+	Until     time.Time
+	// Limit 最多返回的条数，0 表示不限制
+	Limit int
+}
+
+// Match 判断记录是否满足条件
+func (filter AuditFilter) Match(entry *AuditEntry) bool {
+	switch {
+	case filter.Actor != "" && entry.Actor != filter.Actor:
+		return false
+	case filter.Item != "" && entry.Item != filter.Item && entry.Child != filter.Item:
+		return false
+	case filter.UserId != nil && (entry.UserId == nil || fmt.Sprint(entry.UserId) != fmt.Sprint(filter.UserId)):
+		return false
+	case filter.Operation != "" && entry.Operation != filter.Operation:
+		return false
+	case !filter.Since.IsZero() && entry.Time.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !entry.Time.Before(filter.Until):
+		return false
+	}
+	return true
+}
+
+// ---------------------- Context ---------------------------
+
+type auditActorKey struct{}
+
+type auditMetadataKey struct{}
+
+// WithActor 设置审计记录的操作人
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// ActorFromContext 返回 WithActor 设置的操作人，未设置时返回空字符串
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+// WithAuditMetadata 为审计记录附加请求元数据（如请求 ID、来源地址），与 ctx 上已有的元数据合并
+func WithAuditMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	merged := make(map[string]string)
+	for key, value := range AuditMetadataFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, auditMetadataKey{}, merged)
+}
+
+// AuditMetadataFromContext 返回 WithAuditMetadata 附加的元数据，未附加时返回 nil
+func AuditMetadataFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	metadata, _ := ctx.Value(auditMetadataKey{}).(map[string]string)
+	return metadata
+}
+
+// ---------------------- Manager ---------------------------
+
+type auditHolder struct {
+	sink AuditSink
+}
+
+// SetAuditSink 设置审计记录的写入目标，nil 关闭审计。
+// 写入失败只记录日志，不影响变更结果。
+func (manager *DefaultManager) SetAuditSink(sink AuditSink) {
+	manager.auditSink.Store(&auditHolder{sink: sink})
+}
+
+func (manager *DefaultManager) getAuditSink() AuditSink {
+	if holder, ok := manager.auditSink.Load().(*auditHolder); ok {
+		return holder.sink
+	}
+	return nil
+}
+
+func (manager *DefaultManager) auditing() bool {
+	return manager.getAuditSink() != nil
+}
+
+// auditBefore 开启审计时读取变更前的状态
+func (manager *DefaultManager) auditBefore(state func() interface{}) interface{} {
+	if !manager.auditing() {
+		return nil
+	}
+	return state()
+}
+
+// audit 补全时间、操作人与元数据后写入审计记录，未设置 sink 时为空操作
+func (manager *DefaultManager) audit(ctx context.Context, entry AuditEntry, before interface{}, after interface{}) {
+	sink := manager.getAuditSink()
+	if sink == nil {
+		return
+	}
+	entry.Time = time.Now()
+	entry.Actor = ActorFromContext(ctx)
+	entry.Metadata = AuditMetadataFromContext(ctx)
+	entry.Before = auditJSON(before)
+	entry.After = auditJSON(after)
+	if err := sink.Write(&entry); err != nil {
+		manager.log().Error("[rbac] write audit entry failed", "operation", entry.Operation, "err", err)
+	}
+}
+
+func auditJSON(state interface{}) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// auditState 批量删除前的数据
+type auditState struct {
+	Rules       []*Rule       `json:"rules,omitempty"`
+	Items       []Item        `json:"items,omitempty"`
+	Children    []*ItemChild  `json:"children,omitempty"`
+	Assignments []*Assignment `json:"assignments,omitempty"`
+}
+
+func roleNames(roles []*Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if role != nil {
+			names = append(names, role.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryAudit 查询审计记录，sink 需实现 AuditQuerier
+func (manager *DefaultManager) QueryAudit(filter AuditFilter) ([]*AuditEntry, error) {
+	sink := manager.getAuditSink()
+	if sink == nil {
+		return nil, errors.New("audit sink is not set")
+	}
+	querier, ok := sink.(AuditQuerier)
+	if !ok {
+		return nil, fmt.Errorf("audit sink %T does not support queries", sink)
+	}
+	return querier.Query(filter)
+}
+
+// ---------------------- AuditedManager ---------------------------
+
+// AuditedManager 绑定 ctx 的管理器视图，写操作的审计记录取 ctx 中的操作人（WithActor）与元数据（WithAuditMetadata），
+// 读操作与 DefaultManager 相同。
+//
+//	manager.WithContext(gorbac.WithActor(ctx, "alice")).Assign(role, 42)
+type AuditedManager struct {
+	*DefaultManager
+	ctx context.Context
+}
+
+// WithContext 返回绑定 ctx 的管理器视图，直接调用 DefaultManager 的写操作时操作人为空
+func (manager *DefaultManager) WithContext(ctx context.Context) *AuditedManager {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &AuditedManager{DefaultManager: manager, ctx: ctx}
+}
+
+// Context 返回视图绑定的 ctx
+func (view *AuditedManager) Context() context.Context {
+	return view.ctx
+}
+
+func (view *AuditedManager) Add(item Item) bool {
+	return view.add(view.ctx, item)
+}
+
+func (view *AuditedManager) Update(name string, item Item) bool {
+	return view.update(view.ctx, name, item)
+}
+
+func (view *AuditedManager) Remove(item Item) bool {
+	return view.remove(view.ctx, item)
+}
+
+func (view *AuditedManager) AddRule(rule Rule) bool {
+	return view.addRule(view.ctx, rule)
+}
+
+func (view *AuditedManager) UpdateRule(name string, rule Rule) bool {
+	return view.updateRule(view.ctx, name, rule)
+}
+
+func (view *AuditedManager) RemoveRule(rule Rule) bool {
+	return view.removeRule(view.ctx, rule)
+}
+
+func (view *AuditedManager) AddChild(parent Item, child Item) error {
+	return view.addChild(view.ctx, parent, child)
+}
+
+func (view *AuditedManager) RemoveChild(parent Item, child Item) bool {
+	return view.removeChild(view.ctx, parent, child)
+}
+
+func (view *AuditedManager) RemoveChildren(parent Item) bool {
+	return view.removeChildren(view.ctx, parent)
+}
+
+func (view *AuditedManager) Assign(item Item, userId interface{}) *Assignment {
+	return view.assign(view.ctx, item, userId)
+}
+
+func (view *AuditedManager) Assigns(userId interface{}, name ...string) []*Assignment {
+	return view.assigns(view.ctx, userId, name...)
+}
+
+func (view *AuditedManager) Revoke(item Item, userId interface{}) bool {
+	return view.revoke(view.ctx, item, userId)
+}
+
+func (view *AuditedManager) RevokeAll(userId interface{}) bool {
+	return view.revokeAll(view.ctx, userId) == nil
+}
+
+func (view *AuditedManager) RemoveAllAssignmentByUser(userId interface{}) error {
+	return view.revokeAll(view.ctx, userId)
+}
+
+func (view *AuditedManager) RemoveAll() {
+	view.removeAll(view.ctx)
+}
+
+func (view *AuditedManager) RemoveAllPermissions() {
+	view.removeAllItems(view.ctx, PermissionType)
+}
+
+func (view *AuditedManager) RemoveAllRoles() {
+	view.removeAllItems(view.ctx, RoleType)
+}
+
+func (view *AuditedManager) RemoveAllRules() {
+	view.removeAllRules(view.ctx)
+}
+
+func (view *AuditedManager) RemoveAllAssignments() {
+	view.removeAllAssignments(view.ctx)
+}
+
+func (view *AuditedManager) SetDefaultRoles(roles ...*Role) {
+	view.setDefaultRoles(view.ctx, roles)
+}
+
+func (view *AuditedManager) ApplyPolicy(policy *PolicyFile, options SyncOptions) (*PolicyPlan, error) {
+	return view.applyPolicy(view.ctx, policy, options)
+}
+
+func (view *AuditedManager) ImportSnapshot(r io.Reader, options ImportOptions) (*SnapshotStats, error) {
+	return view.importSnapshot(view.ctx, r, options)
+}
+
+func (view *AuditedManager) ImportYii2(data *Yii2Data, options ImportOptions) (*SnapshotStats, error) {
+	return view.importYii2(view.ctx, data, options)
+}
+
+func (view *AuditedManager) Lint(options LintOptions) ([]LintFinding, error) {
+	return view.lint(view.ctx, options)
+}
@@ -0,0 +1,252 @@
+package gorbac
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ---------------------- JSON Lines ---------------------------
+
+// JSONLAuditSink 以 JSON Lines 追加写入文件，查询时顺序扫描整个文件
+type JSONLAuditSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewJSONLAuditSink 以追加模式打开（不存在时创建）path
+func NewJSONLAuditSink(path string) (*JSONLAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLAuditSink{path: path, file: file}, nil
+}
+
+// Write 每条记录一行，以一次写入完成
+func (sink *JSONLAuditSink) Write(entry *AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err = sink.file.Write(append(data, '\n'))
+	return err
+}
+
+// Query 只在读取文件长度时持锁，随后以独立的句柄扫描此前写入的完整行，扫描期间不阻塞写入
+func (sink *JSONLAuditSink) Query(filter AuditFilter) ([]*AuditEntry, error) {
+	sink.mu.Lock()
+	info, err := sink.file.Stat()
+	sink.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(sink.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([]*AuditEntry, 0)
+	reader := bufio.NewReader(io.LimitReader(file, info.Size()))
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			entry, decodeErr := decodeAuditEntry(data)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("line %d: %v", line, decodeErr)
+			}
+			if filter.Match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 文件按时间正序追加，倒序后截取
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func (sink *JSONLAuditSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.file.Close()
+}
+
+func decodeAuditEntry(data []byte) (*AuditEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	entry := &AuditEntry{}
+	if err := decoder.Decode(entry); err != nil {
+		return nil, err
+	}
+	if entry.UserId != nil {
+		entry.UserId = NormalizeUserId(entry.UserId)
+	}
+	return entry, nil
+}
+
+// ---------------------- SQL ---------------------------
+
+// SQLAuditSink 写入审计表（表名取 GetTableName("audit")），建表见 CreateTable
+type SQLAuditSink struct {
+	repo *SQLRepository
+}
+
+func NewSQLAuditSink(db *sql.DB, options SQLOptions) *SQLAuditSink {
+	return &SQLAuditSink{repo: NewSQLRepository(db, options)}
+}
+
+// CreateTable 创建不存在的审计表及 actor、item_name、user_id 索引
+func (sink *SQLAuditSink) CreateTable() error {
+	table := GetTableName("audit")
+	id := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	switch sink.repo.options.Dialect {
+	case DialectMySQL:
+		id = "id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY"
+	case DialectPostgres:
+		id = "id BIGSERIAL PRIMARY KEY"
+	}
+	columns := []string{
+		id,
+		"create_time BIGINT NOT NULL",
+		"actor VARCHAR(128) NOT NULL",
+		"operation VARCHAR(32) NOT NULL",
+		"item_name VARCHAR(64) NOT NULL",
+		"child VARCHAR(64) NOT NULL",
+		"user_id VARCHAR(64) NOT NULL",
+		"before_data TEXT",
+		"after_data TEXT",
+		"metadata TEXT",
+	}
+	indexes := []string{"actor", "item_name", "user_id"}
+
+	// MySQL 不支持 CREATE INDEX IF NOT EXISTS，索引随表创建
+	if sink.repo.options.Dialect == DialectMySQL {
+		for _, column := range indexes {
+			columns = append(columns, fmt.Sprintf("INDEX idx_%s_%s (%s)", table, column, column))
+		}
+	}
+	if err := sink.repo.exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", table, strings.Join(columns, ",\n\t"))); err != nil {
+		return err
+	}
+	if sink.repo.options.Dialect == DialectMySQL {
+		return nil
+	}
+	for _, column := range indexes {
+		if err := sink.repo.exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)", table, column, table, column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sink *SQLAuditSink) Write(entry *AuditEntry) error {
+	userId := ""
+	if entry.UserId != nil {
+		userId = sink.repo.encodeUserId(entry.UserId)
+	}
+	metadata := ""
+	if len(entry.Metadata) > 0 {
+		data, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(data)
+	}
+	return sink.repo.exec(fmt.Sprintf("INSERT INTO %s (create_time, actor, operation, item_name, child, user_id, before_data, after_data, metadata) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", GetTableName("audit")),
+		encodeTime(entry.Time), entry.Actor, string(entry.Operation), entry.Item, entry.Child, userId,
+		string(entry.Before), string(entry.After), metadata)
+}
+
+func (sink *SQLAuditSink) Query(filter AuditFilter) ([]*AuditEntry, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Item != "" {
+		conditions = append(conditions, "(item_name = ? OR child = ?)")
+		args = append(args, filter.Item, filter.Item)
+	}
+	if filter.UserId != nil {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, sink.repo.encodeUserId(filter.UserId))
+	}
+	if filter.Operation != "" {
+		conditions = append(conditions, "operation = ?")
+		args = append(args, string(filter.Operation))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "create_time >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "create_time < ?")
+		args = append(args, filter.Until.Unix())
+	}
+
+	query := fmt.Sprintf("SELECT id, create_time, actor, operation, item_name, child, user_id, before_data, after_data, metadata FROM %s", GetTableName("audit"))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := sink.repo.q.Query(sink.repo.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*AuditEntry, 0)
+	err = scanRows(rows, func() error {
+		var entry AuditEntry
+		var createTime int64
+		var operation, userId string
+		var before, after, metadata sql.NullString
+		if err := rows.Scan(&entry.Id, &createTime, &entry.Actor, &operation, &entry.Item, &entry.Child, &userId, &before, &after, &metadata); err != nil {
+			return err
+		}
+		entry.Time = decodeTime(createTime)
+		entry.Operation = AuditOperation(operation)
+		if userId != "" {
+			entry.UserId = sink.repo.decodeUserId(userId)
+		}
+		if before.String != "" {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.String != "" {
+			entry.After = json.RawMessage(after.String)
+		}
+		if metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &entry.Metadata); err != nil {
+				return fmt.Errorf("audit %d metadata: %v", entry.Id, err)
+			}
+		}
+		entries = append(entries, &entry)
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,261 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingAuditSink 在内存中记录审计条目
+type recordingAuditSink struct {
+	mu      sync.Mutex
+	entries []*AuditEntry
+}
+
+func (sink *recordingAuditSink) Write(entry *AuditEntry) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.entries = append(sink.entries, entry)
+	return nil
+}
+
+// externalManager 第三方的 AuthManager 实现，没有 WithContext 与 GetExecutorRegistry
+type externalManager struct {
+	AuthManager
+}
+
+func TestRbacServiceWithContext(t *testing.T) {
+	sink := &recordingAuditSink{}
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	manager.SetAuditSink(sink)
+	ctx := WithActor(context.Background(), "alice")
+
+	NewRbacServiceWithManager(manager).WithContext(ctx).AddRole("editor", "", "")
+	if len(sink.entries) != 1 || sink.entries[0].Actor != "alice" {
+		t.Fatalf("entries = %+v, want one entry by alice", sink.entries)
+	}
+
+	var external AuthManager = externalManager{manager}
+	service := NewRbacServiceWithManager(external).WithContext(ctx)
+	if service.GetAuthManager() != external {
+		t.Error("WithContext replaced a manager that cannot bind ctx")
+	}
+	service.AddRole("viewer", "", "")
+	if len(sink.entries) != 2 || sink.entries[1].Actor != "" {
+		t.Errorf("entries = %+v, want a second entry without actor", sink.entries)
+	}
+}
+
+// auditSummary 条目中与来源相关的字段
+func auditSummary(entry *AuditEntry) string {
+	return fmt.Sprintf("%s %s %s>%s user=%v before=%v after=%v", entry.Actor, entry.Operation, entry.Item, entry.Child, entry.UserId, entry.Before != nil, entry.After != nil)
+}
+
+func TestAuditEntries(t *testing.T) {
+	sink := &recordingAuditSink{}
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	manager.SetAuditSink(sink)
+
+	ctx := WithAuditMetadata(WithActor(context.Background(), "alice"), map[string]string{"request_id": "r1"})
+	ctx = WithAuditMetadata(ctx, map[string]string{"remote_addr": "10.0.0.1"})
+	view := manager.WithContext(ctx)
+	editor := manager.CreateRole("editor")
+	edit := manager.CreatePermission("posts:edit")
+	view.Add(editor)
+	view.Add(edit)
+	_ = view.AddChild(editor, edit)
+	view.Assign(editor, 42)
+	view.Revoke(editor, 42)
+	edit.Description = "edit posts"
+	view.Update("posts:edit", edit)
+	// 直接调用 DefaultManager 时没有操作人与元数据
+	manager.Remove(edit)
+
+	want := []string{
+		"alice item.add editor> user=<nil> before=false after=true",
+		"alice item.add posts:edit> user=<nil> before=false after=true",
+		"alice child.add editor>posts:edit user=<nil> before=false after=true",
+		"alice assignment.add editor> user=42 before=false after=true",
+		"alice assignment.remove editor> user=42 before=true after=false",
+		"alice item.update posts:edit> user=<nil> before=true after=true",
+		" item.remove posts:edit> user=<nil> before=true after=false",
+	}
+	got := make([]string, 0, len(sink.entries))
+	for _, entry := range sink.entries {
+		got = append(got, auditSummary(entry))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries =\n%v\nwant\n%v", got, want)
+	}
+	metadata := map[string]string{"request_id": "r1", "remote_addr": "10.0.0.1"}
+	for _, entry := range sink.entries[:len(sink.entries)-1] {
+		if !reflect.DeepEqual(entry.Metadata, metadata) {
+			t.Errorf("%s metadata = %v, want %v", entry.Operation, entry.Metadata, metadata)
+		}
+	}
+	if last := sink.entries[len(sink.entries)-1]; last.Metadata != nil {
+		t.Errorf("direct call metadata = %v, want none", last.Metadata)
+	}
+
+	// 视图绑定新的 ctx 后使用新的操作人
+	manager.WithContext(ctx).WithContext(WithActor(context.Background(), "bob")).Add(manager.CreateRole("viewer"))
+	if last := sink.entries[len(sink.entries)-1]; last.Actor != "bob" {
+		t.Errorf("rebound view actor = %q, want bob", last.Actor)
+	}
+}
+
+func TestAuditFilterMatch(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	entry := &AuditEntry{Time: now, Actor: "alice", Operation: AuditAddChild, Item: "editor", Child: "posts:edit", UserId: 42}
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"empty", AuditFilter{}, true},
+		{"actor", AuditFilter{Actor: "alice"}, true},
+		{"other actor", AuditFilter{Actor: "bob"}, false},
+		{"item", AuditFilter{Item: "editor"}, true},
+		{"child as item", AuditFilter{Item: "posts:edit"}, true},
+		{"other item", AuditFilter{Item: "viewer"}, false},
+		{"user id of another type", AuditFilter{UserId: "42"}, true},
+		{"other user", AuditFilter{UserId: 7}, false},
+		{"operation", AuditFilter{Operation: AuditAddChild}, true},
+		{"other operation", AuditFilter{Operation: AuditAssign}, false},
+		{"since inclusive", AuditFilter{Since: now}, true},
+		{"since after", AuditFilter{Since: now.Add(time.Second)}, false},
+		{"until exclusive", AuditFilter{Until: now}, false},
+		{"until after", AuditFilter{Until: now.Add(time.Second)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(entry); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (AuditFilter{UserId: 42}).Match(&AuditEntry{}) {
+		t.Error("user filter matched an entry without user")
+	}
+}
+
+func TestJSONLAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewJSONLAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	manager.SetAuditSink(sink)
+	alice := manager.WithContext(WithAuditMetadata(WithActor(context.Background(), "alice"), map[string]string{"request_id": "r1"}))
+	bob := manager.WithContext(WithActor(context.Background(), "bob"))
+	editor := manager.CreateRole("editor")
+	viewer := manager.CreateRole("viewer")
+	alice.Add(editor)
+	alice.Add(viewer)
+	bob.Assign(editor, 42)
+	bob.Assign(viewer, "u-7")
+	_ = alice.AddChild(editor, viewer)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后追加，查询覆盖两次写入的内容
+	sink, err = NewJSONLAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	manager.SetAuditSink(sink)
+	bob.Revoke(editor, 42)
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []string
+	}{
+		{"all newest first", AuditFilter{}, []string{"assignment.remove", "child.add", "assignment.add", "assignment.add", "item.add", "item.add"}},
+		{"actor", AuditFilter{Actor: "alice"}, []string{"child.add", "item.add", "item.add"}},
+		{"item or child", AuditFilter{Item: "viewer"}, []string{"child.add", "assignment.add", "item.add"}},
+		{"integer user", AuditFilter{UserId: 42}, []string{"assignment.remove", "assignment.add"}},
+		{"string user", AuditFilter{UserId: "u-7"}, []string{"assignment.add"}},
+		{"operation", AuditFilter{Operation: AuditAssign}, []string{"assignment.add", "assignment.add"}},
+		{"limit", AuditFilter{Actor: "bob", Limit: 2}, []string{"assignment.remove", "assignment.add"}},
+		{"until", AuditFilter{Until: time.Now().Add(-time.Hour)}, []string{}},
+	}
+	for _, tt := range tests {
+		entries, err := manager.QueryAudit(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			got = append(got, string(entry.Operation))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: operations = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	entries, _ := manager.QueryAudit(AuditFilter{Actor: "alice", Operation: AuditAddChild})
+	if len(entries) != 1 || entries[0].Metadata["request_id"] != "r1" || entries[0].Child != "viewer" || len(entries[0].After) == 0 {
+		t.Errorf("child.add entry = %+v", entries)
+	}
+	entries, _ = manager.QueryAudit(AuditFilter{UserId: 42, Operation: AuditAssign})
+	if len(entries) != 1 || entries[0].UserId != 42 {
+		t.Errorf("user id of decoded entry = %#v, want int 42", entries)
+	}
+}
+
+func TestJSONLAuditSinkConcurrentQuery(t *testing.T) {
+	sink, err := NewJSONLAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	const writes = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < writes; i++ {
+			if err := sink.Write(&AuditEntry{Time: time.Now(), Operation: AuditAddItem, Item: fmt.Sprintf("item-%d", i)}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	// 与写入并发的查询只看到完整的行，条数单调不减
+	last := 0
+	for writing := true; writing; {
+		select {
+		case <-done:
+			writing = false
+		default:
+		}
+		entries, err := sink.Query(AuditFilter{})
+		if err != nil {
+			t.Fatalf("Query during writes: %v", err)
+		}
+		if len(entries) < last {
+			t.Fatalf("Query returned %d entries after %d", len(entries), last)
+		}
+		last = len(entries)
+	}
+	if last != writes {
+		t.Errorf("final Query returned %d entries, want %d", last, writes)
+	}
+}
+
+func TestQueryAuditErrors(t *testing.T) {
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	if _, err := manager.QueryAudit(AuditFilter{}); err == nil {
+		t.Error("QueryAudit without sink succeeded")
+	}
+	manager.SetAuditSink(&recordingAuditSink{})
+	if _, err := manager.QueryAudit(AuditFilter{}); err == nil {
+		t.Error("QueryAudit on a sink without AuditQuerier succeeded")
+	}
+}
@@ -1,5 +1,10 @@
 package gorbac
 
+import (
+	"context"
+	"io"
+)
+
 type AuthRepository interface {
 	AddItem(item Item) error
 	GetItem(name string) (Item, error)
@@ -88,6 +93,15 @@ type AuthManager interface {
 	Update(name string, item Item) bool
 	UpdateRule(name string, rule Rule) bool
 
+	// ValidateRule
+	/**
+	 * Validates a rule with its executor before it is added or updated.
+	 *
+	 * @param rule Rule $
+	 * @return error the reason the rule is rejected, nil when it is valid
+	 */
+	ValidateRule(rule Rule) error
+
 	GetItem(name string) Item
 
 	// GetRole
@@ -239,6 +253,132 @@ type AuthManager interface {
 	 */
 	GetChildren(name string) []Item
 
+	// GetParents
+	/**
+	 * Returns the direct parents of the item.
+	 *
+	 * @param name string $ the item name
+	 * @return Item[] the parent items
+	 */
+	GetParents(name string) []Item
+
+	// GetAncestors
+	/**
+	 * Returns all ancestors of the item with their distance, nearest first.
+	 *
+	 * @param name string $ the item name
+	 * @return HierarchyNode[] the ancestors
+	 */
+	GetAncestors(name string) []HierarchyNode
+
+	// GetDescendants
+	/**
+	 * Returns all descendants of the item with their distance, nearest first.
+	 *
+	 * @param name     string $ the item name
+	 * @param itemType ItemType $ only return items of this type, NoneType for all
+	 * @param maxDepth int $ the maximum distance, 0 for unlimited
+	 * @return HierarchyNode[] the descendants
+	 */
+	GetDescendants(name string, itemType ItemType, maxDepth int) []HierarchyNode
+
+	// PathsBetween
+	/**
+	 * Returns every path from the ancestor down to the descendant, both ends included.
+	 *
+	 * @param ancestor   string $
+	 * @param descendant string $
+	 * @return array of item name paths, empty if ancestor does not reach descendant
+	 */
+	PathsBetween(ancestor string, descendant string) [][]string
+
+	// ExportDOT
+	/**
+	 * Writes the item hierarchy as a Graphviz DOT graph.
+	 *
+	 * @param w       io.Writer $
+	 * @param options GraphOptions $ optional root item and user filter
+	 * @return error
+	 */
+	ExportDOT(w io.Writer, options GraphOptions) error
+
+	// ExportMermaid
+	/**
+	 * Writes the item hierarchy as a Mermaid flowchart.
+	 *
+	 * @param w       io.Writer $
+	 * @param options GraphOptions $ optional root item and user filter
+	 * @return error
+	 */
+	ExportMermaid(w io.Writer, options GraphOptions) error
+
+	// Lint
+	/**
+	 * Scans items, child relations, rules and assignments for hygiene problems.
+	 *
+	 * @param options LintOptions $ set Fix to remove redundant edges, dangling child rows and dangling assignments
+	 * @return LintFinding[] the findings ordered by severity
+	 */
+	Lint(options LintOptions) ([]LintFinding, error)
+
+	// PlanPolicy
+	/**
+	 * Validates a declarative policy and diffs it against the repository.
+	 *
+	 * @param policy  PolicyFile $ rules, roles, permissions, children, default roles and optional assignments
+	 * @param options SyncOptions $ set KeepUnmanaged to never delete items missing from the policy
+	 * @return PolicyPlan the changes needed to make the repository match the policy
+	 */
+	PlanPolicy(policy *PolicyFile, options SyncOptions) (*PolicyPlan, error)
+
+	// ApplyPolicy
+	/**
+	 * Plans and applies a declarative policy, in one transaction when the repository is a TransactionalRepository.
+	 *
+	 * @param policy  PolicyFile $
+	 * @param options SyncOptions $ set DryRun to only plan
+	 * @return PolicyPlan the planned (and applied) changes
+	 */
+	ApplyPolicy(policy *PolicyFile, options SyncOptions) (*PolicyPlan, error)
+
+	// ExportPolicy
+	/**
+	 * Exports the repository as a declarative policy.
+	 *
+	 * @param withAssignments bool $ include user assignments
+	 * @return PolicyFile
+	 */
+	ExportPolicy(withAssignments bool) (*PolicyFile, error)
+
+	// ExportSnapshot
+	/**
+	 * Writes all rules, items, child relations and assignments as a versioned JSON Lines snapshot.
+	 *
+	 * @param w io.Writer $
+	 * @return SnapshotStats the number of records written
+	 */
+	ExportSnapshot(w io.Writer) (*SnapshotStats, error)
+
+	// ImportSnapshot
+	/**
+	 * Reads and validates a snapshot, then merges it into or replaces the repository.
+	 *
+	 * @param r       io.Reader $
+	 * @param options ImportOptions $ ImportMerge or ImportReplace
+	 * @return SnapshotStats the number of records read
+	 */
+	ImportSnapshot(r io.Reader, options ImportOptions) (*SnapshotStats, error)
+
+	// ImportYii2
+	/**
+	 * Writes data read by LoadYii2PhpFiles or LoadYii2Database into the repository.
+	 *
+	 * @param data    Yii2Data $
+	 * @param options ImportOptions $ ImportMerge or ImportReplace
+	 * @return SnapshotStats the number of records written
+	 */
+	ImportYii2(data *Yii2Data, options ImportOptions) (*SnapshotStats, error)
+
 	// Assign
 	/**
 	 * Assigns a role to a user.
@@ -300,6 +440,26 @@ type AuthManager interface {
 	 */
 	GetUserIdsByRole(roleName string) []interface{}
 
+	// GetUserIdsByPermission
+	/**
+	 * Returns all user IDs that are assigned to the permission or to any of its ancestors.
+	 * Rules are not evaluated.
+	 *
+	 * @param permissionName string $
+	 * @return array of user IDs
+	 */
+	GetUserIdsByPermission(permissionName string) []interface{}
+
+	// WhoCan
+	/**
+	 * Returns the users who can perform the permission, resolving inheritance and default roles.
+	 *
+	 * @param permissionName string $
+	 * @param options        WhoCanOptions $ rule evaluation and pagination
+	 * @return WhoCanResult
+	 */
+	WhoCan(ctx context.Context, permissionName string, options WhoCanOptions) *WhoCanResult
+
 	// RemoveAll
 	/**
 	 * Removes all authorization data, including roles, permissions, rules, and assignments.
@@ -341,6 +501,60 @@ type AuthManager interface {
 
 	GetDefaultRoles() []*Role
 	SetDefaultRoles(roles ...*Role)
+
+	// Explain
+	/**
+	 * Checks access like CheckAccess and returns the granting path and the rule outcomes.
+	 *
+	 * @param userId         string|int $ the user ID
+	 * @param permissionName string $ the permission to check
+	 * @return Decision the decision trace
+	 */
+	Explain(ctx context.Context, userId interface{}, permissionName string) *Decision
+
+	// SetAuditSink
+	/**
+	 * Sets the sink that receives an audit entry for every mutation, nil disables auditing.
+	 */
+	SetAuditSink(sink AuditSink)
+
+	// QueryAudit
+	/**
+	 * Returns the audit entries matching the filter, newest first.
+	 * The sink must implement AuditQuerier.
+	 *
+	 * @param filter AuditFilter $ the query conditions
+	 * @return AuditEntry[] the matching entries
+	 */
+	QueryAudit(filter AuditFilter) ([]*AuditEntry, error)
+
+	// SetDecisionLogger
+	/**
+	 * Sets the logger that records sampled CheckAccess decisions, nil disables decision logging.
+	 */
+	SetDecisionLogger(decisionLogger *DecisionLogger)
+
+	// SetObserver
+	/**
+	 * Sets the hooks that observe checks, cache events and executor timings, nil disables them.
+	 * Repository calls are observed by wrapping the repository with ObserveRepository.
+	 */
+	SetObserver(observer Observer)
+
+	// SetLogger
+	/**
+	 * Sets the structured logger used by this manager, nil falls back to DefaultLogger.
+	 */
+	SetLogger(logger Logger)
+
+	// SetUserIdNormalizer
+	/**
+	 * Sets the function that normalizes user IDs before they are checked, assigned or used as cache keys,
+	 * so that int(5), int64(5) and "5" can refer to the same user. Nil disables normalization.
+	 *
+	 * @param normalizer func $ for example NormalizeIntUserId or NormalizeStringUserId
+	 */
+	SetUserIdNormalizer(normalizer func(userId interface{}) interface{})
 }
 
 //type ManagerInterface interface {
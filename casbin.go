@@ -0,0 +1,485 @@
+package gorbac
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Casbin RBAC 与 gorbac 的对应关系：
+//
+//	p, role, obj, act        => 权限 "obj:act"，角色 role 的子级
+//	p, role, dom, obj, act   => 权限 "dom/obj:act"，角色 "dom/role" 的子级
+//	g, user, role[, dom]     => 用户分配 role（或 "dom/role"）
+//	g, role1, role2[, dom]   => 角色 role2 为 role1 的子级
+//
+// 作为 g 第二个字段出现过的主体视为角色，其余视为用户（可用 CasbinOptions.IsUser 覆盖）。
+type CasbinOptions struct {
+	// Separator 权限名中 obj 与 act 的分隔符，默认 ":"
+	Separator string
+	// DomainSeparator 域与角色/权限名的分隔符，默认 "/"
+	DomainSeparator string
+	// Domains 导出时按 DomainSeparator 拆出域，输出带域的 p/g 记录
+	Domains bool
+	// IsUser 判断主体是否为用户
+	IsUser func(subject string) bool
+}
+
+// CasbinIssue 无法无损转换的内容，Line 为策略 CSV 中的行号（导出或模型相关时为 0）
+type CasbinIssue struct {
+	Line    int    `json:"line,omitempty"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+func (issue CasbinIssue) String() string {
+	if issue.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", issue.Line, issue.Subject, issue.Message)
+	}
+	return fmt.Sprintf("%s: %s", issue.Subject, issue.Message)
+}
+
+func (options CasbinOptions) separator() string {
+	if options.Separator == "" {
+		return ":"
+	}
+	return options.Separator
+}
+
+func (options CasbinOptions) domainSeparator() string {
+	if options.DomainSeparator == "" {
+		return "/"
+	}
+	return options.DomainSeparator
+}
+
+// casbinModel 模型中 p 的字段顺序
+type casbinModel struct {
+	policy []string
+}
+
+func (model *casbinModel) index(field string) int {
+	for i, name := range model.policy {
+		if name == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// CasbinModel 与 ExportCasbin 输出对应的 RBAC 模型
+func CasbinModel(domains bool) string {
+	if domains {
+		return `[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+	}
+	return `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+}
+
+// parseCasbinModel 读取模型中的 policy_definition、role_definition、policy_effect 与 matchers，
+// model 为 nil 时按每行字段数推断。
+func parseCasbinModel(model io.Reader) (*casbinModel, []CasbinIssue, error) {
+	if model == nil {
+		return nil, nil, nil
+	}
+	result := &casbinModel{}
+	issues := make([]CasbinIssue, 0)
+	section := ""
+	scanner := bufio.NewScanner(model)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch section {
+		case "policy_definition":
+			if key != "p" {
+				issues = append(issues, CasbinIssue{Subject: "model " + key, Message: "additional policy types are not imported"})
+				continue
+			}
+			for _, field := range strings.Split(value, ",") {
+				result.policy = append(result.policy, strings.TrimSpace(field))
+			}
+		case "role_definition":
+			if key != "g" {
+				issues = append(issues, CasbinIssue{Subject: "model " + key, Message: "only the g role definition is imported"})
+			}
+		case "policy_effect":
+			if strings.Contains(value, "deny") {
+				issues = append(issues, CasbinIssue{Subject: "model effect", Message: "deny effects have no gorbac equivalent, deny policies are skipped"})
+			}
+		case "matchers":
+			for _, function := range []string{"keyMatch", "regexMatch", "globMatch", "ipMatch", "eval("} {
+				if strings.Contains(value, function) {
+					issues = append(issues, CasbinIssue{Subject: "model matcher", Message: fmt.Sprintf("%s patterns are imported as literal names", strings.TrimSuffix(function, "("))})
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if result.index("sub") < 0 || result.index("obj") < 0 || result.index("act") < 0 {
+		return nil, nil, fmt.Errorf("casbin model: policy_definition must define sub, obj and act")
+	}
+	return result, issues, nil
+}
+
+// ImportCasbin 将 Casbin 模型（可为 nil）与策略 CSV 转换为策略文件，可再用 PlanPolicy/ApplyPolicy 同步到仓库
+func ImportCasbin(model io.Reader, policy io.Reader, options CasbinOptions) (*PolicyFile, []CasbinIssue, error) {
+	definition, issues, err := parseCasbinModel(model)
+	if err != nil {
+		return nil, nil, err
+	}
+	if issues == nil {
+		issues = make([]CasbinIssue, 0)
+	}
+
+	type record struct {
+		line   int
+		fields []string
+	}
+	policies := make([]record, 0)
+	groupings := make([]record, 0)
+	reader := csv.NewReader(policy)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("casbin policy: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		switch fields[0] {
+		case "p":
+			policies = append(policies, record{line, fields[1:]})
+		case "g":
+			groupings = append(groupings, record{line, fields[1:]})
+		default:
+			issues = append(issues, CasbinIssue{Line: line, Subject: fields[0], Message: "policy type is not supported, line skipped"})
+		}
+	}
+
+	isUser := options.IsUser
+	if isUser == nil {
+		roles := make(map[string]bool)
+		for _, g := range groupings {
+			if len(g.fields) >= 2 {
+				roles[g.fields[1]] = true
+			}
+		}
+		isUser = func(subject string) bool { return !roles[subject] }
+	}
+
+	sep, domSep := options.separator(), options.domainSeparator()
+	qualify := func(domain string, name string) string {
+		if domain == "" {
+			return name
+		}
+		return domain + domSep + name
+	}
+
+	roles := make(map[string]bool)
+	permissions := make(map[string]bool)
+	children := make(map[string]map[string]bool)
+	assignments := make(map[string]map[string]bool)
+	addChild := func(parent, child string) {
+		if children[parent] == nil {
+			children[parent] = make(map[string]bool)
+		}
+		children[parent][child] = true
+	}
+	assign := func(user, item string) {
+		if assignments[user] == nil {
+			assignments[user] = make(map[string]bool)
+		}
+		assignments[user][item] = true
+	}
+
+	for _, p := range policies {
+		var sub, dom, obj, act, eft string
+		if definition != nil {
+			get := func(field string) string {
+				if i := definition.index(field); i >= 0 && i < len(p.fields) {
+					return p.fields[i]
+				}
+				return ""
+			}
+			if len(p.fields) < len(definition.policy) {
+				issues = append(issues, CasbinIssue{Line: p.line, Subject: strings.Join(p.fields, ", "), Message: "fewer fields than the policy definition, line skipped"})
+				continue
+			}
+			sub, dom, obj, act, eft = get("sub"), get("dom"), get("obj"), get("act"), get("eft")
+		} else {
+			switch len(p.fields) {
+			case 3:
+				sub, obj, act = p.fields[0], p.fields[1], p.fields[2]
+			case 4:
+				sub, dom, obj, act = p.fields[0], p.fields[1], p.fields[2], p.fields[3]
+			default:
+				issues = append(issues, CasbinIssue{Line: p.line, Subject: strings.Join(p.fields, ", "), Message: "cannot infer fields without a model, line skipped"})
+				continue
+			}
+		}
+		if strings.EqualFold(eft, "deny") {
+			issues = append(issues, CasbinIssue{Line: p.line, Subject: sub, Message: "deny policy has no gorbac equivalent, line skipped"})
+			continue
+		}
+		if strings.ContainsAny(obj+act+dom, "*()") {
+			issues = append(issues, CasbinIssue{Line: p.line, Subject: sub, Message: "pattern is imported as a literal permission name"})
+		}
+
+		permission := qualify(dom, obj+sep+act)
+		permissions[permission] = true
+		if isUser(sub) {
+			assign(sub, permission)
+			continue
+		}
+		role := qualify(dom, sub)
+		roles[role] = true
+		addChild(role, permission)
+	}
+
+	for _, g := range groupings {
+		if len(g.fields) < 2 {
+			issues = append(issues, CasbinIssue{Line: g.line, Subject: strings.Join(g.fields, ", "), Message: "incomplete grouping, line skipped"})
+			continue
+		}
+		dom := ""
+		if len(g.fields) >= 3 {
+			dom = g.fields[2]
+		}
+		if len(g.fields) > 3 {
+			issues = append(issues, CasbinIssue{Line: g.line, Subject: g.fields[0], Message: "extra grouping fields are ignored"})
+		}
+		role := qualify(dom, g.fields[1])
+		roles[role] = true
+		if isUser(g.fields[0]) {
+			assign(g.fields[0], role)
+			continue
+		}
+		parent := qualify(dom, g.fields[0])
+		roles[parent] = true
+		addChild(parent, role)
+	}
+
+	result := &PolicyFile{Children: make(map[string][]string), Assignments: make([]PolicyAssignment, 0)}
+	for _, name := range sortedNames(boolKeys(roles)) {
+		if permissions[name] {
+			issues = append(issues, CasbinIssue{Subject: name, Message: "name is used as both a role and a permission, kept as a role"})
+			delete(permissions, name)
+		}
+		result.Roles = append(result.Roles, PolicyItem{Name: name})
+	}
+	for _, name := range sortedNames(boolKeys(permissions)) {
+		result.Permissions = append(result.Permissions, PolicyItem{Name: name})
+	}
+	for parent, set := range children {
+		result.Children[parent] = sortedNames(boolKeys(set))
+	}
+	for _, user := range sortedNames(mapKeysOfSets(assignments)) {
+		result.Assignments = append(result.Assignments, PolicyAssignment{UserId: user, Items: sortedNames(boolKeys(assignments[user]))})
+	}
+	return result, issues, nil
+}
+
+// ExportCasbin 将策略文件（例如 ExportPolicy(true) 的结果）写为 Casbin 策略 CSV。
+// 权限之间的继承会展开到持有它的角色上，规则、执行器与默认角色无法表示，均在返回的问题中列出。
+func ExportCasbin(policy *PolicyFile, w io.Writer, options CasbinOptions) ([]CasbinIssue, error) {
+	issues := make([]CasbinIssue, 0)
+	sep, domSep := options.separator(), options.domainSeparator()
+
+	types := make(map[string]ItemType)
+	for _, entry := range policy.Roles {
+		types[entry.Name] = RoleType
+	}
+	for _, entry := range policy.Permissions {
+		types[entry.Name] = PermissionType
+	}
+	for _, entry := range append(append([]PolicyItem(nil), policy.Roles...), policy.Permissions...) {
+		if entry.Rule != "" || entry.Executor != "" {
+			issues = append(issues, CasbinIssue{Subject: entry.Name, Message: "rule and executor conditions are dropped"})
+		}
+	}
+	for _, name := range policy.DefaultRoles {
+		issues = append(issues, CasbinIssue{Subject: name, Message: "default roles have no Casbin equivalent"})
+	}
+
+	// 权限及其所有下级权限
+	expand := func(permission string) []string {
+		result := []string{permission}
+		seen := map[string]bool{permission: true}
+		for i := 0; i < len(result); i++ {
+			for _, child := range policy.Children[result[i]] {
+				if types[child] == PermissionType && !seen[child] {
+					seen[child] = true
+					result = append(result, child)
+				}
+			}
+		}
+		return result
+	}
+
+	split := func(name string) (domain string, rest string) {
+		if !options.Domains {
+			return "", name
+		}
+		if i := strings.Index(name, domSep); i >= 0 {
+			return name[:i], name[i+len(domSep):]
+		}
+		issues = append(issues, CasbinIssue{Subject: name, Message: "no domain in name, exported with domain *"})
+		return "*", name
+	}
+	permissionFields := func(name string) (domain, obj, act string) {
+		domain, rest := split(name)
+		i := strings.LastIndex(rest, sep)
+		if i < 0 {
+			issues = append(issues, CasbinIssue{Subject: name, Message: fmt.Sprintf("no %q separator in permission name, exported with act *", sep)})
+			return domain, rest, "*"
+		}
+		return domain, rest[:i], rest[i+len(sep):]
+	}
+
+	// 以 NUL 连接的字段为键去重
+	records := make(map[string][]string)
+	add := func(fields []string) {
+		records[strings.Join(fields, "\x00")] = fields
+	}
+	grant := func(subject string, qualified bool, permission string) {
+		if qualified {
+			_, subject = split(subject)
+		}
+		for _, name := range expand(permission) {
+			domain, obj, act := permissionFields(name)
+			fields := []string{"p", subject, obj, act}
+			if options.Domains {
+				fields = []string{"p", subject, domain, obj, act}
+			}
+			add(fields)
+		}
+	}
+	group := func(member string, qualified bool, role string) {
+		domain, name := split(role)
+		if qualified {
+			_, member = split(member)
+		}
+		fields := []string{"g", member, name}
+		if options.Domains {
+			fields = append(fields, domain)
+		}
+		add(fields)
+	}
+
+	for parent, list := range policy.Children {
+		for _, child := range list {
+			switch {
+			case types[parent] == RoleType && types[child] == RoleType:
+				group(parent, true, child)
+			case types[parent] == RoleType && types[child] == PermissionType:
+				grant(parent, true, child)
+			case types[parent] == PermissionType && types[child] == PermissionType:
+				issues = append(issues, CasbinIssue{Subject: parent + " -> " + child, Message: "permission hierarchy is flattened onto the holding roles"})
+			default:
+				issues = append(issues, CasbinIssue{Subject: parent + " -> " + child, Message: "relation cannot be represented, skipped"})
+			}
+		}
+	}
+	for _, assignment := range policy.Assignments {
+		user := fmt.Sprint(assignment.UserId)
+		for _, name := range assignment.Items {
+			if types[name] == RoleType {
+				group(user, false, name)
+			} else {
+				grant(user, false, name)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		// p 在 g 之前
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] > keys[j][0]
+		}
+		return keys[i] < keys[j]
+	})
+	// 含逗号、引号或换行的字段由 csv.Writer 加引号
+	writer := csv.NewWriter(w)
+	for _, key := range keys {
+		if err := writer.Write(records[key]); err != nil {
+			return issues, err
+		}
+	}
+	writer.Flush()
+	return dedupeIssues(issues), writer.Error()
+}
+
+func dedupeIssues(issues []CasbinIssue) []CasbinIssue {
+	seen := make(map[CasbinIssue]bool)
+	result := make([]CasbinIssue, 0, len(issues))
+	for _, issue := range issues {
+		if !seen[issue] {
+			seen[issue] = true
+			result = append(result, issue)
+		}
+	}
+	return result
+}
+
+func mapKeysOfSets(m map[string]map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
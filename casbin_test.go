@@ -0,0 +1,217 @@
+package gorbac
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func casbinIssueMessages(issues []CasbinIssue) []string {
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	return messages
+}
+
+func TestImportCasbin(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       string
+		policy      string
+		options     CasbinOptions
+		roles       []string
+		permissions []string
+		children    map[string][]string
+		assignments map[string][]string
+		issues      []string
+	}{
+		{
+			name:        "roles and users",
+			policy:      "p, admin, posts, edit\np, viewer, posts, view\ng, admin, viewer\ng, alice, admin\n",
+			roles:       []string{"admin", "viewer"},
+			permissions: []string{"posts:edit", "posts:view"},
+			children:    map[string][]string{"admin": {"posts:edit", "viewer"}, "viewer": {"posts:view"}},
+			assignments: map[string][]string{"alice": {"admin"}},
+		},
+		{
+			name:        "domains",
+			policy:      "p, admin, acme, posts, edit\ng, alice, admin, acme\n",
+			roles:       []string{"acme/admin"},
+			permissions: []string{"acme/posts:edit"},
+			children:    map[string][]string{"acme/admin": {"acme/posts:edit"}},
+			assignments: map[string][]string{"alice": {"acme/admin"}},
+		},
+		{
+			name:        "quoted fields",
+			policy:      "p, admin, \"reports, 2024\", \"say \"\"hi\"\"\"\ng, \"doe, john\", admin\n",
+			roles:       []string{"admin"},
+			permissions: []string{`reports, 2024:say "hi"`},
+			children:    map[string][]string{"admin": {`reports, 2024:say "hi"`}},
+			assignments: map[string][]string{"doe, john": {"admin"}},
+		},
+		{
+			name:        "comments and unsupported lines",
+			policy:      "# header\n\np, bob, posts, view\ng2, a, b\n",
+			permissions: []string{"posts:view"},
+			children:    map[string][]string{},
+			assignments: map[string][]string{"bob": {"posts:view"}},
+			issues:      []string{"line 4: g2: policy type is not supported, line skipped"},
+		},
+		{
+			name:        "model with deny effect",
+			model:       "[policy_definition]\np = sub, obj, act, eft\n[policy_effect]\ne = some(where (p.eft == allow)) && !some(where (p.eft == deny))\n",
+			policy:      "p, admin, posts, edit, allow\np, admin, posts, delete, deny\ng, alice, admin\n",
+			roles:       []string{"admin"},
+			permissions: []string{"posts:edit"},
+			children:    map[string][]string{"admin": {"posts:edit"}},
+			assignments: map[string][]string{"alice": {"admin"}},
+			issues: []string{
+				"model effect: deny effects have no gorbac equivalent, deny policies are skipped",
+				"line 2: admin: deny policy has no gorbac equivalent, line skipped",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var model io.Reader
+			if tt.model != "" {
+				model = strings.NewReader(tt.model)
+			}
+			policy, issues, err := ImportCasbin(model, strings.NewReader(tt.policy), tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roles, permissions := make([]string, 0), make([]string, 0)
+			for _, role := range policy.Roles {
+				roles = append(roles, role.Name)
+			}
+			for _, permission := range policy.Permissions {
+				permissions = append(permissions, permission.Name)
+			}
+			assignments := make(map[string][]string)
+			for _, assignment := range policy.Assignments {
+				assignments[assignment.UserId.(string)] = assignment.Items
+			}
+			if tt.roles == nil {
+				tt.roles = []string{}
+			}
+			if tt.issues == nil {
+				tt.issues = []string{}
+			}
+			if !reflect.DeepEqual(roles, tt.roles) {
+				t.Errorf("roles = %q, want %q", roles, tt.roles)
+			}
+			if !reflect.DeepEqual(permissions, tt.permissions) {
+				t.Errorf("permissions = %q, want %q", permissions, tt.permissions)
+			}
+			if !reflect.DeepEqual(policy.Children, tt.children) {
+				t.Errorf("children = %q, want %q", policy.Children, tt.children)
+			}
+			if !reflect.DeepEqual(assignments, tt.assignments) {
+				t.Errorf("assignments = %q, want %q", assignments, tt.assignments)
+			}
+			if got := casbinIssueMessages(issues); !reflect.DeepEqual(got, tt.issues) {
+				t.Errorf("issues = %q, want %q", got, tt.issues)
+			}
+		})
+	}
+}
+
+func TestExportCasbin(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *PolicyFile
+		options CasbinOptions
+		output  string
+	}{
+		{
+			name: "roles and users",
+			policy: &PolicyFile{
+				Roles:       []PolicyItem{{Name: "admin"}, {Name: "viewer"}},
+				Permissions: []PolicyItem{{Name: "posts:edit"}, {Name: "posts:view"}},
+				Children:    map[string][]string{"admin": {"viewer", "posts:edit"}, "viewer": {"posts:view"}},
+				Assignments: []PolicyAssignment{{UserId: 1, Items: []string{"admin"}}},
+			},
+			output: "p,admin,posts,edit\np,viewer,posts,view\ng,1,admin\ng,admin,viewer\n",
+		},
+		{
+			name: "domains",
+			policy: &PolicyFile{
+				Roles:       []PolicyItem{{Name: "acme/admin"}},
+				Permissions: []PolicyItem{{Name: "acme/posts:edit"}},
+				Children:    map[string][]string{"acme/admin": {"acme/posts:edit"}},
+				Assignments: []PolicyAssignment{{UserId: "alice", Items: []string{"acme/admin"}}},
+			},
+			options: CasbinOptions{Domains: true},
+			output:  "p,admin,acme,posts,edit\ng,alice,admin,acme\n",
+		},
+		{
+			name: "quoted fields",
+			policy: &PolicyFile{
+				Roles:       []PolicyItem{{Name: "admin"}},
+				Permissions: []PolicyItem{{Name: `reports, 2024:say "hi"`}},
+				Children:    map[string][]string{"admin": {`reports, 2024:say "hi"`}},
+				Assignments: []PolicyAssignment{{UserId: "doe, john", Items: []string{"admin"}}},
+			},
+			output: "p,admin,\"reports, 2024\",\"say \"\"hi\"\"\"\ng,\"doe, john\",admin\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := ExportCasbin(tt.policy, &buf, tt.options); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.output {
+				t.Fatalf("output = %q, want %q", buf.String(), tt.output)
+			}
+
+			// 导出结果导入后再次导出，内容不变
+			imported, _, err := ImportCasbin(nil, &buf, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again bytes.Buffer
+			if _, err := ExportCasbin(imported, &again, tt.options); err != nil {
+				t.Fatal(err)
+			}
+			if again.String() != tt.output {
+				t.Errorf("round trip output = %q, want %q", again.String(), tt.output)
+			}
+		})
+	}
+}
+
+func TestExportCasbinIssues(t *testing.T) {
+	policy := &PolicyFile{
+		Roles:        []PolicyItem{{Name: "admin", Rule: "isOwner"}, {Name: "guest"}},
+		Permissions:  []PolicyItem{{Name: "posts"}, {Name: "posts:view"}},
+		Children:     map[string][]string{"admin": {"posts"}, "posts": {"posts:view"}},
+		DefaultRoles: []string{"guest"},
+	}
+	var buf bytes.Buffer
+	issues, err := ExportCasbin(policy, &buf, CasbinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"admin: rule and executor conditions are dropped",
+		"guest: default roles have no Casbin equivalent",
+	}
+	got := casbinIssueMessages(issues)
+	for _, message := range want {
+		found := false
+		for _, issue := range got {
+			found = found || issue == message
+		}
+		if !found {
+			t.Errorf("issues = %q, want %q", got, message)
+		}
+	}
+	if output := buf.String(); !strings.Contains(output, "p,admin,posts,view\n") {
+		t.Errorf("output = %q, want the permission hierarchy flattened onto admin", output)
+	}
+}
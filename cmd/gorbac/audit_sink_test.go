@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+func TestSQLAuditSink(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "rbac.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	options := gorbac.SQLOptions{Dialect: gorbac.DialectSQLite}
+	repo := gorbac.NewSQLRepository(db, options)
+	must(t, repo.CreateTables())
+	sink := gorbac.NewSQLAuditSink(db, options)
+	must(t, sink.CreateTable())
+	// 重复建表不报错
+	must(t, sink.CreateTable())
+
+	manager := gorbac.NewDefaultManager(repo, false)
+	manager.SetAuditSink(sink)
+	start := time.Now().Add(-time.Second)
+	alice := manager.WithContext(gorbac.WithAuditMetadata(gorbac.WithActor(context.Background(), "alice"), map[string]string{"request_id": "r1"}))
+	bob := manager.WithContext(gorbac.WithActor(context.Background(), "bob"))
+	editor := manager.CreateRole("editor")
+	viewer := manager.CreateRole("viewer")
+	alice.Add(editor)
+	alice.Add(viewer)
+	must(t, alice.AddChild(editor, viewer))
+	bob.Assign(editor, 42)
+	bob.Assign(viewer, "u-7")
+	bob.Revoke(editor, 42)
+
+	tests := []struct {
+		name   string
+		filter gorbac.AuditFilter
+		want   []string
+	}{
+		{"all newest first", gorbac.AuditFilter{}, []string{"assignment.remove", "assignment.add", "assignment.add", "child.add", "item.add", "item.add"}},
+		{"actor", gorbac.AuditFilter{Actor: "alice"}, []string{"child.add", "item.add", "item.add"}},
+		{"item or child", gorbac.AuditFilter{Item: "viewer"}, []string{"assignment.add", "child.add", "item.add"}},
+		{"integer user", gorbac.AuditFilter{UserId: 42}, []string{"assignment.remove", "assignment.add"}},
+		{"string user", gorbac.AuditFilter{UserId: "u-7"}, []string{"assignment.add"}},
+		{"operation", gorbac.AuditFilter{Operation: gorbac.AuditAssign, Actor: "bob"}, []string{"assignment.add", "assignment.add"}},
+		{"limit", gorbac.AuditFilter{Limit: 2}, []string{"assignment.remove", "assignment.add"}},
+		{"since", gorbac.AuditFilter{Since: start, Actor: "alice"}, []string{"child.add", "item.add", "item.add"}},
+		{"until", gorbac.AuditFilter{Until: start}, []string{}},
+	}
+	for _, tt := range tests {
+		entries, err := manager.QueryAudit(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			got = append(got, string(entry.Operation))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: operations = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	entries, err := sink.Query(gorbac.AuditFilter{Operation: gorbac.AuditAddChild})
+	must(t, err)
+	if len(entries) != 1 {
+		t.Fatalf("child.add entries = %d, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Id == 0 || entry.Actor != "alice" || entry.Item != "editor" || entry.Child != "viewer" || entry.Before != nil || len(entry.After) == 0 {
+		t.Errorf("child.add entry = %+v", entry)
+	}
+	if !reflect.DeepEqual(entry.Metadata, map[string]string{"request_id": "r1"}) {
+		t.Errorf("metadata = %v, want request_id r1", entry.Metadata)
+	}
+	entries, err = sink.Query(gorbac.AuditFilter{Operation: gorbac.AuditRevoke})
+	must(t, err)
+	if len(entries) != 1 || entries[0].UserId != 42 || len(entries[0].Before) == 0 || entries[0].After != nil || entries[0].Metadata != nil {
+		t.Errorf("assignment.remove entry = %+v", entries)
+	}
+}
@@ -0,0 +1,769 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+// dispatch 执行 args[0] 对应的命令
+func (c *cli) dispatch(args []string) error {
+	commands := map[string]func(args []string) error{
+		"init":        c.init,
+		"role":        c.items(gorbac.RoleType),
+		"permission":  c.items(gorbac.PermissionType),
+		"rule":        c.rules,
+		"edge":        c.edges,
+		"assign":      c.assign,
+		"revoke":      c.revoke,
+		"assignments": c.assignments,
+		"check":       c.check,
+		"explain":     c.explain,
+		"snapshot":    c.snapshot,
+		"policy":      c.policy,
+		"lint":        c.lint,
+		"audit":       c.auditLog,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown command %q", args[0]))
+	}
+	return command(args[1:])
+}
+
+// subcommand 取出子命令名，subcommands 为允许的取值
+func subcommand(command string, args []string, subcommands ...string) (string, []string, error) {
+	if len(args) > 0 {
+		for _, name := range subcommands {
+			if args[0] == name {
+				return name, args[1:], nil
+			}
+		}
+	}
+	return "", nil, usageError(fmt.Sprintf("%s: expected one of %s", command, strings.Join(subcommands, ", ")))
+}
+
+// expect 校验位置参数个数
+func expect(command string, args []string, n int, names string) error {
+	if len(args) != n {
+		return usageError(fmt.Sprintf("%s: expected %s", command, names))
+	}
+	return nil
+}
+
+// userId 十进制整数转为 int，其余保持字符串，与仓库的约定一致
+func userId(id string) interface{} {
+	if n, err := strconv.Atoi(id); err == nil && strconv.Itoa(n) == id {
+		return n
+	}
+	return id
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func (c *cli) init(args []string) error {
+	if err := expect("init", args, 0, "no arguments"); err != nil {
+		return err
+	}
+	if err := c.repo.CreateTables(); err != nil {
+		return err
+	}
+	if err := c.audit.CreateTable(); err != nil {
+		return err
+	}
+	return c.done("tables created")
+}
+
+// ---------------------- Items ---------------------------
+
+func (c *cli) lookup(name string, itemType gorbac.ItemType) (gorbac.Item, error) {
+	item := c.manager.GetItem(name)
+	if item == nil || (itemType != gorbac.NoneType && item.GetType() != itemType) {
+		return nil, fmt.Errorf("item '%s' not found", name)
+	}
+	return item, nil
+}
+
+func (c *cli) items(itemType gorbac.ItemType) func(args []string) error {
+	command := "role"
+	if itemType == gorbac.PermissionType {
+		command = "permission"
+	}
+	return func(args []string) error {
+		name, args, err := subcommand(command, args, "list", "add", "remove")
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "list":
+			return c.listItems(command, itemType, args)
+		case "add":
+			return c.addItem(command, itemType, args)
+		}
+		return c.removeItem(command, itemType, args)
+	}
+}
+
+func (c *cli) listItems(command string, itemType gorbac.ItemType, args []string) error {
+	if err := expect(command+" list", args, 0, "no arguments"); err != nil {
+		return err
+	}
+	items := make([]gorbac.Item, 0)
+	if itemType == gorbac.RoleType {
+		for _, role := range c.manager.GetRoles() {
+			items = append(items, role)
+		}
+	} else {
+		for _, permission := range c.manager.GetPermissions() {
+			items = append(items, permission)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GetName() < items[j].GetName()
+	})
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{item.GetName(), orDash(item.GetDescription()), orDash(item.GetRuleName()), formatTime(item.GetUpdateTime())})
+	}
+	return c.print(items, []string{"NAME", "DESCRIPTION", "RULE", "UPDATED"}, rows)
+}
+
+func (c *cli) addItem(command string, itemType gorbac.ItemType, args []string) error {
+	fs := flag.NewFlagSet(command+" add", flag.ContinueOnError)
+	description := fs.String("description", "", "")
+	ruleName := fs.String("rule", "", "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 1, "NAME"); err != nil {
+		return err
+	}
+	name := args[0]
+	if *ruleName != "" && c.manager.GetRule(*ruleName) == nil {
+		return fmt.Errorf("rule '%s' not found", *ruleName)
+	}
+	if c.manager.GetItem(name) != nil {
+		return fmt.Errorf("item '%s' already exists", name)
+	}
+	now := time.Now()
+	var item gorbac.Item
+	if itemType == gorbac.RoleType {
+		item = gorbac.NewRole(name, *description, *ruleName, "", now, now)
+	} else {
+		item = gorbac.NewPermission(name, *description, *ruleName, "", now, now)
+	}
+	if !c.manager.Add(item) {
+		return fmt.Errorf("add %s '%s' failed", command, name)
+	}
+	return c.done("%s '%s' added", command, name)
+}
+
+func (c *cli) removeItem(command string, itemType gorbac.ItemType, args []string) error {
+	if err := expect(command+" remove", args, 1, "NAME"); err != nil {
+		return err
+	}
+	item, err := c.lookup(args[0], itemType)
+	if err != nil {
+		return err
+	}
+	if !c.manager.Remove(item) {
+		return fmt.Errorf("remove %s '%s' failed", command, item.GetName())
+	}
+	return c.done("%s '%s' removed", command, item.GetName())
+}
+
+// ---------------------- Rules ---------------------------
+
+func (c *cli) rules(args []string) error {
+	name, args, err := subcommand("rule", args, "list", "add", "remove")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "list":
+		return c.listRules(args)
+	case "add":
+		return c.addRule(args)
+	}
+	return c.removeRule(args)
+}
+
+func (c *cli) listRules(args []string) error {
+	if err := expect("rule list", args, 0, "no arguments"); err != nil {
+		return err
+	}
+	rules := append(make([]*gorbac.Rule, 0), c.manager.GetRules()...)
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	rows := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		rows = append(rows, []string{rule.Name, rule.ExecuteName, orDash(rule.Data), formatTime(rule.UpdateTime)})
+	}
+	return c.print(rules, []string{"NAME", "EXECUTOR", "DATA", "UPDATED"}, rows)
+}
+
+func (c *cli) addRule(args []string) error {
+	fs := flag.NewFlagSet("rule add", flag.ContinueOnError)
+	executor := fs.String("executor", "", "")
+	data := fs.String("data", "", "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 1, "NAME"); err != nil {
+		return err
+	}
+	if *executor == "" {
+		return usageError("rule add: -executor is required")
+	}
+	name := args[0]
+	if c.manager.GetRule(name) != nil {
+		return fmt.Errorf("rule '%s' already exists", name)
+	}
+	now := time.Now()
+	rule := gorbac.Rule{Name: name, ExecuteName: *executor, Data: *data, CreateTime: now, UpdateTime: now}
+	if err := c.manager.ValidateRule(rule); err != nil {
+		return err
+	}
+	if !c.manager.AddRule(rule) {
+		return fmt.Errorf("add rule '%s' failed", name)
+	}
+	return c.done("rule '%s' added", name)
+}
+
+func (c *cli) removeRule(args []string) error {
+	if err := expect("rule remove", args, 1, "NAME"); err != nil {
+		return err
+	}
+	rule := c.manager.GetRule(args[0])
+	if rule == nil {
+		return fmt.Errorf("rule '%s' not found", args[0])
+	}
+	if !c.manager.RemoveRule(*rule) {
+		return fmt.Errorf("remove rule '%s' failed", rule.Name)
+	}
+	return c.done("rule '%s' removed", rule.Name)
+}
+
+// ---------------------- Edges ---------------------------
+
+func (c *cli) edges(args []string) error {
+	name, args, err := subcommand("edge", args, "list", "add", "remove")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "list":
+		return c.listEdges(args)
+	case "add":
+		return c.addEdge(args)
+	}
+	return c.removeEdge(args)
+}
+
+// listEdges 列出全部继承关系，指定 PARENT 时只列出其直接子项
+func (c *cli) listEdges(args []string) error {
+	if len(args) > 1 {
+		return usageError("edge list: expected at most one PARENT")
+	}
+	parents := make([]string, 0)
+	if len(args) == 1 {
+		if _, err := c.lookup(args[0], gorbac.NoneType); err != nil {
+			return err
+		}
+		parents = append(parents, args[0])
+	} else {
+		for _, role := range c.manager.GetRoles() {
+			parents = append(parents, role.Name)
+		}
+		for _, permission := range c.manager.GetPermissions() {
+			parents = append(parents, permission.Name)
+		}
+		sort.Strings(parents)
+	}
+	edges := make([]*gorbac.ItemChild, 0)
+	rows := make([][]string, 0)
+	for _, parent := range parents {
+		children := make([]string, 0)
+		for _, child := range c.manager.GetChildren(parent) {
+			children = append(children, child.GetName())
+		}
+		sort.Strings(children)
+		for _, child := range children {
+			edges = append(edges, gorbac.NewItemChild(parent, child))
+			rows = append(rows, []string{parent, child})
+		}
+	}
+	return c.print(edges, []string{"PARENT", "CHILD"}, rows)
+}
+
+func (c *cli) edge(command string, args []string) (gorbac.Item, gorbac.Item, error) {
+	if err := expect(command, args, 2, "PARENT CHILD"); err != nil {
+		return nil, nil, err
+	}
+	parent, err := c.lookup(args[0], gorbac.NoneType)
+	if err != nil {
+		return nil, nil, err
+	}
+	child, err := c.lookup(args[1], gorbac.NoneType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parent, child, nil
+}
+
+func (c *cli) addEdge(args []string) error {
+	parent, child, err := c.edge("edge add", args)
+	if err != nil {
+		return err
+	}
+	if c.manager.HasChild(parent, child) {
+		return fmt.Errorf("'%s' is already a child of '%s'", child.GetName(), parent.GetName())
+	}
+	if err := c.manager.AddChild(parent, child); err != nil {
+		return err
+	}
+	return c.done("'%s' added to '%s'", child.GetName(), parent.GetName())
+}
+
+func (c *cli) removeEdge(args []string) error {
+	parent, child, err := c.edge("edge remove", args)
+	if err != nil {
+		return err
+	}
+	if !c.manager.HasChild(parent, child) {
+		return fmt.Errorf("'%s' is not a child of '%s'", child.GetName(), parent.GetName())
+	}
+	if !c.manager.RemoveChild(parent, child) {
+		return fmt.Errorf("remove child '%s' failed", child.GetName())
+	}
+	return c.done("'%s' removed from '%s'", child.GetName(), parent.GetName())
+}
+
+// ---------------------- Assignments ---------------------------
+
+// assign 已存在的分配保持不变
+func (c *cli) assign(args []string) error {
+	if len(args) < 2 {
+		return usageError("assign: expected USER ITEM...")
+	}
+	id := userId(args[0])
+	items := make([]gorbac.Item, 0, len(args)-1)
+	for _, name := range args[1:] {
+		item, err := c.lookup(name, gorbac.NoneType)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	for _, item := range items {
+		if c.manager.GetAssignment(item.GetName(), id) != nil {
+			continue
+		}
+		if c.manager.Assign(item, id) == nil {
+			return fmt.Errorf("assign '%s' to user %v failed", item.GetName(), id)
+		}
+	}
+	return c.done("user %v assigned %s", id, strings.Join(args[1:], ", "))
+}
+
+func (c *cli) revoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	all := fs.Bool("all", false, "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *all {
+		if err := expect("revoke -all", args, 1, "USER"); err != nil {
+			return err
+		}
+		id := userId(args[0])
+		if !c.manager.RevokeAll(id) {
+			return fmt.Errorf("revoke all assignments of user %v failed", id)
+		}
+		return c.done("all assignments of user %v revoked", id)
+	}
+	if len(args) < 2 {
+		return usageError("revoke: expected USER ITEM... or -all USER")
+	}
+	id := userId(args[0])
+	for _, name := range args[1:] {
+		item := c.manager.GetItem(name)
+		if item == nil || c.manager.GetAssignment(name, id) == nil {
+			return fmt.Errorf("user %v is not assigned to '%s'", id, name)
+		}
+		if !c.manager.Revoke(item, id) {
+			return fmt.Errorf("revoke '%s' from user %v failed", name, id)
+		}
+	}
+	return c.done("user %v revoked %s", id, strings.Join(args[1:], ", "))
+}
+
+func (c *cli) assignments(args []string) error {
+	if err := expect("assignments", args, 1, "USER"); err != nil {
+		return err
+	}
+	assignments := make([]*gorbac.Assignment, 0)
+	for _, assignment := range c.manager.GetAssignments(userId(args[0])) {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].ItemName < assignments[j].ItemName
+	})
+	rows := make([][]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		rows = append(rows, []string{assignment.ItemName, formatTime(assignment.CreateTime)})
+	}
+	return c.print(assignments, []string{"ITEM", "ASSIGNED"}, rows)
+}
+
+// ---------------------- Check ---------------------------
+
+// paramFlags 可重复的 -param key=value，作为规则参数，十进制整数与 true/false 转为对应类型
+type paramFlags map[string]interface{}
+
+func (params paramFlags) String() string {
+	return ""
+}
+
+func (params paramFlags) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	key, raw := value[:i], value[i+1:]
+	if raw == "true" || raw == "false" {
+		params[key] = raw == "true"
+	} else {
+		params[key] = userId(raw)
+	}
+	return nil
+}
+
+// decisionArgs 解析 check 与 explain 的参数
+func decisionArgs(command string, args []string) (context.Context, interface{}, string, error) {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	params := make(paramFlags)
+	fs.Var(params, "param", "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := expect(command, args, 2, "USER PERMISSION"); err != nil {
+		return nil, nil, "", err
+	}
+	return gorbac.WithParams(context.Background(), params), userId(args[0]), args[1], nil
+}
+
+// check 未授权时退出码为 1
+func (c *cli) check(args []string) error {
+	ctx, id, permission, err := decisionArgs("check", args)
+	if err != nil {
+		return err
+	}
+	allowed := c.manager.CheckAccess(ctx, id, permission)
+	result := "denied"
+	if allowed {
+		result = "allowed"
+	}
+	err = c.print(map[string]interface{}{"user_id": id, "permission": permission, "allowed": allowed},
+		[]string{"USER", "PERMISSION", "RESULT"}, [][]string{{fmt.Sprint(id), permission, result}})
+	if err == nil && !allowed {
+		return errFailure
+	}
+	return err
+}
+
+func (c *cli) explain(args []string) error {
+	ctx, id, permission, err := decisionArgs("explain", args)
+	if err != nil {
+		return err
+	}
+	decision := c.manager.Explain(ctx, id, permission)
+	if c.json {
+		return c.print(decision, nil, nil)
+	}
+	result := "denied"
+	if decision.Allowed {
+		result = "allowed"
+	}
+	fmt.Fprintf(c.out, "user %v %s %s (%s)\n", decision.UserId, result, decision.Permission, decision.Duration)
+	if len(decision.Path) > 0 {
+		fmt.Fprintf(c.out, "path: %s\n", strings.Join(decision.Path, " -> "))
+	}
+	if len(decision.Rules) == 0 {
+		return nil
+	}
+	fmt.Fprintln(c.out)
+	rows := make([][]string, 0, len(decision.Rules))
+	for _, outcome := range decision.Rules {
+		rows = append(rows, []string{outcome.Item, orDash(outcome.Rule), orDash(outcome.Executor), string(outcome.Outcome), strconv.FormatBool(outcome.Allowed), orDash(outcome.Error)})
+	}
+	return c.print(nil, []string{"ITEM", "RULE", "EXECUTOR", "OUTCOME", "ALLOWED", "ERROR"}, rows)
+}
+
+// ---------------------- Snapshot & Policy ---------------------------
+
+// output 打开 -file 指定的文件，缺省为标准输出
+func (c *cli) output(path string) (io.Writer, func() error, error) {
+	if path == "" || path == "-" {
+		return c.out, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func (c *cli) snapshot(args []string) error {
+	name, args, err := subcommand("snapshot", args, "export", "import")
+	if err != nil {
+		return err
+	}
+	if name == "export" {
+		return c.exportSnapshot(args)
+	}
+	return c.importSnapshot(args)
+}
+
+func (c *cli) exportSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot export", flag.ContinueOnError)
+	file := fs.String("file", "", "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 0, "no arguments"); err != nil {
+		return err
+	}
+	w, closer, err := c.output(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := c.manager.ExportSnapshot(w); err != nil {
+		closer()
+		return err
+	}
+	return closer()
+}
+
+// importSnapshot FILE 为 "-" 时从标准输入读取，默认合并，-replace 时清空后导入
+func (c *cli) importSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot import", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 1, "FILE"); err != nil {
+		return err
+	}
+	data, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	options := gorbac.ImportOptions{Mode: gorbac.ImportMerge}
+	if *replace {
+		options.Mode = gorbac.ImportReplace
+	}
+	stats, err := c.manager.ImportSnapshot(bytes.NewReader(data), options)
+	if err != nil {
+		return err
+	}
+	return c.print(stats, []string{"RULES", "ITEMS", "CHILDREN", "ASSIGNMENTS"},
+		[][]string{{strconv.Itoa(stats.Rules), strconv.Itoa(stats.Items), strconv.Itoa(stats.Children), strconv.Itoa(stats.Assignments)}})
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (c *cli) policy(args []string) error {
+	name, args, err := subcommand("policy", args, "export", "plan", "apply")
+	if err != nil {
+		return err
+	}
+	if name == "export" {
+		return c.exportPolicy(args)
+	}
+	return c.syncPolicy(name, args)
+}
+
+// exportPolicy 格式缺省时按 -file 的扩展名推断，否则为 yaml
+func (c *cli) exportPolicy(args []string) error {
+	fs := flag.NewFlagSet("policy export", flag.ContinueOnError)
+	assignments := fs.Bool("assignments", false, "")
+	format := fs.String("format", "", "")
+	file := fs.String("file", "", "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 0, "no arguments"); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = "yaml"
+		if strings.EqualFold(filepath.Ext(*file), ".json") {
+			*format = "json"
+		}
+	}
+	policy, err := c.manager.ExportPolicy(*assignments)
+	if err != nil {
+		return err
+	}
+	data, err := policy.Marshal(*format)
+	if err != nil {
+		return err
+	}
+	w, closer, err := c.output(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		closer()
+		return err
+	}
+	return closer()
+}
+
+// syncPolicy plan 只输出计划，apply 写入仓库
+func (c *cli) syncPolicy(name string, args []string) error {
+	fs := flag.NewFlagSet("policy "+name, flag.ContinueOnError)
+	keepUnmanaged := fs.Bool("keep-unmanaged", false, "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 1, "FILE"); err != nil {
+		return err
+	}
+	policy, err := gorbac.LoadPolicyFile(args[0])
+	if err != nil {
+		return err
+	}
+	options := gorbac.SyncOptions{DryRun: name == "plan", KeepUnmanaged: *keepUnmanaged}
+	var plan *gorbac.PolicyPlan
+	if options.DryRun {
+		plan, err = c.manager.PlanPolicy(policy, options)
+	} else {
+		plan, err = c.manager.ApplyPolicy(policy, options)
+	}
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.print(plan, nil, nil)
+	}
+	_, err = fmt.Fprint(c.out, plan.String())
+	return err
+}
+
+// ---------------------- Lint ---------------------------
+
+// lint 存在 error 级别的问题时退出码为 1
+func (c *cli) lint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 0, "no arguments"); err != nil {
+		return err
+	}
+	findings, err := c.manager.Lint(gorbac.LintOptions{Fix: *fix})
+	if err != nil {
+		return err
+	}
+	findings = append(make([]gorbac.LintFinding, 0), findings...)
+	rows := make([][]string, 0, len(findings))
+	failed := false
+	for _, finding := range findings {
+		status := "-"
+		if finding.Fixed {
+			status = "fixed"
+		} else if finding.Fixable {
+			status = "fixable"
+		}
+		failed = failed || (finding.Severity == gorbac.LintError && !finding.Fixed)
+		rows = append(rows, []string{string(finding.Severity), finding.Code, orDash(finding.Item), finding.Message, status})
+	}
+	if err := c.print(findings, []string{"SEVERITY", "CODE", "ITEM", "MESSAGE", "STATUS"}, rows); err != nil {
+		return err
+	}
+	if failed {
+		return errFailure
+	}
+	return nil
+}
+
+// ---------------------- Audit ---------------------------
+
+// auditLog 按操作人、item 或用户查询审计记录，按时间倒序
+func (c *cli) auditLog(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	actor := fs.String("actor", "", "")
+	item := fs.String("item", "", "")
+	user := fs.String("user", "", "")
+	operation := fs.String("operation", "", "")
+	limit := fs.Int("limit", 50, "")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expect(fs.Name(), args, 0, "no arguments"); err != nil {
+		return err
+	}
+	filter := gorbac.AuditFilter{Actor: *actor, Item: *item, Operation: gorbac.AuditOperation(*operation), Limit: *limit}
+	if *user != "" {
+		filter.UserId = userId(*user)
+	}
+	entries, err := c.manager.QueryAudit(filter)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		user := "-"
+		if entry.UserId != nil {
+			user = fmt.Sprint(entry.UserId)
+		}
+		target := entry.Item
+		if entry.Child != "" {
+			target += " -> " + entry.Child
+		}
+		rows = append(rows, []string{formatTime(entry.Time), orDash(entry.Actor), string(entry.Operation), orDash(target), user})
+	}
+	return c.print(entries, []string{"TIME", "ACTOR", "OPERATION", "TARGET", "USER"}, rows)
+}
@@ -0,0 +1,187 @@
+// Command gorbac 管理 SQL 仓库中的角色、权限、规则、继承关系与分配，
+// 并提供 check、explain、快照与策略文件的导入导出以及 lint。
+//
+//	gorbac -driver sqlite -dsn rbac.db init
+//	gorbac -dsn rbac.db role add -description 编辑 editor
+//	gorbac -dsn rbac.db -o json explain 42 posts:edit -param owner_id=42
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/kordar/gorbac"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const usage = `usage: gorbac [-driver name] [-dsn dsn] [-actor name] [-o table|json] <command> [arguments]
+
+global flags (also read from GORBAC_DRIVER, GORBAC_DSN and GORBAC_ACTOR):
+  -driver   sqlite, mysql or postgres (default sqlite)
+  -dsn      data source name
+  -actor    actor recorded in the audit log (default $USER)
+  -o        output format, table or json (default table)
+
+commands:
+  init                                        create the tables, including the audit table
+  role list | add [-description d] [-rule r] NAME | remove NAME
+  permission list | add [-description d] [-rule r] NAME | remove NAME
+  rule list | add -executor e [-data d] NAME | remove NAME
+  edge list [PARENT] | add PARENT CHILD | remove PARENT CHILD
+  assign USER ITEM...
+  revoke USER ITEM... | revoke -all USER
+  assignments USER
+  check [-param k=v]... USER PERMISSION       exit status 1 when denied
+  explain [-param k=v]... USER PERMISSION
+  snapshot export [-file f] | import [-replace] FILE
+  policy export [-assignments] [-format yaml|json] [-file f]
+  policy plan [-keep-unmanaged] FILE | apply [-keep-unmanaged] FILE
+  lint [-fix]
+  audit [-actor a] [-item i] [-user u] [-operation op] [-limit n]
+`
+
+// usageError 参数错误，退出码 2
+type usageError string
+
+func (err usageError) Error() string {
+	return string(err)
+}
+
+// errFailure check 未授权或 lint 发现 error 时以退出码 1 结束，不再输出错误信息
+var errFailure = errors.New("failure")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	global := flag.NewFlagSet("gorbac", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+	driver := global.String("driver", envOr("GORBAC_DRIVER", "sqlite"), "")
+	dsn := global.String("dsn", os.Getenv("GORBAC_DSN"), "")
+	actor := global.String("actor", envOr("GORBAC_ACTOR", os.Getenv("USER")), "")
+	output := global.String("o", "table", "")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "gorbac: unknown output format %q\n", *output)
+		return 2
+	}
+
+	dialect, ok := dialects[*driver]
+	if !ok {
+		fmt.Fprintf(stderr, "gorbac: unsupported driver %q\n", *driver)
+		return 2
+	}
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	options := gorbac.SQLOptions{Dialect: dialect}
+	repo := gorbac.NewSQLRepository(db, options)
+	audit := gorbac.NewSQLAuditSink(db, options)
+	manager := gorbac.NewDefaultManager(repo, false)
+	manager.SetAuditSink(audit)
+
+	// 写操作以 -actor 为操作人，并记录执行的命令与主机名
+	metadata := map[string]string{"command": strings.Join(global.Args(), " ")}
+	if hostname, err := os.Hostname(); err == nil {
+		metadata["hostname"] = hostname
+	}
+	ctx := gorbac.WithAuditMetadata(gorbac.WithActor(context.Background(), *actor), metadata)
+	c := &cli{repo: repo, audit: audit, manager: manager.WithContext(ctx), out: stdout, json: *output == "json"}
+	err = c.dispatch(global.Args())
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case usageError:
+		fmt.Fprintf(stderr, "gorbac: %s\n\n%s", e, usage)
+		return 2
+	}
+	if err != errFailure {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+	}
+	return 1
+}
+
+var dialects = map[string]gorbac.SQLDialect{
+	"sqlite":   gorbac.DialectSQLite,
+	"mysql":    gorbac.DialectMySQL,
+	"postgres": gorbac.DialectPostgres,
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+type cli struct {
+	repo    *gorbac.SQLRepository
+	audit   *gorbac.SQLAuditSink
+	manager *gorbac.AuditedManager
+	out     io.Writer
+	json    bool
+}
+
+// parse 解析子命令参数，允许选项与位置参数交错
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError(fmt.Sprintf("%s: %v", fs.Name(), err))
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// print 以 JSON 输出 value，或以表格输出 header 与 rows
+func (c *cli) print(value interface{}, header []string, rows [][]string) error {
+	if c.json {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// done 输出写操作的结果
+func (c *cli) done(format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	if c.json {
+		return c.print(map[string]string{"result": message}, nil, nil)
+	}
+	_, err := fmt.Fprintln(c.out, message)
+	return err
+}
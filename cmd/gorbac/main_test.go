@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBuild 不使用 go.work 构建命令行工具，确认模块自身的 go.mod 可以解析全部依赖，并在 SQLite 上跑通 init 与 role
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the binary")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "gorbac")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Env = append(os.Environ(), "GOWORK=off")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, output)
+	}
+
+	dsn := filepath.Join(dir, "rbac.db")
+	tests := []struct {
+		args   []string
+		status int
+		output string
+	}{
+		{[]string{"init"}, 0, ""},
+		{[]string{"role", "add", "-description", "editors", "editor"}, 0, "editor"},
+		{[]string{"role", "list"}, 0, "editors"},
+		{[]string{"bogus"}, 2, "usage: gorbac"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(binary, append([]string{"-driver", "sqlite", "-dsn", dsn}, tt.args...)...)
+		cmd.Env = append(os.Environ(), "GORBAC_ACTOR=test")
+		output, _ := cmd.CombinedOutput()
+		if status := cmd.ProcessState.ExitCode(); status != tt.status || !strings.Contains(string(output), tt.output) {
+			t.Errorf("gorbac %s = (%d, %q), want (%d, containing %q)", strings.Join(tt.args, " "), status, output, tt.status, tt.output)
+		}
+	}
+}
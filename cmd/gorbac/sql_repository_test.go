@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+// newSQLiteRepository 在临时目录的 SQLite 文件上建表，SQLRepository 的测试依赖此处导入的驱动
+func newSQLiteRepository(t *testing.T) *gorbac.SQLRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "rbac.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := gorbac.NewSQLRepository(db, gorbac.SQLOptions{Dialect: gorbac.DialectSQLite})
+	if err := repo.CreateTables(); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(items []gorbac.Item) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.GetName())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func childPairs(t *testing.T, repo gorbac.AuthRepository) []string {
+	t.Helper()
+	children, err := repo.FindChildrenList()
+	must(t, err)
+	pairs := make([]string, 0, len(children))
+	for _, child := range children {
+		pairs = append(pairs, child.Parent+">"+child.Child)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+func assignmentPairs(t *testing.T, repo gorbac.AuthRepository) []string {
+	t.Helper()
+	assignments, err := repo.GetAllAssignment()
+	must(t, err)
+	pairs := make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		pairs = append(pairs, reflect.TypeOf(assignment.UserId).String()+":"+assignment.ItemName)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// seed admin -> editor -> posts:edit，用户 7 分配 admin，用户 bob 分配 editor
+func seed(t *testing.T, repo gorbac.AuthRepository) {
+	t.Helper()
+	now := time.Unix(1700000000, 0)
+	must(t, repo.AddRule(gorbac.Rule{Name: "owner", ExecuteName: "owner", Data: `{"field":"author_id"}`, CreateTime: now, UpdateTime: now}))
+	must(t, repo.AddItem(gorbac.NewRole("admin", "administrators", "", "", now, now)))
+	must(t, repo.AddItem(gorbac.NewRole("editor", "", "owner", "", now, now)))
+	must(t, repo.AddItem(gorbac.NewPermission("posts:edit", "", "", "audit", now, now)))
+	must(t, repo.AddItemChild(*gorbac.NewItemChild("admin", "editor")))
+	must(t, repo.AddItemChild(*gorbac.NewItemChild("editor", "posts:edit")))
+	must(t, repo.Assigns(gorbac.NewAssignment(7, "admin"), gorbac.NewAssignment("bob", "editor")))
+}
+
+func TestSQLRepository(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutate      func(t *testing.T, repo *gorbac.SQLRepository)
+		items       []string
+		children    []string
+		assignments []string
+	}{
+		{
+			name:        "seeded",
+			mutate:      func(t *testing.T, repo *gorbac.SQLRepository) {},
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"admin>editor", "editor>posts:edit"},
+			assignments: []string{"int:admin", "string:editor"},
+		},
+		{
+			name: "rename item cascades",
+			mutate: func(t *testing.T, repo *gorbac.SQLRepository) {
+				now := time.Now()
+				must(t, repo.UpdateItem("editor", gorbac.NewRole("writer", "", "owner", "", now, now)))
+			},
+			items:       []string{"admin", "posts:edit", "writer"},
+			children:    []string{"admin>writer", "writer>posts:edit"},
+			assignments: []string{"int:admin", "string:writer"},
+		},
+		{
+			name: "rename onto existing item fails",
+			mutate: func(t *testing.T, repo *gorbac.SQLRepository) {
+				now := time.Now()
+				if err := repo.UpdateItem("editor", gorbac.NewRole("admin", "", "", "", now, now)); err == nil {
+					t.Error("UpdateItem onto an existing name succeeded")
+				}
+			},
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"admin>editor", "editor>posts:edit"},
+			assignments: []string{"int:admin", "string:editor"},
+		},
+		{
+			name:        "remove item cascades",
+			mutate:      func(t *testing.T, repo *gorbac.SQLRepository) { must(t, repo.RemoveItem("editor")) },
+			items:       []string{"admin", "posts:edit"},
+			children:    []string{},
+			assignments: []string{"int:admin"},
+		},
+		{
+			name:        "remove one child",
+			mutate:      func(t *testing.T, repo *gorbac.SQLRepository) { must(t, repo.RemoveChild("admin", "editor")) },
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"editor>posts:edit"},
+			assignments: []string{"int:admin", "string:editor"},
+		},
+		{
+			name:        "revoke all of a user",
+			mutate:      func(t *testing.T, repo *gorbac.SQLRepository) { must(t, repo.RemoveAllAssignmentByUser(7)) },
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"admin>editor", "editor>posts:edit"},
+			assignments: []string{"string:editor"},
+		},
+		{
+			name: "failed transaction rolls back",
+			mutate: func(t *testing.T, repo *gorbac.SQLRepository) {
+				err := repo.Transaction(func(tx gorbac.AuthRepository) error {
+					must(t, tx.RemoveItem("admin"))
+					return errors.New("abort")
+				})
+				if err == nil || err.Error() != "abort" {
+					t.Errorf("Transaction error = %v, want abort", err)
+				}
+			},
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"admin>editor", "editor>posts:edit"},
+			assignments: []string{"int:admin", "string:editor"},
+		},
+		{
+			name: "duplicate assignment fails",
+			mutate: func(t *testing.T, repo *gorbac.SQLRepository) {
+				if err := repo.Assign(*gorbac.NewAssignment(7, "admin")); err == nil {
+					t.Error("duplicate Assign succeeded")
+				}
+			},
+			items:       []string{"admin", "editor", "posts:edit"},
+			children:    []string{"admin>editor", "editor>posts:edit"},
+			assignments: []string{"int:admin", "string:editor"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSQLiteRepository(t)
+			seed(t, repo)
+			tt.mutate(t, repo)
+
+			items, err := repo.FindAllItems()
+			must(t, err)
+			if got := names(items); !reflect.DeepEqual(got, tt.items) {
+				t.Errorf("items = %v, want %v", got, tt.items)
+			}
+			if got := childPairs(t, repo); !reflect.DeepEqual(got, tt.children) {
+				t.Errorf("children = %v, want %v", got, tt.children)
+			}
+			if got := assignmentPairs(t, repo); !reflect.DeepEqual(got, tt.assignments) {
+				t.Errorf("assignments = %v, want %v", got, tt.assignments)
+			}
+		})
+	}
+}
+
+func TestSQLRepositoryFields(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	seed(t, repo)
+
+	editor, err := repo.GetItem("editor")
+	must(t, err)
+	if editor.GetType() != gorbac.RoleType || editor.GetRuleName() != "owner" || editor.GetCreateTime().Unix() != 1700000000 {
+		t.Errorf("editor = %+v", editor)
+	}
+	edit, err := repo.GetItem("posts:edit")
+	must(t, err)
+	if edit.GetType() != gorbac.PermissionType || edit.GetExecuteName() != "audit" {
+		t.Errorf("posts:edit = %+v", edit)
+	}
+	if _, err := repo.GetItem("missing"); err == nil {
+		t.Error("GetItem(missing) succeeded")
+	}
+
+	rule, err := repo.GetRule("owner")
+	must(t, err)
+	if rule.ExecuteName != "owner" || rule.Data != `{"field":"author_id"}` {
+		t.Errorf("rule = %+v", rule)
+	}
+	now := time.Now()
+	must(t, repo.UpdateRule("owner", gorbac.Rule{Name: "author", ExecuteName: "owner", CreateTime: now, UpdateTime: now}))
+	if editor, _ = repo.GetItem("editor"); editor.GetRuleName() != "author" {
+		t.Errorf("rule rename: editor rule = %q, want author", editor.GetRuleName())
+	}
+	must(t, repo.RemoveAllRules())
+	if editor, _ = repo.GetItem("editor"); editor.GetRuleName() != "" {
+		t.Errorf("RemoveAllRules: editor rule = %q, want none", editor.GetRuleName())
+	}
+
+	roles, err := repo.FindRolesByUser("bob")
+	must(t, err)
+	if got := names(roles); !reflect.DeepEqual(got, []string{"editor"}) {
+		t.Errorf("roles of bob = %v, want [editor]", got)
+	}
+	if !repo.HasChild("admin", "editor") || repo.HasChild("editor", "admin") {
+		t.Error("HasChild reports the wrong direction")
+	}
+}
+
+func TestSQLRepositoryManager(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	manager := gorbac.NewDefaultManager(repo, true)
+	now := time.Now()
+	admin := gorbac.NewRole("admin", "", "", "", now, now)
+	viewer := gorbac.NewRole("viewer", "", "", "", now, now)
+	view := gorbac.NewPermission("posts:view", "", "", "", now, now)
+	for _, item := range []gorbac.Item{admin, viewer, view} {
+		if !manager.Add(item) {
+			t.Fatalf("add %s failed", item.GetName())
+		}
+	}
+	must(t, manager.AddChild(admin, viewer))
+	must(t, manager.AddChild(viewer, view))
+	manager.Assign(admin, 1)
+
+	ctx := context.Background()
+	if !manager.CheckAccess(ctx, 1, "posts:view") {
+		t.Fatal("posts:view denied")
+	}
+	manager.Revoke(admin, 1)
+	if manager.CheckAccess(ctx, 1, "posts:view") {
+		t.Error("posts:view allowed after Revoke")
+	}
+
+	// 快照可在 SQL 与内存仓库之间往返
+	var buf bytes.Buffer
+	stats, err := gorbac.ExportSnapshot(repo, &buf)
+	must(t, err)
+	memory := gorbac.NewMemoryRepository()
+	imported, err := gorbac.ImportSnapshot(memory, &buf, gorbac.ImportOptions{})
+	must(t, err)
+	if *imported != *stats {
+		t.Errorf("imported %+v, exported %+v", *imported, *stats)
+	}
+	if got, want := childPairs(t, memory), childPairs(t, repo); !reflect.DeepEqual(got, want) {
+		t.Errorf("memory children = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package gorbac
+
+import "sync"
+
+// bitset 定长位图，按 item 下标记录授权结果
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+func (b bitset) or(other bitset) {
+	for i := range other {
+		b[i] |= other[i]
+	}
+}
+
+// compiledPolicy 预计算的角色/权限传递闭包，每个缓存代（generation）构建一次。
+//
+// 未绑定规则/执行器的 item 组成的子图被展开成位图，用户的所有分配在首次校验时
+// 合并成一个授权位图，之后的校验只需一次位运算；凡是判定结果可能受规则影响的
+// item（自身或沿未绑定规则的父链能到达绑定规则的 item）都交回递归遍历处理。
+type compiledPolicy struct {
+	generation uint64
+	index      map[string]int
+	// gated items bound to a rule or an executor
+	gated bitset
+	// guarded items whose decision may depend on a gated ancestor
+	guarded bitset
+	// closure[i] is the set of items reachable from i through ungated items, nil when i is gated
+	closure  []bitset
+	defaults bitset
+	// userId => *userGrants
+	users sync.Map
+}
+
+// userGrants 用户的授权位图，version 为计算时所依据的分配版本（DefaultManager.assignmentVersion）
+type userGrants struct {
+	version uint64
+	set     bitset
+}
+
+func compilePolicy(generation uint64, items map[string]Item, parents map[string][]string, defaultRoles map[string]*Role) *compiledPolicy {
+	n := len(items)
+	policy := &compiledPolicy{
+		generation: generation,
+		index:      make(map[string]int, n),
+		gated:      newBitset(n),
+		guarded:    newBitset(n),
+		closure:    make([]bitset, n),
+		defaults:   newBitset(n),
+	}
+
+	names := make([]string, 0, n)
+	for name, item := range items {
+		policy.index[name] = len(names)
+		names = append(names, name)
+		if item.GetRuleName() != "" || item.GetExecuteName() != "" {
+			policy.gated.set(policy.index[name])
+		}
+	}
+
+	children := make([][]int, n)
+	for child, list := range parents {
+		c, ok := policy.index[child]
+		if !ok {
+			continue
+		}
+		for _, parent := range list {
+			if p, ok := policy.index[parent]; ok {
+				children[p] = append(children[p], c)
+			}
+		}
+	}
+
+	// 0 = unvisited, 1 = visiting, 2 = done
+	state := make([]uint8, n)
+	var expand func(i int) bitset
+	expand = func(i int) bitset {
+		if state[i] != 0 {
+			return policy.closure[i]
+		}
+		state[i] = 1
+		set := newBitset(n)
+		set.set(i)
+		for _, c := range children[i] {
+			if policy.gated.has(c) || state[c] == 1 {
+				continue
+			}
+			set.or(expand(c))
+		}
+		policy.closure[i] = set
+		state[i] = 2
+		return set
+	}
+
+	guardState := make([]uint8, n)
+	var guard func(i int) bool
+	guard = func(i int) bool {
+		switch guardState[i] {
+		case 1:
+			return false
+		case 2:
+			return policy.guarded.has(i)
+		}
+		guardState[i] = 1
+		guarded := policy.gated.has(i)
+		for _, parent := range parents[names[i]] {
+			if p, ok := policy.index[parent]; ok && guard(p) {
+				guarded = true
+			}
+		}
+		if guarded {
+			policy.guarded.set(i)
+		}
+		guardState[i] = 2
+		return guarded
+	}
+
+	for i := 0; i < n; i++ {
+		if !policy.gated.has(i) {
+			expand(i)
+		}
+		guard(i)
+	}
+
+	for name := range defaultRoles {
+		policy.grant(policy.defaults, name)
+	}
+
+	return policy
+}
+
+func (policy *compiledPolicy) grant(set bitset, name string) {
+	if i, ok := policy.index[name]; ok && policy.closure[i] != nil {
+		set.or(policy.closure[i])
+	}
+}
+
+// grantsOf 返回用户的授权位图，assignments 为分配版本 version 时读取的分配。
+// 缓存的位图版本不同时重新计算；已缓存更新版本的位图时不覆盖，
+// 避免并发的 Revoke 之后仍以撤销前读取的分配写回旧位图。
+func (policy *compiledPolicy) grantsOf(userId interface{}, version uint64, assignments map[string]*Assignment) bitset {
+	cached, ok := policy.users.Load(userId)
+	if ok && cached.(*userGrants).version == version {
+		return cached.(*userGrants).set
+	}
+	set := newBitset(len(policy.index))
+	set.or(policy.defaults)
+	for name := range assignments {
+		policy.grant(set, name)
+	}
+	if !ok || cached.(*userGrants).version < version {
+		policy.users.Store(userId, &userGrants{version: version, set: set})
+	}
+	return set
+}
+
+func (policy *compiledPolicy) forget(userId interface{}) {
+	policy.users.Delete(userId)
+}
+
+func (policy *compiledPolicy) forgetAll() {
+	policy.users.Range(func(key, _ interface{}) bool {
+		policy.users.Delete(key)
+		return true
+	})
+}
+
+// checkAccess 返回判定结果；decided 为 false 时需回退到递归遍历（涉及规则）
+func (policy *compiledPolicy) checkAccess(userId interface{}, itemName string, version uint64, assignments map[string]*Assignment) (allowed bool, decided bool) {
+	i, ok := policy.index[itemName]
+	if !ok {
+		return false, true
+	}
+	if policy.gated.has(i) {
+		return false, false
+	}
+	if policy.grantsOf(userId, version, assignments).has(i) {
+		return true, true
+	}
+	if policy.guarded.has(i) {
+		return false, false
+	}
+	return false, true
+}
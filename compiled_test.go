@@ -0,0 +1,182 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPolicy() *compiledPolicy {
+	now := time.Now()
+	items := map[string]Item{
+		"admin":      NewRole("admin", "", "", "", now, now),
+		"editor":     NewRole("editor", "", "", "", now, now),
+		"owner":      NewRole("owner", "", "isOwner", "", now, now),
+		"guest":      NewRole("guest", "", "", "", now, now),
+		"posts:edit": NewPermission("posts:edit", "", "", "", now, now),
+		"posts:own":  NewPermission("posts:own", "", "", "", now, now),
+		"posts:view": NewPermission("posts:view", "", "", "", now, now),
+	}
+	parents := map[string][]string{
+		"editor":     {"admin"},
+		"owner":      {"editor"},
+		"posts:edit": {"editor"},
+		"posts:own":  {"owner"},
+		"posts:view": {"guest", "editor"},
+	}
+	defaults := map[string]*Role{"guest": items["guest"].(*Role)}
+	return compilePolicy(1, items, parents, defaults)
+}
+
+func TestCompiledPolicyCheckAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		assignments []string
+		item        string
+		allowed     bool
+		decided     bool
+	}{
+		{"direct", []string{"posts:edit"}, "posts:edit", true, true},
+		{"transitive", []string{"admin"}, "posts:edit", true, true},
+		{"default role", nil, "posts:view", true, true},
+		{"not granted", []string{"guest"}, "posts:edit", false, true},
+		{"unknown item", []string{"admin"}, "missing", false, true},
+		{"gated item", []string{"admin"}, "owner", false, false},
+		{"below gated item", []string{"admin"}, "posts:own", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testPolicy()
+			assignments := make(map[string]*Assignment)
+			for _, name := range tt.assignments {
+				assignments[name] = NewAssignment(1, name)
+			}
+			allowed, decided := policy.checkAccess(1, tt.item, 1, assignments)
+			if allowed != tt.allowed || decided != tt.decided {
+				t.Errorf("checkAccess(%s) = (%v, %v), want (%v, %v)", tt.item, allowed, decided, tt.allowed, tt.decided)
+			}
+		})
+	}
+}
+
+func TestCompiledPolicyGrantsVersion(t *testing.T) {
+	policy := testPolicy()
+	admin := map[string]*Assignment{"admin": NewAssignment(1, "admin")}
+	none := map[string]*Assignment{}
+
+	tests := []struct {
+		name        string
+		version     uint64
+		assignments map[string]*Assignment
+		edit        bool
+	}{
+		{"load at version 1", 1, admin, true},
+		{"revoked at version 2", 2, none, false},
+		// 撤销前读取的分配晚于撤销写回，不得覆盖版本 2 的位图
+		{"stale load from version 1", 1, admin, true},
+		{"version 2 still revoked", 2, admin, false},
+	}
+	for _, tt := range tests {
+		set := policy.grantsOf(1, tt.version, tt.assignments)
+		if got := set.has(policy.index["posts:edit"]); got != tt.edit {
+			t.Errorf("%s: posts:edit granted = %v, want %v", tt.name, got, tt.edit)
+		}
+	}
+}
+
+func TestCompiledRevokeDuringCheck(t *testing.T) {
+	for round := 0; round < 50; round++ {
+		manager := newTestManager(t, true)
+		manager.EnableCompiled(true)
+		ctx := context.Background()
+		admin := manager.GetRole("admin")
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						manager.CheckAccess(ctx, 1, "posts:edit")
+					}
+				}
+			}()
+		}
+		manager.Revoke(admin, 1)
+		close(stop)
+		wg.Wait()
+
+		if manager.CheckAccess(ctx, 1, "posts:edit") {
+			t.Fatalf("round %d: posts:edit allowed after Revoke", round)
+		}
+	}
+}
+
+// benchmarkManager 构建 deep（n 层角色链）或 wide（一个角色下 n 个子角色）的层级，
+// 返回管理器与待校验的权限，用户 1 分配根角色
+func benchmarkManager(b *testing.B, shape string, n int) (*DefaultManager, string) {
+	now := time.Now()
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	root := NewRole("role:0", "", "", "", now, now)
+	manager.Add(root)
+	var permission string
+	switch shape {
+	case "deep":
+		parent := Item(root)
+		for i := 1; i < n; i++ {
+			role := NewRole(fmt.Sprintf("role:%d", i), "", "", "", now, now)
+			manager.Add(role)
+			_ = manager.AddChild(parent, role)
+			parent = role
+		}
+		perm := NewPermission("perm:leaf", "", "", "", now, now)
+		manager.Add(perm)
+		_ = manager.AddChild(parent, perm)
+		permission = perm.GetName()
+	case "wide":
+		for i := 1; i < n; i++ {
+			role := NewRole(fmt.Sprintf("role:%d", i), "", "", "", now, now)
+			perm := NewPermission(fmt.Sprintf("perm:%d", i), "", "", "", now, now)
+			manager.Add(role)
+			manager.Add(perm)
+			_ = manager.AddChild(root, role)
+			_ = manager.AddChild(role, perm)
+			permission = perm.GetName()
+		}
+	}
+	manager.Assign(root, 1)
+	return manager, permission
+}
+
+func BenchmarkCheckAccess(b *testing.B) {
+	ctx := context.Background()
+	for _, shape := range []string{"deep", "wide"} {
+		for _, n := range []int{10, 100} {
+			for _, compiled := range []bool{false, true} {
+				engine := "walk"
+				if compiled {
+					engine = "compiled"
+				}
+				b.Run(fmt.Sprintf("%s-%d/%s", shape, n, engine), func(b *testing.B) {
+					manager, permission := benchmarkManager(b, shape, n)
+					manager.EnableCompiled(compiled)
+					if !manager.CheckAccess(ctx, 1, permission) {
+						b.Fatalf("%s not granted", permission)
+					}
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						manager.CheckAccess(ctx, 1, permission)
+					}
+				})
+			}
+		}
+	}
+}
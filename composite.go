@@ -0,0 +1,199 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 组合规则：ExecuteName 为 and/or/not，Data 保存以逗号分隔的操作数，
+// 操作数形如 "rule:<规则名>" 或 "executor:<执行器名>"，不带前缀时视为规则名。
+const (
+	CompositeAnd = "and"
+	CompositeOr  = "or"
+	CompositeNot = "not"
+
+	maxCompositeDepth = 32
+)
+
+type CompositeOperand struct {
+	// Rule is true for a named rule, false for a named executor
+	Rule bool
+	Name string
+}
+
+func (operand CompositeOperand) String() string {
+	if operand.Rule {
+		return "rule:" + operand.Name
+	}
+	return "executor:" + operand.Name
+}
+
+// NewCompositeRule 创建组合规则，operands 见 CompositeOperand 的写法
+func NewCompositeRule(name string, operator string, operands []string, createTime time.Time, updateTime time.Time) *Rule {
+	return &Rule{Name: name, ExecuteName: operator, Data: strings.Join(operands, ","), CreateTime: createTime, UpdateTime: updateTime}
+}
+
+func IsCompositeRule(rule *Rule) bool {
+	if rule == nil {
+		return false
+	}
+	switch rule.ExecuteName {
+	case CompositeAnd, CompositeOr, CompositeNot:
+		return true
+	}
+	return false
+}
+
+// ParseCompositeOperands 解析组合规则的 Data
+func ParseCompositeOperands(data string) ([]CompositeOperand, error) {
+	operands := make([]CompositeOperand, 0)
+	for _, part := range strings.Split(data, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		operand := CompositeOperand{Rule: true, Name: part}
+		if i := strings.Index(part, ":"); i >= 0 {
+			switch part[:i] {
+			case "rule":
+			case "executor":
+				operand.Rule = false
+			default:
+				return nil, fmt.Errorf("unknown operand kind %q", part[:i])
+			}
+			operand.Name = strings.TrimSpace(part[i+1:])
+		}
+		if operand.Name == "" {
+			return nil, fmt.Errorf("empty operand in %q", data)
+		}
+		operands = append(operands, operand)
+	}
+	return operands, nil
+}
+
+// validateComposite 校验操作数个数，并检查替换 oldName 后组合规则之间是否成环
+func (manager *DefaultManager) validateComposite(oldName string, rule Rule) error {
+	operands, err := ParseCompositeOperands(rule.Data)
+	if err != nil {
+		return err
+	}
+	if rule.ExecuteName == CompositeNot && len(operands) != 1 {
+		return fmt.Errorf("rule '%s': not takes exactly one operand, got %d", rule.Name, len(operands))
+	}
+	if len(operands) == 0 {
+		return fmt.Errorf("rule '%s': %s needs at least one operand", rule.Name, rule.ExecuteName)
+	}
+
+	rules := make(map[string]*Rule)
+	for _, r := range manager.GetRules() {
+		rules[r.Name] = r
+	}
+	delete(rules, oldName)
+	rules[rule.Name] = &rule
+
+	// 0 = unvisited, 1 = visiting, 2 = done
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("composite rule cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		r := rules[name]
+		if !IsCompositeRule(r) {
+			return nil
+		}
+		state[name] = 1
+		children, err := ParseCompositeOperands(r.Data)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if !child.Rule {
+				continue
+			}
+			if err := visit(child.Name, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	return visit(rule.Name, nil)
+}
+
+// ruleResult 规则求值结果。组合规则的操作数出错或失败时整个组合规则的结果即为该错误或失败，
+// 不会被 not 取反为允许；失败策略只作用于 item 绑定的顶层规则（见 decideRule）。
+type ruleResult int
+
+const (
+	ruleDenied ruleResult = iota
+	ruleAllowed
+	// ruleInvalid 组合规则配置有误：操作数无法解析、引用的规则不存在或未配置执行器、嵌套过深，总是拒绝
+	ruleInvalid
+	// ruleFailed 执行器失败（未注册、panic、超时或 ctx 取消），由顶层规则的失败策略判定
+	ruleFailed
+)
+
+func resultOf(allowed bool) ruleResult {
+	if allowed {
+		return ruleAllowed
+	}
+	return ruleDenied
+}
+
+// executeComposite 按操作数顺序短路求值组合规则，任一操作数出错或失败时立即返回
+func (manager *DefaultManager) executeComposite(ctx context.Context, userId interface{}, item Item, rule *Rule, rules func(name string) *Rule, depth int, trace *decisionTrace) ruleResult {
+	if depth > maxCompositeDepth {
+		manager.log().Warn("[rbac] composite rule nested too deep", "rule", rule.Name, "limit", maxCompositeDepth)
+		return ruleInvalid
+	}
+	operands, err := ParseCompositeOperands(rule.Data)
+	if err != nil || len(operands) == 0 {
+		manager.log().Warn("[rbac] composite rule has invalid operands", "rule", rule.Name, "err", err)
+		return ruleInvalid
+	}
+
+	for _, operand := range operands {
+		result := manager.executeOperand(ctx, userId, item, rule, operand, rules, depth, trace)
+		if result == ruleInvalid || result == ruleFailed {
+			return result
+		}
+		switch rule.ExecuteName {
+		case CompositeNot:
+			return resultOf(result != ruleAllowed)
+		case CompositeAnd:
+			if result != ruleAllowed {
+				return result
+			}
+		case CompositeOr:
+			if result == ruleAllowed {
+				return result
+			}
+		}
+	}
+	return resultOf(rule.ExecuteName == CompositeAnd)
+}
+
+// executeOperand 执行器操作数失败时返回 ruleFailed，不在此应用失败策略
+func (manager *DefaultManager) executeOperand(ctx context.Context, userId interface{}, item Item, composite *Rule, operand CompositeOperand, rules func(name string) *Rule, depth int, trace *decisionTrace) ruleResult {
+	if !operand.Rule {
+		return manager.runExecutor(ctx, userId, item, composite, operand.Name, trace)
+	}
+
+	rule := rules(operand.Name)
+	if rule == nil {
+		manager.log().Warn("[rbac] composite operand rule does not exist", "rule", composite.Name, "operand", operand.Name)
+		return ruleInvalid
+	}
+	result, handled := manager.executeRule(ctx, userId, item, rule, rules, depth+1, trace)
+	if !handled {
+		manager.log().Warn("[rbac] composite operand rule has no executor", "rule", composite.Name, "operand", operand.Name)
+		return ruleInvalid
+	}
+	return result
+}
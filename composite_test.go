@@ -0,0 +1,110 @@
+package gorbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testExecutor struct {
+	name    string
+	execute func(ctx context.Context) bool
+}
+
+func (e *testExecutor) Name() string {
+	return e.name
+}
+
+func (e *testExecutor) Execute(ctx context.Context, userId interface{}, item Item) bool {
+	return e.execute(ctx)
+}
+
+// newRuleManager 创建管理器：权限 doc:read 绑定规则 check，分配给用户 1。
+// rules 直接写入仓库，不经过 AddRule 的校验，以便构造有误的组合规则。
+func newRuleManager(t *testing.T, cache bool, rules ...*Rule) *DefaultManager {
+	t.Helper()
+	now := time.Now()
+	repo := NewMemoryRepository()
+	for _, rule := range rules {
+		if err := repo.AddRule(*rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manager := NewDefaultManager(repo, cache)
+	registry := NewExecutorRegistry()
+	registry.AddExecutor(&testExecutor{name: "yes", execute: func(ctx context.Context) bool { return true }})
+	registry.AddExecutor(&testExecutor{name: "no", execute: func(ctx context.Context) bool { return false }})
+	manager.SetExecutorRegistry(registry)
+
+	read := NewPermission("doc:read", "", "check", "", now, now)
+	manager.Add(read)
+	manager.Assign(read, 1)
+	return manager
+}
+
+func composite(name string, operator string, operands ...string) *Rule {
+	return NewCompositeRule(name, operator, operands, time.Now(), time.Now())
+}
+
+func TestCompositeRule(t *testing.T) {
+	plain := &Rule{Name: "plain"}
+	yes := NewRule("yes_rule", "yes", time.Now(), time.Now())
+
+	tests := []struct {
+		name    string
+		rules   []*Rule
+		allowed bool
+	}{
+		{"and allows", []*Rule{composite("check", CompositeAnd, "executor:yes", "executor:yes")}, true},
+		{"and denies", []*Rule{composite("check", CompositeAnd, "executor:yes", "executor:no")}, false},
+		{"or allows", []*Rule{composite("check", CompositeOr, "executor:no", "executor:yes")}, true},
+		{"or denies", []*Rule{composite("check", CompositeOr, "executor:no", "executor:no")}, false},
+		{"not denies", []*Rule{composite("check", CompositeNot, "executor:yes")}, false},
+		{"not allows", []*Rule{composite("check", CompositeNot, "executor:no")}, true},
+		{"rule operand", []*Rule{yes, composite("check", CompositeAnd, "rule:yes_rule")}, true},
+		{"nested not", []*Rule{composite("inner", CompositeNot, "executor:yes"), composite("check", CompositeNot, "inner")}, true},
+
+		// 出错的操作数使整个组合规则拒绝，not 不会把错误取反为允许
+		{"not missing rule", []*Rule{composite("check", CompositeNot, "rule:ghost")}, false},
+		{"not rule without executor", []*Rule{plain, composite("check", CompositeNot, "rule:plain")}, false},
+		{"not nested error", []*Rule{composite("inner", CompositeAnd, "executor:yes", "rule:ghost"), composite("check", CompositeNot, "inner")}, false},
+		{"double not error", []*Rule{composite("inner", CompositeNot, "rule:ghost"), composite("check", CompositeNot, "inner")}, false},
+		{"or error first", []*Rule{composite("check", CompositeOr, "rule:ghost", "executor:yes")}, false},
+		{"not invalid operands", []*Rule{{Name: "inner", ExecuteName: CompositeAnd, Data: "bogus:x"}, composite("check", CompositeNot, "inner")}, false},
+		{"not empty operands", []*Rule{{Name: "inner", ExecuteName: CompositeOr}, composite("check", CompositeNot, "inner")}, false},
+		{"not too deep", []*Rule{composite("inner", CompositeNot, "inner"), composite("check", CompositeNot, "inner")}, false},
+	}
+	for _, tt := range tests {
+		for _, cache := range []bool{false, true} {
+			manager := newRuleManager(t, cache, tt.rules...)
+			if got := manager.CheckAccess(context.Background(), 1, "doc:read"); got != tt.allowed {
+				t.Errorf("%s (cache=%v): CheckAccess = %v, want %v", tt.name, cache, got, tt.allowed)
+			}
+		}
+	}
+}
+
+func TestCompositeRuleValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*Rule
+		rule  *Rule
+		err   string
+	}{
+		{"not with two operands", nil, composite("check", CompositeNot, "executor:yes", "executor:no"), "exactly one operand"},
+		{"no operands", nil, composite("check", CompositeAnd), "at least one operand"},
+		{"unknown operand kind", nil, composite("check", CompositeAnd, "bogus:x"), "unknown operand kind"},
+		{"self cycle", nil, composite("check", CompositeNot, "rule:check"), "cycle"},
+		{"indirect cycle", []*Rule{composite("inner", CompositeAnd, "rule:check")}, composite("check", CompositeOr, "rule:inner"), "cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newRuleManager(t, false, tt.rules...)
+			err := manager.ValidateRule(*tt.rule)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ValidateRule error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package gorbac
+
+import (
+	"context"
+	"time"
+)
+
+// Decision 一次权限校验的判定过程，见 DefaultManager.Explain
+type Decision struct {
+	UserId     interface{} `json:"user_id"`
+	Permission string      `json:"permission"`
+	Allowed    bool        `json:"allowed"`
+	// Path 授权路径，从授予权限的分配/默认角色（或放行的规则）到被校验的 item
+	Path []string `json:"path"`
+	// Rules 校验过程中执行过的规则与执行器，包括未授权的分支
+	Rules    []RuleOutcome `json:"rules"`
+	Duration time.Duration `json:"duration"`
+}
+
+// RuleOutcome 规则/执行器的一次执行结果
+type RuleOutcome struct {
+	Item     string           `json:"item"`
+	Rule     string           `json:"rule,omitempty"`
+	Executor string           `json:"executor,omitempty"`
+	Outcome  ExecutionOutcome `json:"outcome"`
+	// Allowed 执行器返回的结果，失败时为 false；失败策略作用于顶层规则，不体现在这里
+	Allowed  bool          `json:"allowed"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Cached 结果来自请求级判定缓存（见 DecisionCache）
+	Cached bool `json:"cached,omitempty"`
+}
+
+// decisionTrace 收集判定过程，nil 时所有方法均为空操作
+type decisionTrace struct {
+	path  []string
+	rules []RuleOutcome
+}
+
+// grant 递归返回时依次记录授权路径上的 item
+func (trace *decisionTrace) grant(itemName string, allowed bool) {
+	if trace == nil || !allowed {
+		return
+	}
+	trace.path = append(trace.path, itemName)
+}
+
+func (trace *decisionTrace) rule(outcome RuleOutcome) {
+	if trace == nil {
+		return
+	}
+	trace.rules = append(trace.rules, outcome)
+}
+
+// Explain 与 CheckAccess 判定相同，同时返回授权路径与规则执行结果
+func (manager *DefaultManager) Explain(ctx context.Context, userId interface{}, permissionName string) *Decision {
+	userId = manager.userId(userId)
+	start := time.Now()
+	trace := &decisionTrace{}
+	allowed := manager.checkAccess(ctx, userId, permissionName, trace)
+	if !allowed {
+		trace.path = nil
+	}
+	return &Decision{
+		UserId:     userId,
+		Permission: permissionName,
+		Allowed:    allowed,
+		Path:       trace.path,
+		Rules:      trace.rules,
+		Duration:   time.Since(start),
+	}
+}
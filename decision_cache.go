@@ -0,0 +1,112 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type decisionCacheKey struct{}
+
+// DecisionCache 请求级判定缓存，挂在 context 上（HTTP 服务可使用 gorbac/http 的 DecisionCacheMiddleware），缓存同一请求内执行器的结果与最终判定，
+// 键为 (用户, item, 规则参数)。只应在单个请求内使用：缓存期间的分配变更与 ctx.* 取值变化不会反映出来。
+type DecisionCache struct {
+	mu         sync.Mutex
+	decisions  map[string]bool
+	executions map[string]memoizedExecution
+}
+
+type memoizedExecution struct {
+	allowed bool
+	outcome ExecutionOutcome
+}
+
+func NewDecisionCache() *DecisionCache {
+	return &DecisionCache{
+		decisions:  make(map[string]bool),
+		executions: make(map[string]memoizedExecution),
+	}
+}
+
+// WithDecisionCache 为 ctx 挂上新的请求级判定缓存
+func WithDecisionCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, decisionCacheKey{}, NewDecisionCache())
+}
+
+// DecisionCacheFromContext 返回 ctx 上的请求级判定缓存，未挂载时返回 nil
+func DecisionCacheFromContext(ctx context.Context) *DecisionCache {
+	if ctx == nil {
+		return nil
+	}
+	cache, _ := ctx.Value(decisionCacheKey{}).(*DecisionCache)
+	return cache
+}
+
+func (cache *DecisionCache) decision(key string) (allowed bool, ok bool) {
+	if cache == nil {
+		return false, false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	allowed, ok = cache.decisions[key]
+	return
+}
+
+func (cache *DecisionCache) storeDecision(key string, allowed bool) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.decisions[key] = allowed
+}
+
+func (cache *DecisionCache) execution(key string) (memoized memoizedExecution, ok bool) {
+	if cache == nil {
+		return memoized, false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	memoized, ok = cache.executions[key]
+	return
+}
+
+func (cache *DecisionCache) storeExecution(key string, memoized memoizedExecution) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.executions[key] = memoized
+}
+
+// decisionKey 由用户、名称与规则参数组成缓存键，类型参与比较以区分 5 与 "5"。
+// 每个字段带长度前缀，参数值使用 %#v，名称或值中的分隔符不会使不同的组合得到相同的键。
+func decisionKey(ctx context.Context, userId interface{}, names ...string) string {
+	var sb strings.Builder
+	writeKeyField(&sb, fmt.Sprintf("%T:%#v", userId, userId))
+	for _, name := range names {
+		writeKeyField(&sb, name)
+	}
+	params := ParamsFromContext(ctx)
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeKeyField(&sb, key)
+		writeKeyField(&sb, fmt.Sprintf("%T:%#v", params[key], params[key]))
+	}
+	return sb.String()
+}
+
+// writeKeyField 以 "长度:内容" 写入一个字段
+func writeKeyField(sb *strings.Builder, field string) {
+	sb.WriteString(strconv.Itoa(len(field)))
+	sb.WriteByte(':')
+	sb.WriteString(field)
+}
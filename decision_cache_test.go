@@ -0,0 +1,104 @@
+package gorbac
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecisionCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		cached bool
+		params []map[string]interface{}
+		calls  int32
+	}{
+		{"without cache", false, []map[string]interface{}{nil, nil, nil}, 3},
+		{"same params", true, []map[string]interface{}{{"id": 1}, {"id": 1}, {"id": 1}}, 1},
+		{"different params", true, []map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 1}}, 2},
+		{"typed params", true, []map[string]interface{}{{"id": 1}, {"id": "1"}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			manager := newRuleManager(t, true, NewRule("check", "counted", time.Now(), time.Now()))
+			manager.GetExecutorRegistry().AddExecutor(&testExecutor{name: "counted", execute: func(ctx context.Context) bool {
+				atomic.AddInt32(&calls, 1)
+				return true
+			}})
+
+			ctx := context.Background()
+			if tt.cached {
+				ctx = WithDecisionCache(ctx)
+			}
+			for _, params := range tt.params {
+				if !manager.CheckAccess(WithParams(ctx, params), 1, "doc:read") {
+					t.Fatal("CheckAccess denied")
+				}
+			}
+			if calls != tt.calls {
+				t.Errorf("executor called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestDecisionCacheExplain(t *testing.T) {
+	manager := newRuleManager(t, true, NewRule("check", "yes", time.Now(), time.Now()))
+	ctx := WithDecisionCache(context.Background())
+	manager.CheckAccess(ctx, 1, "doc:read")
+
+	decision := manager.Explain(ctx, 1, "doc:read")
+	if !decision.Allowed || len(decision.Rules) != 1 || !decision.Rules[0].Cached {
+		t.Errorf("Explain = %+v, want one cached rule outcome", decision)
+	}
+}
+
+func TestDecisionKeyCollisions(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b func() string
+	}{
+		{"names across separator",
+			func() string { return decisionKey(context.Background(), 1, "a|b", "c") },
+			func() string { return decisionKey(context.Background(), 1, "a", "b|c") }},
+		{"name and param",
+			func() string { return decisionKey(context.Background(), 1, "doc:read") },
+			func() string {
+				return decisionKey(WithParams(context.Background(), map[string]interface{}{"": ""}), 1, "doc")
+			}},
+		{"param value with separator",
+			func() string {
+				return decisionKey(WithParams(context.Background(), map[string]interface{}{"a": "1|b=int:2"}), 1, "doc:read")
+			},
+			func() string {
+				return decisionKey(WithParams(context.Background(), map[string]interface{}{"a": "1", "b": 2}), 1, "doc:read")
+			}},
+		{"list elements",
+			func() string {
+				return decisionKey(WithParams(context.Background(), map[string]interface{}{"tags": []string{"a b"}}), 1, "doc:read")
+			},
+			func() string {
+				return decisionKey(WithParams(context.Background(), map[string]interface{}{"tags": []string{"a", "b"}}), 1, "doc:read")
+			}},
+		{"user with separator",
+			func() string { return decisionKey(context.Background(), "1|doc", "read") },
+			func() string { return decisionKey(context.Background(), "1", "doc", "read") }},
+		{"typed user",
+			func() string { return decisionKey(context.Background(), 5, "doc:read") },
+			func() string { return decisionKey(context.Background(), "5", "doc:read") }},
+	}
+	for _, tt := range tests {
+		if a, b := tt.a(), tt.b(); a == b {
+			t.Errorf("%s: both keys are %q", tt.name, a)
+		}
+	}
+
+	params := func() context.Context {
+		return WithParams(context.Background(), map[string]interface{}{"id": 1, "tags": []string{"a"}})
+	}
+	if decisionKey(params(), 1, "doc:read") != decisionKey(params(), 1, "doc:read") {
+		t.Error("equal inputs produced different keys")
+	}
+}
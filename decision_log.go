@@ -0,0 +1,265 @@
+package gorbac
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultDecisionBufferSize = 1024
+
+// DecisionRecord 一次被记录的访问判定
+type DecisionRecord struct {
+	Decision
+	Time time.Time `json:"time"`
+	// Sampled 为 false 表示未命中采样、仅因 AlwaysLogDenies 记录，此时不含规则执行结果
+	Sampled bool `json:"sampled"`
+}
+
+// DecisionSink 判定记录的写入目标，由 DecisionLogger 的后台协程串行调用
+type DecisionSink interface {
+	WriteDecision(record *DecisionRecord) error
+}
+
+// DecisionSinkFunc 函数形式的 DecisionSink
+type DecisionSinkFunc func(record *DecisionRecord) error
+
+func (fn DecisionSinkFunc) WriteDecision(record *DecisionRecord) error {
+	return fn(record)
+}
+
+// JSONDecisionSink 以 JSON Lines 写入 w，例如日志文件或标准输出
+type JSONDecisionSink struct {
+	encoder *json.Encoder
+}
+
+func NewJSONDecisionSink(w io.Writer) *JSONDecisionSink {
+	return &JSONDecisionSink{encoder: json.NewEncoder(w)}
+}
+
+func (sink *JSONDecisionSink) WriteDecision(record *DecisionRecord) error {
+	return sink.encoder.Encode(record)
+}
+
+// DecisionSampling 匹配 Pattern 的权限使用的采样率
+type DecisionSampling struct {
+	Pattern string
+	// Rate 采样率，0~1
+	Rate float64
+}
+
+type DecisionLogOptions struct {
+	// Include 需要记录的权限，支持 * 通配（如 "admin:*"），为空时匹配全部权限
+	Include []string
+	// Exclude 不记录的权限，优先于 Include
+	Exclude []string
+	// Sampling 按权限覆盖采样率，按顺序取第一个匹配项
+	Sampling []DecisionSampling
+	// SampleRate 未匹配 Sampling 的权限的采样率，0~1。零值表示不采样，此时只有 AlwaysLogDenies 会记录拒绝；
+	// 需要记录全部判定时设为 1
+	SampleRate float64
+	// AlwaysLogDenies 未命中采样的拒绝也会记录
+	AlwaysLogDenies bool
+	// BufferSize 异步缓冲区大小，默认 1024，缓冲区满时丢弃记录并计入 Dropped
+	BufferSize int
+	// Logger 记录 sink 写入失败，默认 DefaultLogger
+	Logger Logger
+}
+
+// DecisionLogger 访问判定日志：CheckAccess 按权限过滤与采样后将记录放入缓冲区，
+// 由后台协程写入 sink。缓冲区满时直接丢弃，不阻塞 CheckAccess。
+type DecisionLogger struct {
+	sink    DecisionSink
+	options DecisionLogOptions
+	records chan *DecisionRecord
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	closed  int32
+	dropped uint64
+	failed  uint64
+}
+
+// NewDecisionLogger 创建判定日志并启动后台写入协程，不再使用时调用 Close
+func NewDecisionLogger(sink DecisionSink, options DecisionLogOptions) *DecisionLogger {
+	if options.BufferSize <= 0 {
+		options.BufferSize = defaultDecisionBufferSize
+	}
+	decisionLogger := &DecisionLogger{
+		sink:    sink,
+		options: options,
+		records: make(chan *DecisionRecord, options.BufferSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go decisionLogger.run()
+	return decisionLogger
+}
+
+func (decisionLogger *DecisionLogger) run() {
+	defer close(decisionLogger.done)
+	for {
+		select {
+		case record := <-decisionLogger.records:
+			decisionLogger.write(record)
+		case <-decisionLogger.stop:
+			// 写完缓冲区中剩余的记录
+			for {
+				select {
+				case record := <-decisionLogger.records:
+					decisionLogger.write(record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (decisionLogger *DecisionLogger) write(record *DecisionRecord) {
+	if err := decisionLogger.sink.WriteDecision(record); err != nil {
+		atomic.AddUint64(&decisionLogger.failed, 1)
+		decisionLogger.logger().Error("[rbac] write decision failed", "permission", record.Permission, "err", err)
+	}
+}
+
+// Close 停止接收新记录，写完缓冲区后返回
+func (decisionLogger *DecisionLogger) Close() {
+	decisionLogger.once.Do(func() {
+		atomic.StoreInt32(&decisionLogger.closed, 1)
+		close(decisionLogger.stop)
+	})
+	<-decisionLogger.done
+}
+
+// Dropped 因缓冲区已满被丢弃的记录数
+func (decisionLogger *DecisionLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&decisionLogger.dropped)
+}
+
+func (decisionLogger *DecisionLogger) logger() Logger {
+	if decisionLogger.options.Logger != nil {
+		return decisionLogger.options.Logger
+	}
+	return DefaultLogger()
+}
+
+// Failed sink 写入失败的记录数
+func (decisionLogger *DecisionLogger) Failed() uint64 {
+	return atomic.LoadUint64(&decisionLogger.failed)
+}
+
+// sample 判断权限是否需要记录：matched 为 false 时不记录，sampled 表示命中采样、需要记录完整判定过程
+func (decisionLogger *DecisionLogger) sample(permissionName string) (sampled bool, matched bool) {
+	options := decisionLogger.options
+	for _, pattern := range options.Exclude {
+		if globMatch(pattern, permissionName) {
+			return false, false
+		}
+	}
+	if len(options.Include) > 0 {
+		included := false
+		for _, pattern := range options.Include {
+			if globMatch(pattern, permissionName) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false, false
+		}
+	}
+
+	rate := options.SampleRate
+	for _, sampling := range options.Sampling {
+		if globMatch(sampling.Pattern, permissionName) {
+			rate = sampling.Rate
+			break
+		}
+	}
+	sampled = rate >= 1 || (rate > 0 && rand.Float64() < rate)
+	return sampled, sampled || options.AlwaysLogDenies
+}
+
+// log 非阻塞地放入缓冲区
+func (decisionLogger *DecisionLogger) log(record *DecisionRecord) {
+	if atomic.LoadInt32(&decisionLogger.closed) == 1 {
+		return
+	}
+	select {
+	case decisionLogger.records <- record:
+	default:
+		atomic.AddUint64(&decisionLogger.dropped, 1)
+	}
+}
+
+// globMatch 匹配只含 * 通配符的模式，* 可匹配任意（包括空的）字符序列
+func globMatch(pattern string, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+// ---------------------- Manager ---------------------------
+
+type decisionLoggerHolder struct {
+	logger *DecisionLogger
+}
+
+// SetDecisionLogger 设置访问判定日志，nil 关闭。Explain 不会被记录。
+func (manager *DefaultManager) SetDecisionLogger(decisionLogger *DecisionLogger) {
+	manager.decisionLogger.Store(&decisionLoggerHolder{logger: decisionLogger})
+}
+
+func (manager *DefaultManager) getDecisionLogger() *DecisionLogger {
+	if holder, ok := manager.decisionLogger.Load().(*decisionLoggerHolder); ok {
+		return holder.logger
+	}
+	return nil
+}
+
+// checkAccessLogged 命中采样时与 Explain 一样收集授权路径与规则执行结果，
+// 否则正常校验，仅在拒绝时记录结果与耗时
+func (manager *DefaultManager) checkAccessLogged(ctx context.Context, userId interface{}, permissionName string, decisionLogger *DecisionLogger, sampled bool) bool {
+	start := time.Now()
+	var trace *decisionTrace
+	if sampled {
+		trace = &decisionTrace{}
+	}
+	allowed := manager.checkAccess(ctx, userId, permissionName, trace)
+	if !sampled && allowed {
+		return allowed
+	}
+
+	record := &DecisionRecord{
+		Decision: Decision{UserId: userId, Permission: permissionName, Allowed: allowed, Duration: time.Since(start)},
+		Time:     start,
+		Sampled:  sampled,
+	}
+	if trace != nil {
+		if allowed {
+			record.Path = trace.path
+		}
+		record.Rules = trace.rules
+	}
+	decisionLogger.log(record)
+	return allowed
+}
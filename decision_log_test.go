@@ -0,0 +1,102 @@
+package gorbac
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"admin:*", "admin:users", true},
+		{"*:edit", "posts:edit", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "acb", false},
+		{"*", "", true},
+		{"x", "x", true},
+		{"x*", "y", false},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecisionLoggerSample(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    DecisionLogOptions
+		permission string
+		sampled    bool
+		matched    bool
+	}{
+		{"zero rate samples nothing", DecisionLogOptions{}, "posts:edit", false, false},
+		{"zero rate keeps denies", DecisionLogOptions{AlwaysLogDenies: true}, "posts:edit", false, true},
+		{"full rate", DecisionLogOptions{SampleRate: 1}, "posts:edit", true, true},
+		{"not included", DecisionLogOptions{SampleRate: 1, Include: []string{"admin:*"}}, "posts:edit", false, false},
+		{"excluded", DecisionLogOptions{SampleRate: 1, Exclude: []string{"posts:*"}, AlwaysLogDenies: true}, "posts:edit", false, false},
+		{"sampling override off", DecisionLogOptions{SampleRate: 1, Sampling: []DecisionSampling{{Pattern: "posts:*", Rate: 0}}}, "posts:edit", false, false},
+		{"sampling override on", DecisionLogOptions{Sampling: []DecisionSampling{{Pattern: "*:edit", Rate: 1}}}, "posts:edit", true, true},
+		{"first sampling wins", DecisionLogOptions{Sampling: []DecisionSampling{{Pattern: "posts:*", Rate: 0}, {Pattern: "*:edit", Rate: 1}}}, "posts:edit", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisionLogger := NewDecisionLogger(DecisionSinkFunc(func(record *DecisionRecord) error { return nil }), tt.options)
+			defer decisionLogger.Close()
+			sampled, matched := decisionLogger.sample(tt.permission)
+			if sampled != tt.sampled || matched != tt.matched {
+				t.Errorf("sample(%s) = (%v, %v), want (%v, %v)", tt.permission, sampled, matched, tt.sampled, tt.matched)
+			}
+		})
+	}
+}
+
+func TestDecisionLoggerRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DecisionLogOptions
+		// 依次校验用户 1 的 posts:edit（允许）与用户 2 的 posts:edit（拒绝）
+		records []bool
+		sampled []bool
+	}{
+		{"zero rate", DecisionLogOptions{}, nil, nil},
+		{"zero rate with denies", DecisionLogOptions{AlwaysLogDenies: true}, []bool{false}, []bool{false}},
+		{"full rate", DecisionLogOptions{SampleRate: 1}, []bool{true, false}, []bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTestManager(t, true)
+			var mu sync.Mutex
+			records := make([]*DecisionRecord, 0)
+			decisionLogger := NewDecisionLogger(DecisionSinkFunc(func(record *DecisionRecord) error {
+				mu.Lock()
+				defer mu.Unlock()
+				records = append(records, record)
+				return nil
+			}), tt.options)
+			manager.SetDecisionLogger(decisionLogger)
+
+			ctx := context.Background()
+			manager.CheckAccess(ctx, 1, "posts:edit")
+			manager.CheckAccess(ctx, 2, "posts:edit")
+			decisionLogger.Close()
+
+			if len(records) != len(tt.records) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.records))
+			}
+			for i, record := range records {
+				if record.Allowed != tt.records[i] || record.Sampled != tt.sampled[i] {
+					t.Errorf("record %d = (allowed %v, sampled %v), want (%v, %v)", i, record.Allowed, record.Sampled, tt.records[i], tt.sampled[i])
+				}
+				if record.Sampled && record.Allowed && len(record.Path) == 0 {
+					t.Errorf("record %d: sampled grant has no path", i)
+				}
+			}
+		})
+	}
+}
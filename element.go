@@ -32,16 +32,23 @@ type Item interface {
 }
 
 type Rule struct {
-	Name        string    `json:"name"`
-	ExecuteName string    `json:"execute_name"`
-	CreateTime  time.Time `json:"create_time"`
-	UpdateTime  time.Time `json:"update_time"`
+	Name        string `json:"name"`
+	ExecuteName string `json:"execute_name"`
+	// Data 执行器使用的规则数据，例如 ExpressionExecutor 的表达式
+	Data       string    `json:"data"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
 }
 
 func NewRule(name string, executeName string, createTime time.Time, updateTime time.Time) *Rule {
 	return &Rule{Name: name, ExecuteName: executeName, CreateTime: createTime, UpdateTime: updateTime}
 }
 
+// NewExpressionRule 创建由内置表达式执行器求值的规则
+func NewExpressionRule(name string, expression string, createTime time.Time, updateTime time.Time) *Rule {
+	return &Rule{Name: name, ExecuteName: ExpressionExecutorName, Data: expression, CreateTime: createTime, UpdateTime: updateTime}
+}
+
 func (rule *Rule) SetName(name string) {
 	rule.Name = name
 }
@@ -51,7 +58,11 @@ func (rule *Rule) SetExecutor(executor Executor) {
 }
 
 func (rule *Rule) GetExecutor() Executor {
-	return ExecuteManager.GetExecutor(rule.ExecuteName)
+	return rule.GetExecutorFrom(ExecuteManager)
+}
+
+func (rule *Rule) GetExecutorFrom(registry *ExecutorRegistry) Executor {
+	return registry.GetExecutor(rule.ExecuteName)
 }
 
 // ------------- role
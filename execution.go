@@ -0,0 +1,222 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailurePolicy 执行器失败（未注册、panic、超时或 ctx 取消）时的判定
+type FailurePolicy int
+
+const (
+	FailClosed FailurePolicy = iota // 拒绝访问（默认）
+	FailOpen                        // 允许访问
+)
+
+func (policy FailurePolicy) String() string {
+	if policy == FailOpen {
+		return "fail-open"
+	}
+	return "fail-closed"
+}
+
+// ExecutionOutcome 一次执行器调用的结果
+type ExecutionOutcome string
+
+const (
+	OutcomeOK       ExecutionOutcome = "ok"
+	OutcomeMissing  ExecutionOutcome = "missing"
+	OutcomePanic    ExecutionOutcome = "panic"
+	OutcomeTimeout  ExecutionOutcome = "timeout"
+	OutcomeCanceled ExecutionOutcome = "canceled"
+)
+
+// executionConfig 执行器超时与失败策略，键为空字符串时表示默认值
+type executionConfig struct {
+	mu       sync.RWMutex
+	timeouts map[string]time.Duration
+	policies map[string]FailurePolicy
+}
+
+func (config *executionConfig) timeout(executorName string) time.Duration {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+	if timeout, ok := config.timeouts[executorName]; ok {
+		return timeout
+	}
+	return config.timeouts[""]
+}
+
+func (config *executionConfig) policy(ruleName string) FailurePolicy {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+	if policy, ok := config.policies[ruleName]; ok {
+		return policy
+	}
+	return config.policies[""]
+}
+
+// SetExecutorTimeout 配置执行器的超时时间，executorName 为空时设置默认值，timeout <= 0 表示不限时。
+// 实际超时取配置值与 ctx 截止时间中较早者。
+func (manager *DefaultManager) SetExecutorTimeout(executorName string, timeout time.Duration) {
+	manager.execution.mu.Lock()
+	defer manager.execution.mu.Unlock()
+	if manager.execution.timeouts == nil {
+		manager.execution.timeouts = make(map[string]time.Duration)
+	}
+	manager.execution.timeouts[executorName] = timeout
+}
+
+// SetFailurePolicy 配置规则的失败策略，ruleName 为空时设置默认值（默认 FailClosed）。
+// 直接绑定在 item 上的执行器使用默认策略。组合规则中任一执行器失败时整个组合规则失败，
+// 按 item 绑定的顶层规则的策略判定一次，and/or/not 不会改变失败的结果。
+func (manager *DefaultManager) SetFailurePolicy(ruleName string, policy FailurePolicy) {
+	manager.execution.mu.Lock()
+	defer manager.execution.mu.Unlock()
+	if manager.execution.policies == nil {
+		manager.execution.policies = make(map[string]FailurePolicy)
+	}
+	manager.execution.policies[ruleName] = policy
+}
+
+// runExecutor 执行名为 executorName 的执行器，隔离 panic、按超时与 ctx 限时，失败时返回 ruleFailed。
+// rule 为触发执行的规则，组合规则的执行器操作数不会把组合规则本身交给 RuleExecutor。
+func (manager *DefaultManager) runExecutor(ctx context.Context, userId interface{}, item Item, rule *Rule, executorName string, trace *decisionTrace) ruleResult {
+	observer := manager.getObserver()
+	var start time.Time
+	if trace != nil || observer != nil {
+		start = time.Now()
+	}
+
+	ruleName := ""
+	if rule != nil {
+		ruleName = rule.Name
+	}
+
+	// 请求级缓存命中时不再执行
+	cache := DecisionCacheFromContext(ctx)
+	var key string
+	if cache != nil {
+		key = decisionKey(ctx, userId, item.GetName(), ruleName, executorName)
+		memoized, ok := cache.execution(key)
+		manager.observeCache(CacheExecution, ok)
+		if ok {
+			trace.rule(RuleOutcome{Item: item.GetName(), Rule: ruleName, Executor: executorName, Outcome: memoized.outcome, Allowed: memoized.allowed, Cached: true})
+			return executionResult(memoized.allowed, memoized.outcome)
+		}
+	}
+
+	executor := manager.GetExecutorRegistry().GetExecutor(executorName)
+	allowed, outcome, err := manager.invoke(ctx, executor, executorName, userId, item, rule)
+
+	if outcome != OutcomeOK {
+		manager.log().Warn("[rbac] executor failed", "executor", executorName, "item", item.GetName(), "rule", ruleName, "outcome", outcome, "err", err)
+	}
+	cache.storeExecution(key, memoizedExecution{allowed: allowed, outcome: outcome})
+	if observer != nil {
+		observer.ExecutorDone(ctx, executorName, outcome, time.Since(start))
+	}
+
+	if trace != nil {
+		o := RuleOutcome{
+			Item:     item.GetName(),
+			Rule:     ruleName,
+			Executor: executorName,
+			Outcome:  outcome,
+			Allowed:  allowed,
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			o.Error = err.Error()
+		}
+		trace.rule(o)
+	}
+	return executionResult(allowed, outcome)
+}
+
+func executionResult(allowed bool, outcome ExecutionOutcome) ruleResult {
+	if outcome != OutcomeOK {
+		return ruleFailed
+	}
+	return resultOf(allowed)
+}
+
+// decideRule 将 item 绑定的顶层规则（或直接绑定的执行器，ruleName 为空）的结果转为判定：
+// 执行器失败按 ruleName 的失败策略判定，组合规则配置有误时拒绝
+func (manager *DefaultManager) decideRule(item Item, ruleName string, result ruleResult) bool {
+	switch result {
+	case ruleAllowed:
+		return true
+	case ruleFailed:
+		policy := manager.execution.policy(ruleName)
+		manager.log().Warn("[rbac] rule failed", "item", item.GetName(), "rule", ruleName, "policy", policy)
+		return policy == FailOpen
+	}
+	return false
+}
+
+type invokeResult struct {
+	allowed bool
+	err     error
+}
+
+func (manager *DefaultManager) invoke(ctx context.Context, executor Executor, executorName string, userId interface{}, item Item, rule *Rule) (bool, ExecutionOutcome, error) {
+	if executor == nil {
+		return false, OutcomeMissing, fmt.Errorf("executor '%s' is not registered", executorName)
+	}
+
+	call := func(ctx context.Context) (result invokeResult) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = invokeResult{err: fmt.Errorf("executor panic: %v", r)}
+			}
+		}()
+		if ruleExecutor, ok := executor.(RuleExecutor); ok && rule != nil && !IsCompositeRule(rule) {
+			return invokeResult{allowed: ruleExecutor.ExecuteRule(ctx, userId, item, rule)}
+		}
+		return invokeResult{allowed: executor.Execute(ctx, userId, item)}
+	}
+
+	timeout := manager.execution.timeout(executorName)
+	// 不限时且 ctx 不可取消时直接在当前协程执行
+	if timeout <= 0 && ctx.Done() == nil {
+		result := call(ctx)
+		if result.err != nil {
+			return false, OutcomePanic, result.err
+		}
+		return result.allowed, OutcomeOK, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return false, ctxOutcome(err), err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan invokeResult, 1)
+	go func() {
+		done <- call(ctx)
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return false, OutcomePanic, result.err
+		}
+		return result.allowed, OutcomeOK, nil
+	case <-ctx.Done():
+		return false, ctxOutcome(ctx.Err()), ctx.Err()
+	}
+}
+
+func ctxOutcome(err error) ExecutionOutcome {
+	if err == context.DeadlineExceeded {
+		return OutcomeTimeout
+	}
+	return OutcomeCanceled
+}
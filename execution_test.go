@@ -0,0 +1,104 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newFailureManager(t *testing.T, rules ...*Rule) *DefaultManager {
+	t.Helper()
+	manager := newRuleManager(t, true, rules...)
+	registry := manager.GetExecutorRegistry()
+	registry.AddExecutor(&testExecutor{name: "panic", execute: func(ctx context.Context) bool { panic("boom") }})
+	registry.AddExecutor(&testExecutor{name: "slow", execute: func(ctx context.Context) bool {
+		<-ctx.Done()
+		return true
+	}})
+	manager.SetExecutorTimeout("slow", 5*time.Millisecond)
+	return manager
+}
+
+func TestFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []*Rule
+		policies map[string]FailurePolicy
+		allowed  bool
+		outcome  ExecutionOutcome
+	}{
+		{"panic fails closed", []*Rule{NewRule("check", "panic", time.Now(), time.Now())}, nil, false, OutcomePanic},
+		{"panic fails open", []*Rule{NewRule("check", "panic", time.Now(), time.Now())}, map[string]FailurePolicy{"check": FailOpen}, true, OutcomePanic},
+		{"default policy", []*Rule{NewRule("check", "panic", time.Now(), time.Now())}, map[string]FailurePolicy{"": FailOpen}, true, OutcomePanic},
+		{"timeout", []*Rule{NewRule("check", "slow", time.Now(), time.Now())}, nil, false, OutcomeTimeout},
+		{"missing executor", []*Rule{NewRule("check", "ghost", time.Now(), time.Now())}, map[string]FailurePolicy{"check": FailOpen}, true, OutcomeMissing},
+
+		// 失败沿组合规则原样向上传递，not 不会把失败取反为允许
+		{"not panic fails closed", []*Rule{composite("check", CompositeNot, "executor:panic")}, nil, false, OutcomePanic},
+		{"not timeout fails closed", []*Rule{composite("check", CompositeNot, "executor:slow")}, nil, false, OutcomeTimeout},
+		{"not panic fails open", []*Rule{composite("check", CompositeNot, "executor:panic")}, map[string]FailurePolicy{"check": FailOpen}, true, OutcomePanic},
+		{"and with failing not", []*Rule{composite("check", CompositeAnd, "executor:yes", "rule:inner"), composite("inner", CompositeNot, "executor:slow")}, nil, false, OutcomeTimeout},
+		{"or with failing operand", []*Rule{composite("check", CompositeOr, "executor:panic", "executor:yes")}, nil, false, OutcomePanic},
+		// 只有顶层规则的失败策略生效
+		{"inner policy ignored", []*Rule{composite("check", CompositeNot, "rule:inner"), composite("inner", CompositeAnd, "executor:panic")}, map[string]FailurePolicy{"inner": FailOpen}, false, OutcomePanic},
+		{"top policy applied once", []*Rule{composite("check", CompositeNot, "rule:inner"), composite("inner", CompositeNot, "executor:panic")}, map[string]FailurePolicy{"check": FailOpen}, true, OutcomePanic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newFailureManager(t, tt.rules...)
+			for rule, policy := range tt.policies {
+				manager.SetFailurePolicy(rule, policy)
+			}
+			ctx := context.Background()
+			if got := manager.CheckAccess(ctx, 1, "doc:read"); got != tt.allowed {
+				t.Errorf("CheckAccess = %v, want %v", got, tt.allowed)
+			}
+
+			decision := manager.Explain(ctx, 1, "doc:read")
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Explain allowed = %v, want %v", decision.Allowed, tt.allowed)
+			}
+			found := false
+			for _, outcome := range decision.Rules {
+				if outcome.Outcome == tt.outcome {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Explain rules = %+v, want an outcome %s", decision.Rules, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestFailurePolicyItemExecutor(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		executor string
+		policy   FailurePolicy
+		ctx      func() context.Context
+		allowed  bool
+	}{
+		{"ok", "yes", FailClosed, context.Background, true},
+		{"panic closed", "panic", FailClosed, context.Background, false},
+		{"panic open", "panic", FailOpen, context.Background, true},
+		{"canceled closed", "yes", FailClosed, func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newFailureManager(t)
+			manager.SetFailurePolicy("", tt.policy)
+			write := NewPermission("doc:write", "", "", tt.executor, now, now)
+			manager.Add(write)
+			manager.Assign(write, 1)
+			if got := manager.CheckAccess(tt.ctx(), 1, "doc:write"); got != tt.allowed {
+				t.Errorf("CheckAccess = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
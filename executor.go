@@ -2,7 +2,9 @@ package gorbac
 
 import (
 	"context"
-	log "github.com/kordar/gologger"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
 type Executor interface {
@@ -10,23 +12,67 @@ type Executor interface {
 	Execute(ctx context.Context, userId interface{}, item Item) bool
 }
 
-// ExecuteManager /****************** execute manger *****************************/
-var ExecuteManager = container{
-	content: make(map[string]Executor),
+// RuleExecutor 需要读取规则本身（例如规则中保存的表达式）的执行器，
+// 通过规则调用时优先使用 ExecuteRule
+type RuleExecutor interface {
+	Executor
+	ExecuteRule(ctx context.Context, userId interface{}, item Item, rule *Rule) bool
+}
+
+// RuleValidator 在 AddRule/UpdateRule 时校验规则，返回错误则拒绝写入
+type RuleValidator interface {
+	ValidateRule(rule Rule) error
 }
 
-type container struct {
+// ExecuteManager /****************** execute manger *****************************/
+// 全局默认注册表，未单独配置注册表的 DefaultManager 使用它，保持向后兼容
+var ExecuteManager = NewExecutorRegistry()
+
+// ExecutorRegistry 执行器注册表，并发安全，可为每个 DefaultManager/RbacService 单独创建
+type ExecutorRegistry struct {
+	mu      sync.RWMutex
 	content map[string]Executor
 }
 
-func (container *container) AddExecutor(executor Executor) {
+// NewExecutorRegistry 创建注册表，内置执行器（如 ExpressionExecutor）已预先注册
+func NewExecutorRegistry() *ExecutorRegistry {
+	registry := &ExecutorRegistry{content: make(map[string]Executor)}
+	registry.AddExecutor(NewExpressionExecutor())
+	return registry
+}
+
+func (container *ExecutorRegistry) AddExecutor(executor Executor) {
+	container.mu.Lock()
+	defer container.mu.Unlock()
 	container.content[executor.Name()] = executor
 }
 
-func (container *container) GetExecutor(name string) Executor {
+func (container *ExecutorRegistry) GetExecutor(name string) Executor {
+	container.mu.RLock()
+	defer container.mu.RUnlock()
 	return container.content[name]
 }
 
+func (container *ExecutorRegistry) RemoveExecutor(name string) {
+	container.mu.Lock()
+	defer container.mu.Unlock()
+	delete(container.content, name)
+}
+
+// Executors 按名称排序返回已注册的执行器
+func (container *ExecutorRegistry) Executors() []Executor {
+	container.mu.RLock()
+	defer container.mu.RUnlock()
+	executors := make([]Executor, 0, len(container.content))
+	for _, executor := range container.content {
+		executors = append(executors, executor)
+	}
+	sort.Slice(executors, func(i, j int) bool {
+		return executors[i].Name() < executors[j].Name()
+	})
+	return executors
+}
+
 type DemoExecutor struct {
 }
 
@@ -35,8 +81,67 @@ func (d *DemoExecutor) Name() string {
 }
 
 func (d *DemoExecutor) Execute(ctx context.Context, userId interface{}, item Item) bool {
-	log.Info("============================================")
-	log.Info("==============DEMO===============")
-	log.Info("============================================")
+	DefaultLogger().Debug("[rbac] demo executor", "user_id", userId, "item", item.GetName())
 	return true
 }
+
+// ExpressionExecutorName 内置表达式执行器名称，规则的 Data 保存表达式
+const ExpressionExecutorName = "expression"
+
+// ExpressionExecutor 内置表达式执行器，在规则 Data 中保存布尔表达式（见 CompileExpression），
+// 无需编写 Go 执行器即可配置动态规则。求值出错时拒绝访问。
+type ExpressionExecutor struct {
+	// source => *Expression
+	compiled sync.Map
+	logger   atomic.Value // *loggerHolder
+}
+
+func NewExpressionExecutor() *ExpressionExecutor {
+	return &ExpressionExecutor{}
+}
+
+// SetLogger 设置表达式编译与求值失败时使用的日志，nil 时使用 DefaultLogger
+func (e *ExpressionExecutor) SetLogger(logger Logger) {
+	e.logger.Store(&loggerHolder{logger: logger})
+}
+
+func (e *ExpressionExecutor) Name() string {
+	return ExpressionExecutorName
+}
+
+// Execute 表达式保存在规则上，直接绑定到 item 时没有可求值的表达式
+func (e *ExpressionExecutor) Execute(ctx context.Context, userId interface{}, item Item) bool {
+	loggerOrDefault(&e.logger).Warn("[rbac] expression executor needs a rule", "item", item.GetName())
+	return false
+}
+
+func (e *ExpressionExecutor) ExecuteRule(ctx context.Context, userId interface{}, item Item, rule *Rule) bool {
+	expression, err := e.compile(rule.Data)
+	if err != nil {
+		loggerOrDefault(&e.logger).Warn("[rbac] invalid rule expression", "rule", rule.Name, "item", item.GetName(), "err", err)
+		return false
+	}
+	allowed, err := expression.Evaluate(ctx, userId, item)
+	if err != nil {
+		loggerOrDefault(&e.logger).Warn("[rbac] rule expression evaluation failed", "rule", rule.Name, "item", item.GetName(), "err", err)
+		return false
+	}
+	return allowed
+}
+
+func (e *ExpressionExecutor) ValidateRule(rule Rule) error {
+	_, err := e.compile(rule.Data)
+	return err
+}
+
+func (e *ExpressionExecutor) compile(source string) (*Expression, error) {
+	if expression, ok := e.compiled.Load(source); ok {
+		return expression.(*Expression), nil
+	}
+	expression, err := CompileExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	e.compiled.Store(source, expression)
+	return expression, nil
+}
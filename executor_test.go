@@ -0,0 +1,86 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newGateManager 权限 doc:read 绑定规则 check（执行器 gate），分配给用户 1；registry 为 nil 时使用全局 ExecuteManager
+func newGateManager(t *testing.T, registry *ExecutorRegistry) *DefaultManager {
+	t.Helper()
+	now := time.Now()
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	if registry != nil {
+		manager.SetExecutorRegistry(registry)
+	}
+	if !manager.AddRule(*NewRule("check", "gate", now, now)) {
+		t.Fatal("add rule check failed")
+	}
+	read := NewPermission("doc:read", "", "check", "", now, now)
+	manager.Add(read)
+	manager.Assign(read, 1)
+	return manager
+}
+
+func gate(allowed bool) *testExecutor {
+	return &testExecutor{name: "gate", execute: func(ctx context.Context) bool { return allowed }}
+}
+
+func TestExecutorRegistryIsolation(t *testing.T) {
+	allowRegistry := NewExecutorRegistry()
+	allowRegistry.AddExecutor(gate(true))
+	denyRegistry := NewExecutorRegistry()
+	denyRegistry.AddExecutor(gate(false))
+	allow := newGateManager(t, allowRegistry)
+	deny := newGateManager(t, denyRegistry)
+
+	ctx := context.Background()
+	if !allow.CheckAccess(ctx, 1, "doc:read") {
+		t.Error("manager with the allowing gate denied")
+	}
+	if deny.CheckAccess(ctx, 1, "doc:read") {
+		t.Error("manager with the denying gate allowed")
+	}
+
+	// 通过服务注册与移除只影响该服务的管理器
+	NewRbacServiceWithManager(deny).RemoveExecutor("gate")
+	if allowRegistry.GetExecutor("gate") == nil {
+		t.Error("RemoveExecutor on one service removed the other manager's executor")
+	}
+	NewRbacServiceWithManager(deny).RegisterExecutor(gate(true))
+	if !deny.CheckAccess(ctx, 1, "doc:read") {
+		t.Error("executor registered through the service is not used")
+	}
+	if ExecuteManager.GetExecutor("gate") != nil {
+		t.Error("executor registered on a separate registry leaked into ExecuteManager")
+	}
+}
+
+func TestExecuteManagerCompatibility(t *testing.T) {
+	ExecuteManager.AddExecutor(gate(true))
+	t.Cleanup(func() { ExecuteManager.RemoveExecutor("gate") })
+
+	manager := newGateManager(t, nil)
+	if manager.GetExecutorRegistry() != ExecuteManager {
+		t.Fatal("manager without its own registry does not use ExecuteManager")
+	}
+	ctx := context.Background()
+	if !manager.CheckAccess(ctx, 1, "doc:read") {
+		t.Error("executor registered on ExecuteManager is not used")
+	}
+	if rule := manager.GetRule("check"); rule.GetExecutor() == nil {
+		t.Error("Rule.GetExecutor does not resolve from ExecuteManager")
+	}
+
+	// 没有注册表的自定义管理器经 RbacService 注册到 ExecuteManager
+	service := NewRbacServiceWithManager(externalManager{manager})
+	if len(service.Executors()) != len(ExecuteManager.Executors()) {
+		t.Error("service over a custom manager does not list ExecuteManager's executors")
+	}
+
+	isolated := newGateManager(t, NewExecutorRegistry())
+	if isolated.CheckAccess(ctx, 1, "doc:read") {
+		t.Error("manager with its own registry resolved an executor from ExecuteManager")
+	}
+}
@@ -0,0 +1,780 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 表达式规则的复杂度上限，保证解析与求值的开销有界
+const (
+	maxExpressionLength = 4096
+	maxExpressionNodes  = 512
+	maxExpressionDepth  = 64
+	maxExpressionSteps  = 10000
+)
+
+var errExpressionBudget = errors.New("expression evaluation budget exceeded")
+
+type exprKind int
+
+const (
+	kindAny exprKind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindList
+	kindNull
+)
+
+func (k exprKind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindList:
+		return "list"
+	case kindNull:
+		return "null"
+	}
+	return "any"
+}
+
+// 表达式中可用的字段及其类型，params 与 ctx 下的字段为动态类型
+var (
+	userFields = map[string]exprKind{"id": kindAny}
+	itemFields = map[string]exprKind{
+		"name":         kindString,
+		"type":         kindNumber,
+		"description":  kindString,
+		"rule_name":    kindString,
+		"execute_name": kindString,
+	}
+	timeFields = map[string]exprKind{
+		"year":    kindNumber,
+		"month":   kindNumber,
+		"day":     kindNumber,
+		"hour":    kindNumber,
+		"minute":  kindNumber,
+		"weekday": kindNumber,
+		"unix":    kindNumber,
+	}
+)
+
+type exprNode interface {
+	kind() exprKind
+}
+
+type literalNode struct {
+	value interface{}
+	k     exprKind
+}
+
+type pathNode struct {
+	root   string
+	fields []string
+	k      exprKind
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+	k           exprKind
+}
+
+type listNode struct {
+	elements []exprNode
+}
+
+func (n *literalNode) kind() exprKind { return n.k }
+func (n *pathNode) kind() exprKind    { return n.k }
+func (n *unaryNode) kind() exprKind   { return kindBool }
+func (n *binaryNode) kind() exprKind  { return n.k }
+func (n *listNode) kind() exprKind    { return kindList }
+
+// Expression 已解析并通过类型检查的布尔表达式，可并发求值
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+func (expression *Expression) String() string {
+	return expression.source
+}
+
+// CompileExpression 解析并类型检查规则表达式，例如
+//
+//	params.owner_id == user.id && time.hour < 18
+//
+// 支持 && || ! == != < <= > >= in、括号、列表 [a, b]、数字/字符串/true/false/null 字面量，
+// 以及 user.id、item.*、params.*、ctx.*、time.* 字段。
+func CompileExpression(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, errors.New("empty expression")
+	}
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression longer than %d bytes", maxExpressionLength)
+	}
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().pos)
+	}
+	if k := root.kind(); k != kindBool && k != kindAny {
+		return nil, fmt.Errorf("expression must be bool, got %s", k)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// Evaluate 求值表达式，params 取自 ParamsFromContext(ctx)，ctx.<name> 取自 ctx.Value(ContextKey(name))
+func (expression *Expression) Evaluate(ctx context.Context, userId interface{}, item Item) (bool, error) {
+	env := &exprEnv{ctx: ctx, userId: userId, item: item, params: ParamsFromContext(ctx), now: time.Now()}
+	value, err := env.eval(expression.root)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression result is %T, not bool", value)
+	}
+	return result, nil
+}
+
+// ------------------------------ lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	// value holds the decoded literal for number and string tokens
+	value interface{}
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func lexExpression(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			number, err := parseNumber(source[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", source[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], pos: start, value: number})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(source) && rune(source[i]) != c; i++ {
+				if source[i] == '\\' && i+1 < len(source) {
+					i++
+				}
+				sb.WriteByte(source[i])
+			}
+			if i >= len(source) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: source[start:i], pos: start, value: sb.String()})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(source)}), nil
+}
+
+// ------------------------------ parser & type checker
+
+type exprParser struct {
+	tokens []token
+	pos    int
+	nodes  int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokenOp && t.text == text
+}
+
+func (p *exprParser) expectOp(text string) error {
+	if !p.isOp(text) {
+		return fmt.Errorf("expected %q at offset %d, got %q", text, p.peek().pos, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) node(depth int) error {
+	p.nodes++
+	if p.nodes > maxExpressionNodes {
+		return fmt.Errorf("expression has more than %d nodes", maxExpressionNodes)
+	}
+	if depth > maxExpressionDepth {
+		return fmt.Errorf("expression nested deeper than %d", maxExpressionDepth)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr(depth int) (exprNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical("||", left, right, depth); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd(depth int) (exprNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical("&&", left, right, depth); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) logical(op string, left, right exprNode, depth int) (exprNode, error) {
+	if err := p.node(depth); err != nil {
+		return nil, err
+	}
+	for _, operand := range []exprNode{left, right} {
+		if k := operand.kind(); k != kindBool && k != kindAny {
+			return nil, fmt.Errorf("operand of %s must be bool, got %s", op, k)
+		}
+	}
+	return &binaryNode{op: op, left: left, right: right, k: kindBool}, nil
+}
+
+func (p *exprParser) parseNot(depth int) (exprNode, error) {
+	if !p.isOp("!") {
+		return p.parseCompare(depth)
+	}
+	p.next()
+	if err := p.node(depth + 1); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseNot(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if k := operand.kind(); k != kindBool && k != kindAny {
+		return nil, fmt.Errorf("operand of ! must be bool, got %s", k)
+	}
+	return &unaryNode{op: "!", operand: operand}, nil
+}
+
+func (p *exprParser) parseCompare(depth int) (exprNode, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		op = t.text
+	case t.kind == tokenIdent && t.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.node(depth); err != nil {
+		return nil, err
+	}
+
+	lk, rk := left.kind(), right.kind()
+	switch op {
+	case "==", "!=":
+		if lk != kindAny && rk != kindAny && lk != kindNull && rk != kindNull && lk != rk {
+			return nil, fmt.Errorf("cannot compare %s %s %s", lk, op, rk)
+		}
+	case "in":
+		if rk != kindList && rk != kindAny {
+			return nil, fmt.Errorf("right operand of in must be a list, got %s", rk)
+		}
+	default:
+		for _, k := range []exprKind{lk, rk} {
+			if k != kindAny && k != kindNumber && k != kindString {
+				return nil, fmt.Errorf("cannot order %s values with %s", k, op)
+			}
+		}
+		if lk != kindAny && rk != kindAny && lk != rk {
+			return nil, fmt.Errorf("cannot compare %s %s %s", lk, op, rk)
+		}
+	}
+	return &binaryNode{op: op, left: left, right: right, k: kindBool}, nil
+}
+
+func (p *exprParser) parsePrimary(depth int) (exprNode, error) {
+	if err := p.node(depth); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &literalNode{value: t.value, k: kindNumber}, nil
+	case tokenString:
+		return &literalNode{value: t.value, k: kindString}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true, k: kindBool}, nil
+		case "false":
+			return &literalNode{value: false, k: kindBool}, nil
+		case "null", "nil":
+			return &literalNode{value: nil, k: kindNull}, nil
+		}
+		return p.parsePath(t)
+	case tokenOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expectOp(")")
+		case "[":
+			list := &listNode{}
+			for !p.isOp("]") {
+				if len(list.elements) > 0 {
+					if err := p.expectOp(","); err != nil {
+						return nil, err
+					}
+				}
+				element, err := p.parsePrimary(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				list.elements = append(list.elements, element)
+			}
+			p.next()
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+func (p *exprParser) parsePath(root token) (exprNode, error) {
+	path := &pathNode{root: root.text}
+	for p.isOp(".") {
+		p.next()
+		field := p.next()
+		if field.kind != tokenIdent {
+			return nil, fmt.Errorf("expected field name at offset %d", field.pos)
+		}
+		path.fields = append(path.fields, field.text)
+	}
+
+	var known map[string]exprKind
+	switch path.root {
+	case "params", "ctx":
+		if len(path.fields) == 0 {
+			return nil, fmt.Errorf("%s needs a field, e.g. %s.name", path.root, path.root)
+		}
+		path.k = kindAny
+		return path, nil
+	case "user":
+		known = userFields
+	case "item":
+		known = itemFields
+	case "time":
+		known = timeFields
+	default:
+		return nil, fmt.Errorf("unknown identifier %q at offset %d", path.root, root.pos)
+	}
+	if len(path.fields) != 1 {
+		return nil, fmt.Errorf("%s needs exactly one field", path.root)
+	}
+	k, ok := known[path.fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s.%s", path.root, path.fields[0])
+	}
+	path.k = k
+	return path, nil
+}
+
+// ------------------------------ evaluation
+
+type exprEnv struct {
+	ctx    context.Context
+	userId interface{}
+	item   Item
+	params map[string]interface{}
+	now    time.Time
+	steps  int
+}
+
+func (env *exprEnv) step() error {
+	env.steps++
+	if env.steps > maxExpressionSteps {
+		return errExpressionBudget
+	}
+	if env.ctx != nil && env.steps%64 == 0 {
+		return env.ctx.Err()
+	}
+	return nil
+}
+
+func (env *exprEnv) eval(node exprNode) (interface{}, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case *literalNode:
+		return n.value, nil
+	case *pathNode:
+		return env.resolve(n), nil
+	case *listNode:
+		values := make([]interface{}, 0, len(n.elements))
+		for _, element := range n.elements {
+			value, err := env.eval(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case *unaryNode:
+		value, err := env.evalBool(n.operand)
+		return !value, err
+	case *binaryNode:
+		return env.evalBinary(n)
+	}
+	return nil, fmt.Errorf("unknown expression node %T", node)
+}
+
+func (env *exprEnv) evalBool(node exprNode) (bool, error) {
+	value, err := env.eval(node)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v is %T, not bool", value, value)
+	}
+	return b, nil
+}
+
+func (env *exprEnv) evalBinary(n *binaryNode) (interface{}, error) {
+	switch n.op {
+	case "&&", "||":
+		left, err := env.evalBool(n.left)
+		if err != nil {
+			return nil, err
+		}
+		// 短路求值
+		if (n.op == "&&") != left {
+			return left, nil
+		}
+		return env.evalBool(n.right)
+	}
+
+	left, err := env.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := env.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "in":
+		list := reflect.ValueOf(right)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return false, nil
+		}
+		for i := 0; i < list.Len(); i++ {
+			if err := env.step(); err != nil {
+				return nil, err
+			}
+			if exprEqual(left, list.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			less, equal := compareNumbers(l, r)
+			return compareOrdered(n.op, less, equal), nil
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return compareOrdered(n.op, l < r, l == r), nil
+		}
+	}
+	return false, fmt.Errorf("cannot compare %T %s %T", left, n.op, right)
+}
+
+func compareOrdered(op string, less bool, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+func exprEqual(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if l, ok := toNumber(left); ok {
+		r, ok := toNumber(right)
+		if !ok {
+			return false
+		}
+		_, equal := compareNumbers(l, r)
+		return equal
+	}
+	lv, rv := reflect.ValueOf(left), reflect.ValueOf(right)
+	if lv.Type().Comparable() && rv.Type().Comparable() {
+		return left == right
+	}
+	return false
+}
+
+// number 表达式中的数值，整数保持 int64 或 uint64 的精确值，避免超过 2^53 的 ID 转为 float64 后相等
+type number struct {
+	kind reflect.Kind // reflect.Int64、reflect.Uint64 或 reflect.Float64
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func (n number) float() float64 {
+	switch n.kind {
+	case reflect.Int64:
+		return float64(n.i)
+	case reflect.Uint64:
+		return float64(n.u)
+	}
+	return n.f
+}
+
+func toNumber(value interface{}) (number, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: reflect.Int64, i: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: reflect.Uint64, u: v.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: reflect.Float64, f: v.Float()}, true
+	}
+	return number{}, false
+}
+
+// compareNumbers 两侧均为整数时精确比较（含有符号与无符号混合），只有一侧是浮点数时才按 float64 比较
+func compareNumbers(l, r number) (less bool, equal bool) {
+	switch {
+	case l.kind == reflect.Float64 || r.kind == reflect.Float64:
+		lf, rf := l.float(), r.float()
+		return lf < rf, lf == rf
+	case l.kind == reflect.Int64 && r.kind == reflect.Int64:
+		return l.i < r.i, l.i == r.i
+	case l.kind == reflect.Uint64 && r.kind == reflect.Uint64:
+		return l.u < r.u, l.u == r.u
+	case l.kind == reflect.Int64:
+		if l.i < 0 {
+			return true, false
+		}
+		return uint64(l.i) < r.u, uint64(l.i) == r.u
+	default:
+		if r.i < 0 {
+			return false, false
+		}
+		return l.u < uint64(r.i), l.u == uint64(r.i)
+	}
+}
+
+// parseNumber 整数字面量解析为 int64（超出时为 uint64），带小数点的解析为 float64
+func parseNumber(text string) (interface{}, error) {
+	if !strings.Contains(text, ".") {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func (env *exprEnv) resolve(path *pathNode) interface{} {
+	switch path.root {
+	case "user":
+		return env.userId
+	case "item":
+		if env.item == nil {
+			return nil
+		}
+		switch path.fields[0] {
+		case "name":
+			return env.item.GetName()
+		case "type":
+			return float64(env.item.GetType().Value())
+		case "description":
+			return env.item.GetDescription()
+		case "rule_name":
+			return env.item.GetRuleName()
+		case "execute_name":
+			return env.item.GetExecuteName()
+		}
+	case "time":
+		switch path.fields[0] {
+		case "year":
+			return float64(env.now.Year())
+		case "month":
+			return float64(env.now.Month())
+		case "day":
+			return float64(env.now.Day())
+		case "hour":
+			return float64(env.now.Hour())
+		case "minute":
+			return float64(env.now.Minute())
+		case "weekday":
+			return float64(env.now.Weekday())
+		case "unix":
+			return float64(env.now.Unix())
+		}
+	case "params":
+		if env.params == nil {
+			return nil
+		}
+		return lookupFields(env.params, path.fields)
+	case "ctx":
+		if env.ctx == nil {
+			return nil
+		}
+		return lookupFields(env.ctx.Value(ContextKey(path.fields[0])), path.fields[1:])
+	}
+	return nil
+}
+
+// lookupFields 沿字段逐级读取字符串键的 map，缺失时返回 nil
+func lookupFields(value interface{}, fields []string) interface{} {
+	for _, field := range fields {
+		if value == nil {
+			return nil
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			value = m[field]
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		entry := v.MapIndex(reflect.ValueOf(field).Convert(v.Type().Key()))
+		if !entry.IsValid() {
+			return nil
+		}
+		value = entry.Interface()
+	}
+	return value
+}
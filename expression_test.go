@@ -0,0 +1,203 @@
+package gorbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"empty", "  ", "empty expression"},
+		{"too long", strings.Repeat("a", maxExpressionLength+1), "longer than"},
+		{"unterminated string", `params.name == "abc`, "unterminated string"},
+		{"unexpected character", "params.a # 1", "unexpected character"},
+		{"trailing token", "true true", "unexpected"},
+		{"unknown identifier", "foo.bar == 1", "unknown identifier"},
+		{"unknown field", "item.owner == 1", "unknown field"},
+		{"params without field", "params == 1", "needs a field"},
+		{"non bool result", "item.name", "must be bool"},
+		{"compare mismatch", `item.name == 1`, "cannot compare"},
+		{"order bools", "true < false", "cannot order"},
+		{"in non list", `item.name in "abc"`, "must be a list"},
+		{"and non bool", "item.type && true", "operand of && must be bool"},
+		{"not non bool", "!item.name", "operand of ! must be bool"},
+		{"missing paren", "(true", `expected ")"`},
+		{"too deep", strings.Repeat("(", maxExpressionDepth+2) + "true" + strings.Repeat(")", maxExpressionDepth+2), "nested deeper"},
+		{"too many nodes", strings.TrimSuffix(strings.Repeat("true || ", maxExpressionNodes), " || "), "more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileExpression(tt.source)
+			if err == nil {
+				t.Fatalf("CompileExpression(%q) succeeded, want error containing %q", tt.source, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("CompileExpression(%q) error = %q, want it to contain %q", tt.source, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	now := time.Now()
+	item := NewPermission("posts:edit", "edit posts", "owner", "", now, now)
+	ctx := WithParams(context.Background(), map[string]interface{}{
+		"owner_id": 5,
+		"status":   "draft",
+		"tags":     []string{"go", "rbac"},
+		"meta":     map[string]interface{}{"level": 3},
+	})
+	ctx = context.WithValue(ctx, ContextKey("tenant"), "acme")
+
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+		err    bool
+	}{
+		{"user matches param", "params.owner_id == user.id", true, false},
+		{"number literal", "params.owner_id == 5.0", true, false},
+		{"not equal", `params.status != "draft"`, false, false},
+		{"item field", `item.name == "posts:edit" && item.rule_name == 'owner'`, true, false},
+		{"in list literal", `params.status in ["draft", "review"]`, true, false},
+		{"in param list", `"rbac" in params.tags`, true, false},
+		{"in non list param", `"x" in params.status`, false, false},
+		{"nested param", "params.meta.level >= 3", true, false},
+		{"ordered strings", `params.status < "e"`, true, false},
+		{"missing param is null", "params.missing == null", true, false},
+		{"ctx value", `ctx.tenant == "acme"`, true, false},
+		{"negation", "!(params.owner_id == 6)", true, false},
+		{"or short circuit", "true || params.status < 1", true, false},
+		{"and short circuit", "false && params.status < 1", false, false},
+		{"precedence", "false && false || true", true, false},
+		{"time field", "time.year > 2000", true, false},
+		{"dynamic compare error", "params.status < 1", false, true},
+		{"dynamic non bool", "params.status", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := CompileExpression(tt.source)
+			if err != nil {
+				t.Fatalf("CompileExpression(%q): %v", tt.source, err)
+			}
+			got, err := expression.Evaluate(ctx, 5, item)
+			if (err != nil) != tt.err {
+				t.Fatalf("Evaluate(%q) error = %v, want error %v", tt.source, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionLargeIntegers(t *testing.T) {
+	// 两个 ID 只在 2^53 以上不同，转为 float64 后相等
+	const owner, other int64 = 1<<53 + 1, 1 << 53
+	ctx := WithParams(context.Background(), map[string]interface{}{
+		"owner_id":  owner,
+		"uint_id":   uint64(owner),
+		"max_uint":  uint64(1<<64 - 1),
+		"negative":  int64(-1),
+		"ratio":     0.5,
+		"owner_ids": []int64{owner},
+	})
+
+	tests := []struct {
+		name   string
+		source string
+		userId interface{}
+		want   bool
+	}{
+		{"equal", "params.owner_id == user.id", owner, true},
+		{"differ above 2^53", "params.owner_id == user.id", other, false},
+		{"not equal above 2^53", "params.owner_id != user.id", other, true},
+		{"in list above 2^53", "user.id in params.owner_ids", other, false},
+		{"less above 2^53", "user.id < params.owner_id", other, true},
+		{"greater above 2^53", "user.id > params.owner_id", other, false},
+		{"uint and int equal", "params.uint_id == user.id", owner, true},
+		{"uint and int differ", "params.uint_id == user.id", other, false},
+		{"negative below uint", "params.negative < params.max_uint", nil, true},
+		{"uint above int", "params.max_uint > user.id", int64(1<<63 - 1), true},
+		{"negative never equals uint", "params.negative == params.max_uint", nil, false},
+		{"integer literal", "user.id == 9007199254740993", owner, true},
+		{"integer literal differs", "user.id == 9007199254740993", other, false},
+		{"uint literal", "params.max_uint == 18446744073709551615", nil, true},
+		{"float falls back", "params.ratio < user.id", other, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := CompileExpression(tt.source)
+			if err != nil {
+				t.Fatalf("CompileExpression(%q): %v", tt.source, err)
+			}
+			got, err := expression.Evaluate(ctx, tt.userId, nil)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): %v", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) with user %v = %v, want %v", tt.source, tt.userId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionEvaluateBudget(t *testing.T) {
+	list := make([]interface{}, maxExpressionSteps)
+	ctx := WithParams(context.Background(), map[string]interface{}{"list": list})
+	expression, err := CompileExpression("1 in params.list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expression.Evaluate(ctx, 1, nil); err != errExpressionBudget {
+		t.Errorf("Evaluate error = %v, want %v", err, errExpressionBudget)
+	}
+}
+
+func TestExpressionRuleCheckAccess(t *testing.T) {
+	now := time.Now()
+	manager := NewDefaultManager(NewMemoryRepository(), true)
+	registry := NewExecutorRegistry()
+	registry.AddExecutor(NewExpressionExecutor())
+	manager.SetExecutorRegistry(registry)
+
+	rule := NewExpressionRule("owner", "params.owner_id == user.id", now, now)
+	if !manager.AddRule(*rule) {
+		t.Fatal("AddRule failed")
+	}
+	edit := NewPermission("posts:edit", "", "owner", "", now, now)
+	manager.Add(edit)
+	manager.Assign(edit, 5)
+
+	tests := []struct {
+		name    string
+		owner   interface{}
+		allowed bool
+	}{
+		{"owner", 5, true},
+		{"other user", 6, false},
+		{"missing param", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]interface{}{}
+			if tt.owner != nil {
+				params["owner_id"] = tt.owner
+			}
+			ctx := WithParams(context.Background(), params)
+			if got := manager.CheckAccess(ctx, 5, "posts:edit"); got != tt.allowed {
+				t.Errorf("CheckAccess = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+
+	if manager.AddRule(*NewExpressionRule("broken", "params.x ==", now, now)) {
+		t.Error("AddRule accepted an invalid expression")
+	}
+}
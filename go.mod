@@ -1,5 +1,5 @@
 module github.com/kordar/gorbac
 
-go 1.16
+go 1.18
 
-require github.com/kordar/gologger v0.0.8
+require gopkg.in/yaml.v3 v3.0.1
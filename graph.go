@@ -0,0 +1,203 @@
+package gorbac
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GraphOptions 导出层级图的范围，Root 与 UserId 同时设置时取交集
+type GraphOptions struct {
+	// Root 非空时只导出该 item 及其所有后代
+	Root string
+	// UserId 非 nil 时只导出用户分配的 item、默认角色及其所有后代（不执行规则）
+	UserId interface{}
+}
+
+// hierarchyGraph 从仓库读取的 item 与父子关系
+type hierarchyGraph struct {
+	items []Item
+	edges []*ItemChild
+}
+
+// ExportDOT 以 Graphviz DOT 格式导出 item 层级：角色为圆角矩形，权限为椭圆，绑定规则或执行器的 item 使用虚线边框并标注
+func (manager *DefaultManager) ExportDOT(w io.Writer, options GraphOptions) error {
+	graph, err := manager.hierarchyGraph(options)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph rbac {\n")
+	sb.WriteString("  rankdir=TB;\n")
+	sb.WriteString("  node [fontname=\"Helvetica\", style=filled];\n")
+	for _, item := range graph.items {
+		shape, fill, style := "ellipse", "#d1e7dd", "filled"
+		if item.GetType() == RoleType {
+			shape, fill, style = "box", "#cfe2ff", "rounded,filled"
+		}
+		if isGated(item) {
+			style += ",dashed"
+		}
+		label := item.GetName()
+		if note := gateNote(item); note != "" {
+			label += "\n" + note
+		}
+		fmt.Fprintf(&sb, "  %s [shape=%s, style=%q, fillcolor=%q, label=%s];\n", dotQuote(item.GetName()), shape, style, fill, dotQuote(label))
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&sb, "  %s -> %s;\n", dotQuote(edge.Parent), dotQuote(edge.Child))
+	}
+	sb.WriteString("}\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// ExportMermaid 以 Mermaid flowchart 格式导出 item 层级，样式同 ExportDOT
+func (manager *DefaultManager) ExportMermaid(w io.Writer, options GraphOptions) error {
+	graph, err := manager.hierarchyGraph(options)
+	if err != nil {
+		return err
+	}
+
+	// Mermaid 节点 ID 不能包含任意字符，按顺序编号
+	ids := make(map[string]string, len(graph.items))
+	gated := make([]string, 0)
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	for i, item := range graph.items {
+		id := fmt.Sprintf("n%d", i)
+		ids[item.GetName()] = id
+		label := mermaidEscape(item.GetName())
+		if note := gateNote(item); note != "" {
+			label += "<br/><i>" + mermaidEscape(note) + "</i>"
+		}
+		class, left, right := "permission", "([", "])"
+		if item.GetType() == RoleType {
+			class, left, right = "role", "(", ")"
+		}
+		fmt.Fprintf(&sb, "  %s%s\"%s\"%s:::%s\n", id, left, label, right, class)
+		if isGated(item) {
+			gated = append(gated, id)
+		}
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&sb, "  %s --> %s\n", ids[edge.Parent], ids[edge.Child])
+	}
+	if len(gated) > 0 {
+		fmt.Fprintf(&sb, "  class %s gated\n", strings.Join(gated, ","))
+	}
+	sb.WriteString("  classDef role fill:#cfe2ff,stroke:#084298\n")
+	sb.WriteString("  classDef permission fill:#d1e7dd,stroke:#0f5132\n")
+	sb.WriteString("  classDef gated stroke-dasharray:5 5\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// hierarchyGraph 读取所有 item 与父子关系并按 options 裁剪，角色在前，同类型按名称排序
+func (manager *DefaultManager) hierarchyGraph(options GraphOptions) (*hierarchyGraph, error) {
+	items, err := manager.mapper.FindAllItems()
+	if err != nil {
+		return nil, fmt.Errorf("find all items: %v", err)
+	}
+	edges, err := manager.mapper.FindChildrenList()
+	if err != nil {
+		return nil, fmt.Errorf("find children list: %v", err)
+	}
+
+	children := make(map[string][]string)
+	for _, edge := range edges {
+		children[edge.Parent] = append(children[edge.Parent], edge.Child)
+	}
+	reach := func(roots []string) map[string]bool {
+		result := make(map[string]bool)
+		for _, root := range roots {
+			result[root] = true
+			manager.walkHierarchy(root, func(name string) []string { return children[name] }, 0, func(name string, depth int) {
+				result[name] = true
+			})
+		}
+		return result
+	}
+
+	var keep []map[string]bool
+	if options.Root != "" {
+		keep = append(keep, reach([]string{options.Root}))
+	}
+	if options.UserId != nil {
+		roots := make([]string, 0)
+		for name := range manager.GetAssignments(options.UserId) {
+			roots = append(roots, name)
+		}
+		for _, role := range manager.GetDefaultRoles() {
+			roots = append(roots, role.GetName())
+		}
+		keep = append(keep, reach(roots))
+	}
+	included := func(name string) bool {
+		for _, set := range keep {
+			if !set[name] {
+				return false
+			}
+		}
+		return true
+	}
+
+	graph := &hierarchyGraph{items: make([]Item, 0), edges: make([]*ItemChild, 0)}
+	names := make(map[string]bool)
+	for _, item := range items {
+		if included(item.GetName()) {
+			graph.items = append(graph.items, item)
+			names[item.GetName()] = true
+		}
+	}
+	for _, edge := range edges {
+		if names[edge.Parent] && names[edge.Child] {
+			graph.edges = append(graph.edges, edge)
+		}
+	}
+
+	sort.Slice(graph.items, func(i, j int) bool {
+		a, b := graph.items[i], graph.items[j]
+		if a.GetType() != b.GetType() {
+			return a.GetType() == RoleType
+		}
+		return a.GetName() < b.GetName()
+	})
+	sort.Slice(graph.edges, func(i, j int) bool {
+		if graph.edges[i].Parent != graph.edges[j].Parent {
+			return graph.edges[i].Parent < graph.edges[j].Parent
+		}
+		return graph.edges[i].Child < graph.edges[j].Child
+	})
+	return graph, nil
+}
+
+func isGated(item Item) bool {
+	return item.GetRuleName() != "" || item.GetExecuteName() != ""
+}
+
+func gateNote(item Item) string {
+	notes := make([]string, 0, 2)
+	if item.GetRuleName() != "" {
+		notes = append(notes, "rule: "+item.GetRuleName())
+	}
+	if item.GetExecuteName() != "" {
+		notes = append(notes, "executor: "+item.GetExecuteName())
+	}
+	return strings.Join(notes, ", ")
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
@@ -0,0 +1,58 @@
+package gorbac
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportGraph(t *testing.T) {
+	now := time.Now()
+	manager := newTestManager(t, true)
+	manager.Add(NewPermission(`posts:"quoted"`, "", "owner", "", now, now))
+	_ = manager.AddChild(manager.GetRole("editor"), manager.GetPermission(`posts:"quoted"`))
+
+	tests := []struct {
+		name     string
+		export   func(w *bytes.Buffer, options GraphOptions) error
+		options  GraphOptions
+		contains []string
+		excludes []string
+	}{
+		{"dot", func(w *bytes.Buffer, o GraphOptions) error { return manager.ExportDOT(w, o) }, GraphOptions{},
+			[]string{"digraph rbac {", `"admin" [shape=box, style="rounded,filled"`, `"admin" -> "editor";`, `"posts:\"quoted\"" [shape=ellipse, style="filled,dashed"`, `label="posts:\"quoted\"\nrule: owner"`},
+			nil},
+		{"dot root", func(w *bytes.Buffer, o GraphOptions) error { return manager.ExportDOT(w, o) }, GraphOptions{Root: "viewer"},
+			[]string{`"viewer" -> "posts:view";`},
+			[]string{`"admin"`, `"editor"`}},
+		{"dot user", func(w *bytes.Buffer, o GraphOptions) error { return manager.ExportDOT(w, o) }, GraphOptions{UserId: 1, Root: "editor"},
+			[]string{`"editor" -> "posts:edit";`},
+			[]string{`"viewer"`}},
+		{"dot unassigned user", func(w *bytes.Buffer, o GraphOptions) error { return manager.ExportDOT(w, o) }, GraphOptions{UserId: 99},
+			[]string{"digraph rbac {"},
+			[]string{"->", `"admin"`}},
+		{"mermaid", func(w *bytes.Buffer, o GraphOptions) error { return manager.ExportMermaid(w, o) }, GraphOptions{},
+			[]string{"flowchart TD", `n0("admin"):::role`, "n0 --> n1", `"posts:#quot;quoted#quot;<br/><i>rule: owner</i>"`, "class n3 gated", "classDef gated"},
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.export(&buf, tt.options); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output does not contain %q:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("output contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
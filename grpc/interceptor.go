@@ -0,0 +1,167 @@
+// Package grpc 提供基于 gorbac 的 gRPC 一元与流式服务端拦截器：按完整方法名（/pkg.Service/Method）
+// 映射所需权限，从 incoming metadata 解析用户，并以请求 ctx 调用 CheckAccess。
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kordar/gorbac"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UserResolver 从 incoming metadata 中解析用户 ID，ok 为 false 时返回 Unauthenticated
+type UserResolver func(ctx context.Context, md metadata.MD) (userId interface{}, ok bool)
+
+// ParamsResolver 提取规则参数，执行器通过 gorbac.ParamsFromContext 读取；流式调用时 req 为 nil
+type ParamsResolver func(ctx context.Context, fullMethod string, req interface{}) map[string]interface{}
+
+// MethodRule 方法所需的权限，Permissions 为空时不做校验（无需用户）
+type MethodRule struct {
+	Permissions []string
+	// Any 为 true 时拥有任意一个权限即可
+	Any bool
+}
+
+type Options struct {
+	// Manager 权限校验，必填
+	Manager gorbac.Access
+	// Resolver 用户解析器，必填
+	Resolver UserResolver
+	// Methods 完整方法名到权限的映射，"/pkg.Service/*" 匹配服务下未单独列出的方法
+	Methods map[string]MethodRule
+	// AllowUnmatched 为 true 时未映射的方法直接放行，默认返回 PermissionDenied
+	AllowUnmatched bool
+	// Params 规则参数解析器，可选，结果与 ctx 中已有的规则参数合并
+	Params ParamsResolver
+}
+
+// Interceptor 权限校验拦截器
+type Interceptor struct {
+	options Options
+}
+
+func NewInterceptor(options Options) (*Interceptor, error) {
+	if options.Manager == nil {
+		return nil, errors.New("gorbac/grpc: Options.Manager is required")
+	}
+	if options.Resolver == nil {
+		return nil, errors.New("gorbac/grpc: Options.Resolver is required")
+	}
+	for method := range options.Methods {
+		if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			return nil, fmt.Errorf("gorbac/grpc: invalid method name '%s', expected /pkg.Service/Method", method)
+		}
+	}
+	return &Interceptor{options: options}, nil
+}
+
+// Unary 一元调用拦截器
+func (interceptor *Interceptor) Unary() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		ctx, err := interceptor.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream 流式调用拦截器，在建立流时校验一次
+func (interceptor *Interceptor) Stream() grpclib.StreamServerInterceptor {
+	return func(srv interface{}, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		ctx, err := interceptor.authorize(stream.Context(), info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// rule 先按完整方法名查找，再按服务通配查找
+func (interceptor *Interceptor) rule(fullMethod string) (MethodRule, bool) {
+	if rule, ok := interceptor.options.Methods[fullMethod]; ok {
+		return rule, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		rule, ok := interceptor.options.Methods[fullMethod[:i]+"/*"]
+		return rule, ok
+	}
+	return MethodRule{}, false
+}
+
+// authorize 校验通过后返回附加了用户 ID 与规则参数的 ctx
+func (interceptor *Interceptor) authorize(ctx context.Context, fullMethod string, req interface{}) (context.Context, error) {
+	rule, ok := interceptor.rule(fullMethod)
+	if !ok {
+		if interceptor.options.AllowUnmatched {
+			return ctx, nil
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not mapped to any permission", fullMethod)
+	}
+	if len(rule.Permissions) == 0 {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	userId, ok := interceptor.options.Resolver(ctx, md)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	if params := interceptor.params(ctx, fullMethod, req); len(params) > 0 {
+		ctx = gorbac.WithParams(ctx, params)
+	}
+
+	denied := make([]string, 0)
+	for _, permission := range rule.Permissions {
+		if interceptor.options.Manager.CheckAccess(ctx, userId, permission) {
+			if rule.Any {
+				denied = denied[:0]
+				break
+			}
+		} else {
+			denied = append(denied, permission)
+		}
+	}
+	if len(denied) > 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "permission denied: %s", strings.Join(denied, ", "))
+	}
+	return context.WithValue(ctx, userKey{}, userId), nil
+}
+
+// params 合并 ctx 中已有的规则参数与 ParamsResolver 的结果，后者优先
+func (interceptor *Interceptor) params(ctx context.Context, fullMethod string, req interface{}) map[string]interface{} {
+	params := make(map[string]interface{})
+	for key, value := range gorbac.ParamsFromContext(ctx) {
+		params[key] = value
+	}
+	if interceptor.options.Params != nil {
+		for key, value := range interceptor.options.Params(ctx, fullMethod, req) {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+type userKey struct{}
+
+// UserIdFromContext 返回拦截器解析出的用户 ID，未经过校验的方法返回 false
+func UserIdFromContext(ctx context.Context) (interface{}, bool) {
+	userId := ctx.Value(userKey{})
+	return userId, userId != nil
+}
+
+type serverStream struct {
+	grpclib.ServerStream
+	ctx context.Context
+}
+
+func (stream *serverStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/kordar/gorbac"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestOptions 用户 1 分配 ops（含 health:check），用户取自 metadata user-id
+func newTestOptions(methods map[string]MethodRule, allowUnmatched bool) Options {
+	service := gorbac.NewRbacService(gorbac.NewMemoryRepository(), true)
+	service.AddRole("ops", "", "")
+	service.AddPermission("health:check", "", "")
+	service.AddPermission("health:watch", "", "")
+	_ = service.AssignChildren("ops", "health:check")
+	service.Assign(1, "ops")
+	return Options{
+		Manager:        service.GetAuthManager(),
+		Methods:        methods,
+		AllowUnmatched: allowUnmatched,
+		Resolver: func(ctx context.Context, md metadata.MD) (interface{}, bool) {
+			values := md.Get("user-id")
+			if len(values) == 0 {
+				return nil, false
+			}
+			id, err := strconv.Atoi(values[0])
+			return id, err == nil
+		},
+	}
+}
+
+// dialHealth 在 bufconn 上启动挂载拦截器的 health 服务
+func dialHealth(t *testing.T, interceptor *Interceptor) healthpb.HealthClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpclib.NewServer(grpclib.UnaryInterceptor(interceptor.Unary()), grpclib.StreamInterceptor(interceptor.Stream()))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpclib.Dial("passthrough:///bufconn",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestInterceptor(t *testing.T) {
+	checkOnly := map[string]MethodRule{"/grpc.health.v1.Health/Check": {Permissions: []string{"health:check"}}}
+	service := map[string]MethodRule{"/grpc.health.v1.Health/*": {Permissions: []string{"health:check"}}}
+	anyOf := map[string]MethodRule{"/grpc.health.v1.Health/*": {Permissions: []string{"health:watch", "health:check"}, Any: true}}
+	allOf := map[string]MethodRule{"/grpc.health.v1.Health/*": {Permissions: []string{"health:watch", "health:check"}}}
+
+	tests := []struct {
+		name           string
+		methods        map[string]MethodRule
+		allowUnmatched bool
+		userId         string
+		check          codes.Code
+		watch          codes.Code
+	}{
+		{"allowed", checkOnly, true, "1", codes.OK, codes.OK},
+		{"denied", checkOnly, true, "2", codes.PermissionDenied, codes.OK},
+		{"unauthenticated", checkOnly, true, "", codes.Unauthenticated, codes.OK},
+		{"unmapped denied by default", checkOnly, false, "1", codes.OK, codes.PermissionDenied},
+		{"unmapped denied without user", checkOnly, false, "", codes.Unauthenticated, codes.PermissionDenied},
+		{"service wildcard", service, false, "1", codes.OK, codes.OK},
+		{"service wildcard denied", service, false, "2", codes.PermissionDenied, codes.PermissionDenied},
+		{"any", anyOf, false, "1", codes.OK, codes.OK},
+		{"all", allOf, false, "1", codes.PermissionDenied, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor, err := NewInterceptor(newTestOptions(tt.methods, tt.allowUnmatched))
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := dialHealth(t, interceptor)
+			ctx := context.Background()
+			if tt.userId != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "user-id", tt.userId)
+			}
+
+			_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+			if got := status.Code(err); got != tt.check {
+				t.Errorf("Check code = %s, want %s (%v)", got, tt.check, err)
+			}
+
+			// 流式调用在建立流时校验，错误在首次 Recv 时返回
+			stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if got := status.Code(err); got != tt.watch {
+				t.Errorf("Watch code = %s, want %s (%v)", got, tt.watch, err)
+			}
+		})
+	}
+}
+
+func TestInterceptorContext(t *testing.T) {
+	options := newTestOptions(map[string]MethodRule{"/pkg.Service/Get": {Permissions: []string{"health:check"}}}, false)
+	options.Params = func(ctx context.Context, fullMethod string, req interface{}) map[string]interface{} {
+		return map[string]interface{}{"method": fullMethod, "req": req}
+	}
+	interceptor, err := NewInterceptor(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 上游拦截器附加的参数保留，同名参数以 Params 的结果为准
+	ctx := gorbac.WithParams(context.Background(), map[string]interface{}{"tenant": "acme", "method": "upstream"})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("user-id", "1"))
+	info := &grpclib.UnaryServerInfo{FullMethod: "/pkg.Service/Get"}
+	_, err = interceptor.Unary()(ctx, "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if userId, ok := UserIdFromContext(ctx); !ok || userId != 1 {
+			t.Errorf("UserIdFromContext = (%v, %v), want (1, true)", userId, ok)
+		}
+		params := gorbac.ParamsFromContext(ctx)
+		if params["method"] != "/pkg.Service/Get" || params["req"] != "request" || params["tenant"] != "acme" {
+			t.Errorf("params = %v", params)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewInterceptorErrors(t *testing.T) {
+	valid := newTestOptions(nil, false)
+	tests := []struct {
+		name   string
+		modify func(options *Options)
+	}{
+		{"no manager", func(options *Options) { options.Manager = nil }},
+		{"no resolver", func(options *Options) { options.Resolver = nil }},
+		{"invalid method", func(options *Options) { options.Methods = map[string]MethodRule{"pkg.Service/Get": {}} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := valid
+			tt.modify(&options)
+			if _, err := NewInterceptor(options); err == nil {
+				t.Error("NewInterceptor succeeded, want error")
+			}
+		})
+	}
+}
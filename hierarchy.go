@@ -0,0 +1,180 @@
+package gorbac
+
+import (
+	"sort"
+)
+
+// maxHierarchyPaths PathsBetween 最多返回的路径数
+const maxHierarchyPaths = 1024
+
+// HierarchyNode 层级查询的结果，Depth 为与被查询 item 的最短距离，直接父级/子级为 1
+type HierarchyNode struct {
+	Item  Item `json:"item"`
+	Depth int  `json:"depth"`
+}
+
+// GetParents 获取 item 的直接父级
+func (manager *DefaultManager) GetParents(name string) []Item {
+	snapshot := manager.loadFromCache()
+	items := make([]Item, 0)
+	for _, parent := range sortedNames(manager.parentNames(snapshot, name)) {
+		if item := manager.lookupItem(snapshot, parent); item != nil {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetAncestors 获取 item 的所有祖先，按距离由近到远排列
+func (manager *DefaultManager) GetAncestors(name string) []HierarchyNode {
+	snapshot := manager.loadFromCache()
+	return manager.hierarchyNodes(snapshot, name, func(n string) []string {
+		return manager.parentNames(snapshot, n)
+	}, NoneType, 0)
+}
+
+// GetDescendants 获取 item 的所有后代，按距离由近到远排列。
+// itemType 为 NoneType 时不过滤类型，maxDepth <= 0 表示不限深度。
+func (manager *DefaultManager) GetDescendants(name string, itemType ItemType, maxDepth int) []HierarchyNode {
+	snapshot := manager.loadFromCache()
+	return manager.hierarchyNodes(snapshot, name, func(n string) []string {
+		return manager.childNames(snapshot, n)
+	}, itemType, maxDepth)
+}
+
+// PathsBetween 返回从 ancestor 向下到 descendant 的所有路径，每条路径包含两端的 item。
+// ancestor 不是 descendant 的祖先时返回空列表，路径过多时只返回前 maxHierarchyPaths 条。
+func (manager *DefaultManager) PathsBetween(ancestor string, descendant string) [][]string {
+	snapshot := manager.loadFromCache()
+	paths := make([][]string, 0)
+	if manager.lookupItem(snapshot, ancestor) == nil || manager.lookupItem(snapshot, descendant) == nil {
+		return paths
+	}
+
+	// 只沿能到达 descendant 的 item 向下搜索
+	reachable := make(map[string]bool)
+	for _, name := range manager.collectAncestors(snapshot, descendant) {
+		reachable[name] = true
+	}
+	if !reachable[ancestor] {
+		return paths
+	}
+
+	onPath := make(map[string]bool)
+	var walk func(name string, path []string) bool
+	walk = func(name string, path []string) bool {
+		path = append(path, name)
+		if name == descendant {
+			paths = append(paths, append([]string(nil), path...))
+			return len(paths) < maxHierarchyPaths
+		}
+		onPath[name] = true
+		defer delete(onPath, name)
+		for _, child := range sortedNames(manager.childNames(snapshot, name)) {
+			if !reachable[child] || onPath[child] {
+				continue
+			}
+			if !walk(child, path) {
+				return false
+			}
+		}
+		return true
+	}
+	if !walk(ancestor, nil) {
+		manager.log().Warn("[rbac] paths truncated", "ancestor", ancestor, "descendant", descendant, "limit", maxHierarchyPaths)
+	}
+	return paths
+}
+
+func (manager *DefaultManager) hierarchyNodes(snapshot *policySnapshot, name string, next func(name string) []string, itemType ItemType, maxDepth int) []HierarchyNode {
+	nodes := make([]HierarchyNode, 0)
+	manager.walkHierarchy(name, next, maxDepth, func(n string, depth int) {
+		item := manager.lookupItem(snapshot, n)
+		if item == nil || (itemType != NoneType && item.GetType() != itemType) {
+			return
+		}
+		nodes = append(nodes, HierarchyNode{Item: item, Depth: depth})
+	})
+	return nodes
+}
+
+// walkHierarchy 从 name 出发广度优先遍历，每个 item 只访问一次（不含 name 本身），同一 item 的相邻项按名称排序
+func (manager *DefaultManager) walkHierarchy(name string, next func(name string) []string, maxDepth int, visit func(name string, depth int)) {
+	visited := map[string]bool{name: true}
+	level := []string{name}
+	for depth := 1; len(level) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		nextLevel := make([]string, 0)
+		for _, current := range level {
+			for _, n := range sortedNames(next(current)) {
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+				visit(n, depth)
+				nextLevel = append(nextLevel, n)
+			}
+		}
+		level = nextLevel
+	}
+}
+
+// collectAncestors 返回 name 及其所有祖先
+func (manager *DefaultManager) collectAncestors(snapshot *policySnapshot, name string) []string {
+	result := []string{name}
+	manager.walkHierarchy(name, func(n string) []string {
+		return manager.parentNames(snapshot, n)
+	}, 0, func(n string, depth int) {
+		result = append(result, n)
+	})
+	return result
+}
+
+// lookupItem 缓存已加载时从快照读取，否则查询仓库
+func (manager *DefaultManager) lookupItem(snapshot *policySnapshot, name string) Item {
+	if snapshot.loaded {
+		return snapshot.items[name]
+	}
+	item, err := manager.mapper.GetItem(name)
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// parentNames 返回 item 的直接父级，缓存已加载时使用快照的父级索引
+func (manager *DefaultManager) parentNames(snapshot *policySnapshot, name string) []string {
+	if snapshot.loaded {
+		return snapshot.parents[name]
+	}
+	itemChildren, err := manager.mapper.FindChildrenFormChild(name)
+	if err != nil {
+		return nil
+	}
+	parents := make([]string, 0, len(itemChildren))
+	for _, itemChild := range itemChildren {
+		parents = append(parents, itemChild.Parent)
+	}
+	return parents
+}
+
+// childNames 返回 item 的直接子级，缓存已加载时使用快照的子级索引
+func (manager *DefaultManager) childNames(snapshot *policySnapshot, name string) []string {
+	if snapshot.loaded {
+		return snapshot.children[name]
+	}
+	items, err := manager.mapper.FindChildren(name)
+	if err != nil {
+		return nil
+	}
+	children := make([]string, 0, len(items))
+	for _, item := range items {
+		children = append(children, item.GetName())
+	}
+	return children
+}
+
+func sortedNames(names []string) []string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,61 @@
+package gorbac
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func formatNodes(nodes []HierarchyNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		parts = append(parts, fmt.Sprintf("%s:%d", node.Item.GetName(), node.Depth))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newDiamondManager 在 newTestManager 的层级上增加 editor -> posts:view，
+// 使 admin 经 editor 与 viewer 两条路径到达 posts:view
+func newDiamondManager(t *testing.T, cache bool) *DefaultManager {
+	t.Helper()
+	manager := newTestManager(t, cache)
+	if err := manager.AddChild(manager.GetRole("editor"), manager.GetPermission("posts:view")); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestHierarchyQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func(manager *DefaultManager) string
+		want  string
+	}{
+		{"parents", func(m *DefaultManager) string {
+			names := make([]string, 0)
+			for _, item := range m.GetParents("posts:view") {
+				names = append(names, item.GetName())
+			}
+			return strings.Join(names, " ")
+		}, "editor viewer"},
+		{"ancestors", func(m *DefaultManager) string { return formatNodes(m.GetAncestors("posts:view")) }, "editor:1 viewer:1 admin:2"},
+		{"ancestors of root", func(m *DefaultManager) string { return formatNodes(m.GetAncestors("admin")) }, ""},
+		{"descendants", func(m *DefaultManager) string { return formatNodes(m.GetDescendants("admin", NoneType, 0)) }, "editor:1 viewer:1 posts:edit:2 posts:view:2"},
+		{"descendant permissions", func(m *DefaultManager) string { return formatNodes(m.GetDescendants("admin", PermissionType, 0)) }, "posts:edit:2 posts:view:2"},
+		{"descendants max depth", func(m *DefaultManager) string { return formatNodes(m.GetDescendants("admin", NoneType, 1)) }, "editor:1 viewer:1"},
+		{"unknown item", func(m *DefaultManager) string { return formatNodes(m.GetDescendants("ghost", NoneType, 0)) }, ""},
+		{"paths", func(m *DefaultManager) string { return fmt.Sprint(m.PathsBetween("admin", "posts:view")) }, "[[admin editor posts:view] [admin viewer posts:view]]"},
+		{"path to self", func(m *DefaultManager) string { return fmt.Sprint(m.PathsBetween("editor", "editor")) }, "[[editor]]"},
+		{"no path", func(m *DefaultManager) string { return fmt.Sprint(m.PathsBetween("viewer", "posts:edit")) }, "[]"},
+		{"reverse direction", func(m *DefaultManager) string { return fmt.Sprint(m.PathsBetween("posts:view", "admin")) }, "[]"},
+	}
+	for _, tt := range tests {
+		for _, cache := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/cache=%v", tt.name, cache), func(t *testing.T) {
+				if got := tt.query(newDiamondManager(t, cache)); got != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
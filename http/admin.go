@@ -0,0 +1,344 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kordar/gorbac"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+	maxBodySize      = 8 << 20
+)
+
+type AdminOptions struct {
+	// Middleware 保护 API 的中间件，必填
+	Middleware *Middleware
+	// ReadPermission 只读接口所需权限，默认 "rbac:read"
+	ReadPermission string
+	// WritePermission 写接口所需权限，默认 "rbac:write"
+	WritePermission string
+	// RequireIfMatch 为 true 时修改与删除单个资源必须携带 If-Match，否则返回 428
+	RequireIfMatch bool
+	// UserId 将路径中的用户 ID 转为仓库使用的类型，默认十进制整数转为 int，其余保持字符串
+	UserId func(id string) interface{}
+}
+
+// AdminHandler 管理 RBAC 数据的 JSON REST API，路径以 /v1 开头，挂载到子路径时配合 http.StripPrefix 使用
+type AdminHandler struct {
+	service *gorbac.RbacService
+	manager gorbac.AuthManager
+	options AdminOptions
+	routes  []*adminRoute
+	// writes 串行执行写接口，If-Match 的校验与随后的写入之间不会插入其他写请求
+	writes sync.Mutex
+}
+
+// adminFunc 接口处理函数，vars 为路由变量
+type adminFunc func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string)
+
+type adminRoute struct {
+	route  *compiledRoute
+	write  bool
+	handle adminFunc
+}
+
+func NewAdminHandler(service *gorbac.RbacService, options AdminOptions) (*AdminHandler, error) {
+	if service == nil {
+		return nil, errors.New("gorbac/http: service is required")
+	}
+	if options.Middleware == nil {
+		return nil, errors.New("gorbac/http: AdminOptions.Middleware is required")
+	}
+	if options.ReadPermission == "" {
+		options.ReadPermission = "rbac:read"
+	}
+	if options.WritePermission == "" {
+		options.WritePermission = "rbac:write"
+	}
+	handler := &AdminHandler{service: service, manager: service.GetAuthManager(), options: options}
+	if err := handler.register(); err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+func (handler *AdminHandler) handle(method string, pattern string, handle adminFunc) {
+	handler.routes = append(handler.routes, &adminRoute{
+		route:  &compiledRoute{route: Route{Method: method, Pattern: pattern}},
+		write:  method != nethttp.MethodGet,
+		handle: handle,
+	})
+}
+
+// register 注册全部接口并编译路由
+func (handler *AdminHandler) register() error {
+	handler.registerItems()
+	handler.registerRules()
+	handler.registerUsers()
+	handler.registerPolicy()
+
+	table := RouteTable{}
+	for _, route := range handler.routes {
+		table.Routes = append(table.Routes, route.route.route)
+	}
+	compiled, err := compileRoutes(table)
+	if err != nil {
+		return err
+	}
+	for i, route := range handler.routes {
+		route.route = compiled[i]
+	}
+	return nil
+}
+
+// ServeHTTP 匹配路由后经中间件校验读/写权限，路由变量同时作为规则参数
+func (handler *AdminHandler) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	allowed := make([]string, 0)
+	for _, route := range handler.routes {
+		vars, ok := route.route.matchPath(r.URL.Path)
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(route.route.route.Method, r.Method) {
+			allowed = append(allowed, route.route.route.Method)
+			continue
+		}
+		permission := handler.options.ReadPermission
+		if route.write {
+			permission = handler.options.WritePermission
+		}
+		next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			if !route.write {
+				route.handle(w, r, vars)
+				return
+			}
+			// 加锁前读完请求体，慢速客户端不会阻塞其他写请求
+			data, ok := readBody(w, r)
+			if !ok {
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(data))
+			handler.writes.Lock()
+			defer handler.writes.Unlock()
+			route.handle(w, r, vars)
+		})
+		handler.options.Middleware.serve(w, r, next, []string{permission}, false, vars)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, nethttp.StatusMethodNotAllowed, "method %s is not allowed", r.Method)
+		return
+	}
+	writeError(w, nethttp.StatusNotFound, "no route for %s", r.URL.Path)
+}
+
+func (handler *AdminHandler) userId(id string) interface{} {
+	if handler.options.UserId != nil {
+		return handler.options.UserId(id)
+	}
+	if n, err := strconv.Atoi(id); err == nil && strconv.Itoa(n) == id {
+		return n
+	}
+	return id
+}
+
+// changes 绑定请求的管理器视图：ctx 中未设置操作人时以中间件解析出的用户为操作人，
+// 并以请求方法、路径、来源地址与 X-Request-Id 作为审计元数据
+func (handler *AdminHandler) changes(r *nethttp.Request) gorbac.AuthManager {
+	ctx := r.Context()
+	if subject := FromContext(ctx); subject != nil && gorbac.ActorFromContext(ctx) == "" {
+		ctx = gorbac.WithActor(ctx, fmt.Sprint(subject.UserId))
+	}
+	metadata := map[string]string{"method": r.Method, "path": r.URL.Path, "remote_addr": r.RemoteAddr}
+	if id := r.Header.Get("X-Request-Id"); id != "" {
+		metadata["request_id"] = id
+	}
+	return handler.service.WithContext(gorbac.WithAuditMetadata(ctx, metadata)).GetAuthManager()
+}
+
+// ---------------------- 响应 ---------------------------
+
+type apiError struct {
+	Error string `json:"error"`
+	// Fields 校验失败的字段与原因
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type pageResponse struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+}
+
+func writeJSON(w nethttp.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w nethttp.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, apiError{Error: fmt.Sprintf(format, args...)})
+}
+
+// writeValidation 以 422 写出字段校验错误
+func writeValidation(w nethttp.ResponseWriter, fields map[string]string) {
+	writeJSON(w, nethttp.StatusUnprocessableEntity, apiError{Error: "validation failed", Fields: fields})
+}
+
+// writeResource 写出单个资源及其 ETag
+func writeResource(w nethttp.ResponseWriter, status int, resource interface{}) {
+	w.Header().Set("ETag", etag(resource))
+	writeJSON(w, status, resource)
+}
+
+// etag 资源 JSON 的摘要
+func etag(resource interface{}) string {
+	data, _ := json.Marshal(resource)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%x"`, sum[:12])
+}
+
+// notModified 处理 GET 的 If-None-Match
+func notModified(w nethttp.ResponseWriter, r *nethttp.Request, resource interface{}) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag(resource)) {
+		w.Header().Set("ETag", etag(resource))
+		w.WriteHeader(nethttp.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// precondition 校验 If-Match，不满足时写出 412/428 并返回 false。
+// 写接口在 writes 锁内执行，校验通过后直到写入完成，资源不会被同一处理器的其他请求修改
+func (handler *AdminHandler) precondition(w nethttp.ResponseWriter, r *nethttp.Request, current interface{}) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		if handler.options.RequireIfMatch {
+			writeError(w, nethttp.StatusPreconditionRequired, "If-Match header is required")
+			return false
+		}
+		return true
+	}
+	if !etagMatches(match, etag(current)) {
+		writeError(w, nethttp.StatusPreconditionFailed, "resource has been modified")
+		return false
+	}
+	return true
+}
+
+func etagMatches(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------- 请求 ---------------------------
+
+// decode 解析 JSON 请求体，未知字段视为错误，失败时写出 400
+func decode(w nethttp.ResponseWriter, r *nethttp.Request, v interface{}) bool {
+	data, ok := readBody(w, r)
+	if !ok {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, nethttp.StatusBadRequest, "invalid request body: %v", err)
+		return false
+	}
+	return true
+}
+
+// readBody 读取完整的请求体，超过 maxBodySize 时写出 413，不会截断后继续处理
+func readBody(w nethttp.ResponseWriter, r *nethttp.Request) ([]byte, bool) {
+	data, err := ioutil.ReadAll(nethttp.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		// 读满上限后仍有数据时 MaxBytesReader 返回错误（http.MaxBytesError 需要 Go 1.19）
+		if len(data) == maxBodySize {
+			writeError(w, nethttp.StatusRequestEntityTooLarge, "request body is larger than %d bytes", maxBodySize)
+			return nil, false
+		}
+		writeError(w, nethttp.StatusBadRequest, "read request body: %v", err)
+		return nil, false
+	}
+	return data, true
+}
+
+// pagination 读取 offset 与 limit，limit 默认 50、最大 500
+func pagination(w nethttp.ResponseWriter, r *nethttp.Request) (offset int, limit int, ok bool) {
+	fields := make(map[string]string)
+	query := r.URL.Query()
+	limit = defaultPageLimit
+	if value := query.Get("offset"); value != "" {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			fields["offset"] = "must be a non-negative integer"
+		} else {
+			offset = n
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > maxPageLimit {
+			fields["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxPageLimit)
+		} else {
+			limit = n
+		}
+	}
+	if len(fields) > 0 {
+		writeValidation(w, fields)
+		return 0, 0, false
+	}
+	return offset, limit, true
+}
+
+// bounds 返回分页在长度为 total 的列表中的区间
+func bounds(total int, offset int, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+// boolQuery 解析布尔查询参数，缺省为 false
+func boolQuery(r *nethttp.Request, name string) bool {
+	value, _ := strconv.ParseBool(r.URL.Query().Get(name))
+	return value
+}
+
+// ruleParams 查询参数中 "param.<name>" 形式的规则参数
+func ruleParams(r *nethttp.Request) map[string]interface{} {
+	params := make(map[string]interface{})
+	for key, values := range r.URL.Query() {
+		if strings.HasPrefix(key, "param.") && len(values) > 0 {
+			params[strings.TrimPrefix(key, "param.")] = values[0]
+		}
+	}
+	return params
+}
+
+func sortItems(items []gorbac.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GetName() < items[j].GetName()
+	})
+}
@@ -0,0 +1,897 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+// writePage 分页写出长度为 total 的列表，slice 返回 [start, end) 区间的元素
+func writePage(w nethttp.ResponseWriter, r *nethttp.Request, total int, slice func(start int, end int) interface{}) {
+	offset, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	start, end := bounds(total, offset, limit)
+	writeJSON(w, nethttp.StatusOK, pageResponse{Items: slice(start, end), Total: total, Offset: offset, Limit: limit})
+}
+
+// confirmed 批量删除要求 ?confirm=true
+func confirmed(w nethttp.ResponseWriter, r *nethttp.Request) bool {
+	if !boolQuery(r, "confirm") {
+		writeValidation(w, map[string]string{"confirm": "must be true to remove all records"})
+		return false
+	}
+	return true
+}
+
+// ---------------------- Items ---------------------------
+
+type itemRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	RuleName    string `json:"rule_name"`
+	// ExecuteName 省略时更新接口保留原有执行器，为空字符串时清除
+	ExecuteName *string `json:"execute_name"`
+}
+
+func (request itemRequest) executeName() string {
+	if request.ExecuteName == nil {
+		return ""
+	}
+	return *request.ExecuteName
+}
+
+type childRequest struct {
+	Child string `json:"child"`
+}
+
+func (handler *AdminHandler) registerItems() {
+	collections := []struct {
+		path     string
+		itemType gorbac.ItemType
+	}{{"/v1/roles", gorbac.RoleType}, {"/v1/permissions", gorbac.PermissionType}}
+	for _, c := range collections {
+		collection, itemType := c.path, c.itemType
+		handler.handle(nethttp.MethodGet, collection, handler.listItems(itemType))
+		handler.handle(nethttp.MethodPost, collection, handler.createItem(itemType))
+		handler.handle(nethttp.MethodDelete, collection, handler.removeAllItems(itemType))
+		handler.handle(nethttp.MethodGet, collection+"/{name}", handler.getItem(itemType))
+		handler.handle(nethttp.MethodPut, collection+"/{name}", handler.updateItem(itemType))
+		handler.handle(nethttp.MethodDelete, collection+"/{name}", handler.deleteItem(itemType))
+	}
+	handler.handle(nethttp.MethodGet, "/v1/roles/{name}/users", handler.roleUsers)
+	handler.handle(nethttp.MethodGet, "/v1/permissions/{name}/users", handler.permissionUsers)
+
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}", handler.getItem(gorbac.NoneType))
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}/children", handler.listChildren)
+	handler.handle(nethttp.MethodPost, "/v1/items/{name}/children", handler.addChild)
+	handler.handle(nethttp.MethodDelete, "/v1/items/{name}/children", handler.removeChildren)
+	handler.handle(nethttp.MethodDelete, "/v1/items/{name}/children/{child}", handler.removeChild)
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}/parents", handler.listParents)
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}/ancestors", handler.listAncestors)
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}/descendants", handler.listDescendants)
+	handler.handle(nethttp.MethodGet, "/v1/items/{name}/paths/{descendant}", handler.listPaths)
+}
+
+// lookup 查找 item，itemType 为 NoneType 时不限类型，不存在时写出 404
+func (handler *AdminHandler) lookup(w nethttp.ResponseWriter, name string, itemType gorbac.ItemType) (gorbac.Item, bool) {
+	item := handler.manager.GetItem(name)
+	if item == nil || (itemType != gorbac.NoneType && item.GetType() != itemType) {
+		writeError(w, nethttp.StatusNotFound, "item '%s' not found", name)
+		return nil, false
+	}
+	return item, true
+}
+
+func (handler *AdminHandler) validateItem(request itemRequest) map[string]string {
+	fields := make(map[string]string)
+	if request.Name == "" {
+		fields["name"] = "is required"
+	}
+	if request.RuleName != "" && handler.manager.GetRule(request.RuleName) == nil {
+		fields["rule_name"] = "references an unknown rule"
+	}
+	if name := request.executeName(); name != "" && !handler.executorRegistered(name) {
+		fields["execute_name"] = "references an unregistered executor"
+	}
+	return fields
+}
+
+func (handler *AdminHandler) executorRegistered(name string) bool {
+	for _, executor := range handler.service.Executors() {
+		if executor.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newItem(itemType gorbac.ItemType, request itemRequest, createTime time.Time, updateTime time.Time) gorbac.Item {
+	if itemType == gorbac.RoleType {
+		return gorbac.NewRole(request.Name, request.Description, request.RuleName, request.executeName(), createTime, updateTime)
+	}
+	return gorbac.NewPermission(request.Name, request.Description, request.RuleName, request.executeName(), createTime, updateTime)
+}
+
+func (handler *AdminHandler) itemsOf(itemType gorbac.ItemType) []gorbac.Item {
+	items := make([]gorbac.Item, 0)
+	if itemType == gorbac.RoleType {
+		for _, role := range handler.manager.GetRoles() {
+			items = append(items, role)
+		}
+	} else {
+		for _, permission := range handler.manager.GetPermissions() {
+			items = append(items, permission)
+		}
+	}
+	sortItems(items)
+	return items
+}
+
+func (handler *AdminHandler) listItems(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		items := handler.itemsOf(itemType)
+		writePage(w, r, len(items), func(start int, end int) interface{} { return items[start:end] })
+	}
+}
+
+func (handler *AdminHandler) getItem(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		item, ok := handler.lookup(w, vars["name"], itemType)
+		if !ok || notModified(w, r, item) {
+			return
+		}
+		writeResource(w, nethttp.StatusOK, item)
+	}
+}
+
+func (handler *AdminHandler) createItem(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		var request itemRequest
+		if !decode(w, r, &request) {
+			return
+		}
+		if fields := handler.validateItem(request); len(fields) > 0 {
+			writeValidation(w, fields)
+			return
+		}
+		if handler.manager.GetItem(request.Name) != nil {
+			writeError(w, nethttp.StatusConflict, "item '%s' already exists", request.Name)
+			return
+		}
+		now := time.Now()
+		if !handler.changes(r).Add(newItem(itemType, request, now, now)) {
+			writeError(w, nethttp.StatusInternalServerError, "add item '%s' failed", request.Name)
+			return
+		}
+		w.Header().Set("Location", r.URL.Path+"/"+url.PathEscape(request.Name))
+		writeResource(w, nethttp.StatusCreated, handler.manager.GetItem(request.Name))
+	}
+}
+
+// updateItem 整体替换 item，请求体中的 name 与路径不同时重命名
+func (handler *AdminHandler) updateItem(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		name := vars["name"]
+		current, ok := handler.lookup(w, name, itemType)
+		if !ok || !handler.precondition(w, r, current) {
+			return
+		}
+		var request itemRequest
+		if !decode(w, r, &request) {
+			return
+		}
+		if request.Name == "" {
+			request.Name = name
+		}
+		if fields := handler.validateItem(request); len(fields) > 0 {
+			writeValidation(w, fields)
+			return
+		}
+		// 省略 execute_name 时保留原有执行器，不因整体替换而去掉执行器的限制
+		if request.ExecuteName == nil {
+			executeName := current.GetExecuteName()
+			request.ExecuteName = &executeName
+		}
+		if request.Name != name && handler.manager.GetItem(request.Name) != nil {
+			writeError(w, nethttp.StatusConflict, "item '%s' already exists", request.Name)
+			return
+		}
+		if !handler.changes(r).Update(name, newItem(itemType, request, current.GetCreateTime(), time.Now())) {
+			writeError(w, nethttp.StatusInternalServerError, "update item '%s' failed", name)
+			return
+		}
+		writeResource(w, nethttp.StatusOK, handler.manager.GetItem(request.Name))
+	}
+}
+
+func (handler *AdminHandler) deleteItem(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		item, ok := handler.lookup(w, vars["name"], itemType)
+		if !ok || !handler.precondition(w, r, item) {
+			return
+		}
+		if !handler.changes(r).Remove(item) {
+			writeError(w, nethttp.StatusInternalServerError, "remove item '%s' failed", item.GetName())
+			return
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
+
+func (handler *AdminHandler) removeAllItems(itemType gorbac.ItemType) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		if !confirmed(w, r) {
+			return
+		}
+		if itemType == gorbac.RoleType {
+			handler.changes(r).RemoveAllRoles()
+		} else {
+			handler.changes(r).RemoveAllPermissions()
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
+
+func (handler *AdminHandler) roleUsers(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.RoleType); !ok {
+		return
+	}
+	userIds := handler.manager.GetUserIdsByRole(vars["name"])
+	writePage(w, r, len(userIds), func(start int, end int) interface{} { return userIds[start:end] })
+}
+
+// permissionUsers 反查拥有权限的用户，?evaluate=true 时执行规则
+func (handler *AdminHandler) permissionUsers(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.PermissionType); !ok {
+		return
+	}
+	offset, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	ctx := gorbac.WithParams(r.Context(), ruleParams(r))
+	options := gorbac.WhoCanOptions{EvaluateRules: boolQuery(r, "evaluate"), Offset: offset, Limit: limit}
+	writeJSON(w, nethttp.StatusOK, handler.manager.WhoCan(ctx, vars["name"], options))
+}
+
+func (handler *AdminHandler) listChildren(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.NoneType); !ok {
+		return
+	}
+	children := append(make([]gorbac.Item, 0), handler.manager.GetChildren(vars["name"])...)
+	sortItems(children)
+	writePage(w, r, len(children), func(start int, end int) interface{} { return children[start:end] })
+}
+
+func (handler *AdminHandler) addChild(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	parent, ok := handler.lookup(w, vars["name"], gorbac.NoneType)
+	if !ok {
+		return
+	}
+	var request childRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	child := handler.manager.GetItem(request.Child)
+	if child == nil {
+		writeValidation(w, map[string]string{"child": "references an unknown item"})
+		return
+	}
+	if handler.manager.HasChild(parent, child) {
+		writeError(w, nethttp.StatusConflict, "'%s' is already a child of '%s'", child.GetName(), parent.GetName())
+		return
+	}
+	if err := handler.changes(r).AddChild(parent, child); err != nil {
+		writeValidation(w, map[string]string{"child": err.Error()})
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+url.PathEscape(child.GetName()))
+	writeJSON(w, nethttp.StatusCreated, gorbac.NewItemChild(parent.GetName(), child.GetName()))
+}
+
+func (handler *AdminHandler) removeChild(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	parent, ok := handler.lookup(w, vars["name"], gorbac.NoneType)
+	if !ok {
+		return
+	}
+	child := handler.manager.GetItem(vars["child"])
+	if child == nil || !handler.manager.HasChild(parent, child) {
+		writeError(w, nethttp.StatusNotFound, "'%s' is not a child of '%s'", vars["child"], parent.GetName())
+		return
+	}
+	if !handler.changes(r).RemoveChild(parent, child) {
+		writeError(w, nethttp.StatusInternalServerError, "remove child '%s' failed", child.GetName())
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) removeChildren(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	parent, ok := handler.lookup(w, vars["name"], gorbac.NoneType)
+	if !ok {
+		return
+	}
+	if !handler.changes(r).RemoveChildren(parent) {
+		writeError(w, nethttp.StatusInternalServerError, "remove children of '%s' failed", parent.GetName())
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) listParents(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.NoneType); !ok {
+		return
+	}
+	parents := append(make([]gorbac.Item, 0), handler.manager.GetParents(vars["name"])...)
+	sortItems(parents)
+	writeJSON(w, nethttp.StatusOK, parents)
+}
+
+func (handler *AdminHandler) listAncestors(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.NoneType); !ok {
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, append(make([]gorbac.HierarchyNode, 0), handler.manager.GetAncestors(vars["name"])...))
+}
+
+// listDescendants ?type=role|permission 限定类型，?depth=N 限定深度
+func (handler *AdminHandler) listDescendants(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.NoneType); !ok {
+		return
+	}
+	fields := make(map[string]string)
+	itemType := gorbac.NoneType
+	switch r.URL.Query().Get("type") {
+	case "":
+	case "role":
+		itemType = gorbac.RoleType
+	case "permission":
+		itemType = gorbac.PermissionType
+	default:
+		fields["type"] = "must be role or permission"
+	}
+	depth := 0
+	if value := r.URL.Query().Get("depth"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			fields["depth"] = "must be a non-negative integer"
+		}
+		depth = n
+	}
+	if len(fields) > 0 {
+		writeValidation(w, fields)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, append(make([]gorbac.HierarchyNode, 0), handler.manager.GetDescendants(vars["name"], itemType, depth)...))
+}
+
+func (handler *AdminHandler) listPaths(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if _, ok := handler.lookup(w, vars["name"], gorbac.NoneType); !ok {
+		return
+	}
+	if _, ok := handler.lookup(w, vars["descendant"], gorbac.NoneType); !ok {
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, append(make([][]string, 0), handler.manager.PathsBetween(vars["name"], vars["descendant"])...))
+}
+
+// ---------------------- Rules ---------------------------
+
+type ruleRequest struct {
+	Name        string `json:"name"`
+	ExecuteName string `json:"execute_name"`
+	Data        string `json:"data"`
+}
+
+func (handler *AdminHandler) registerRules() {
+	handler.handle(nethttp.MethodGet, "/v1/rules", handler.listRules)
+	handler.handle(nethttp.MethodPost, "/v1/rules", handler.createRule)
+	handler.handle(nethttp.MethodDelete, "/v1/rules", handler.removeAllRules)
+	handler.handle(nethttp.MethodGet, "/v1/rules/{name}", handler.getRule)
+	handler.handle(nethttp.MethodPut, "/v1/rules/{name}", handler.updateRule)
+	handler.handle(nethttp.MethodDelete, "/v1/rules/{name}", handler.deleteRule)
+	handler.handle(nethttp.MethodGet, "/v1/executors", handler.listExecutors)
+}
+
+func (handler *AdminHandler) lookupRule(w nethttp.ResponseWriter, name string) (*gorbac.Rule, bool) {
+	rule := handler.manager.GetRule(name)
+	if rule == nil {
+		writeError(w, nethttp.StatusNotFound, "rule '%s' not found", name)
+		return nil, false
+	}
+	return rule, true
+}
+
+func (handler *AdminHandler) validateRule(request ruleRequest, rule gorbac.Rule) map[string]string {
+	fields := make(map[string]string)
+	if request.Name == "" {
+		fields["name"] = "is required"
+	}
+	if request.ExecuteName == "" {
+		fields["execute_name"] = "is required"
+	}
+	if len(fields) == 0 {
+		if err := handler.manager.ValidateRule(rule); err != nil {
+			fields["data"] = err.Error()
+		}
+	}
+	return fields
+}
+
+func (handler *AdminHandler) listRules(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	rules := append(make([]*gorbac.Rule, 0), handler.manager.GetRules()...)
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	writePage(w, r, len(rules), func(start int, end int) interface{} { return rules[start:end] })
+}
+
+func (handler *AdminHandler) getRule(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	rule, ok := handler.lookupRule(w, vars["name"])
+	if !ok || notModified(w, r, rule) {
+		return
+	}
+	writeResource(w, nethttp.StatusOK, rule)
+}
+
+func (handler *AdminHandler) createRule(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	var request ruleRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	now := time.Now()
+	rule := gorbac.Rule{Name: request.Name, ExecuteName: request.ExecuteName, Data: request.Data, CreateTime: now, UpdateTime: now}
+	if fields := handler.validateRule(request, rule); len(fields) > 0 {
+		writeValidation(w, fields)
+		return
+	}
+	if handler.manager.GetRule(request.Name) != nil {
+		writeError(w, nethttp.StatusConflict, "rule '%s' already exists", request.Name)
+		return
+	}
+	if !handler.changes(r).AddRule(rule) {
+		writeError(w, nethttp.StatusInternalServerError, "add rule '%s' failed", request.Name)
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+url.PathEscape(request.Name))
+	writeResource(w, nethttp.StatusCreated, handler.manager.GetRule(request.Name))
+}
+
+// updateRule 整体替换规则，请求体中的 name 与路径不同时重命名
+func (handler *AdminHandler) updateRule(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	name := vars["name"]
+	current, ok := handler.lookupRule(w, name)
+	if !ok || !handler.precondition(w, r, current) {
+		return
+	}
+	var request ruleRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	if request.Name == "" {
+		request.Name = name
+	}
+	rule := gorbac.Rule{Name: request.Name, ExecuteName: request.ExecuteName, Data: request.Data, CreateTime: current.CreateTime, UpdateTime: time.Now()}
+	if fields := handler.validateRule(request, rule); len(fields) > 0 {
+		writeValidation(w, fields)
+		return
+	}
+	if request.Name != name && handler.manager.GetRule(request.Name) != nil {
+		writeError(w, nethttp.StatusConflict, "rule '%s' already exists", request.Name)
+		return
+	}
+	if !handler.changes(r).UpdateRule(name, rule) {
+		writeError(w, nethttp.StatusInternalServerError, "update rule '%s' failed", name)
+		return
+	}
+	writeResource(w, nethttp.StatusOK, handler.manager.GetRule(request.Name))
+}
+
+func (handler *AdminHandler) deleteRule(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	rule, ok := handler.lookupRule(w, vars["name"])
+	if !ok || !handler.precondition(w, r, rule) {
+		return
+	}
+	if !handler.changes(r).RemoveRule(*rule) {
+		writeError(w, nethttp.StatusInternalServerError, "remove rule '%s' failed", rule.Name)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) removeAllRules(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if !confirmed(w, r) {
+		return
+	}
+	handler.changes(r).RemoveAllRules()
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) listExecutors(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	names := make([]string, 0)
+	for _, executor := range handler.service.Executors() {
+		names = append(names, executor.Name())
+	}
+	sort.Strings(names)
+	writeJSON(w, nethttp.StatusOK, names)
+}
+
+// ---------------------- Users ---------------------------
+
+func (handler *AdminHandler) registerUsers() {
+	handler.handle(nethttp.MethodGet, "/v1/users/{id}/assignments", handler.listAssignments)
+	handler.handle(nethttp.MethodDelete, "/v1/users/{id}/assignments", handler.revokeAll)
+	handler.handle(nethttp.MethodPut, "/v1/users/{id}/assignments/{item}", handler.assign)
+	handler.handle(nethttp.MethodDelete, "/v1/users/{id}/assignments/{item}", handler.revoke)
+	handler.handle(nethttp.MethodGet, "/v1/users/{id}/roles", handler.userRoles)
+	handler.handle(nethttp.MethodGet, "/v1/users/{id}/permissions", handler.userPermissions)
+	handler.handle(nethttp.MethodGet, "/v1/users/{id}/check", handler.check)
+	handler.handle(nethttp.MethodGet, "/v1/users/{id}/explain", handler.explain)
+	handler.handle(nethttp.MethodDelete, "/v1/assignments", handler.removeAllAssignments)
+}
+
+func (handler *AdminHandler) listAssignments(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	assignments := make([]*gorbac.Assignment, 0)
+	for _, assignment := range handler.manager.GetAssignments(handler.userId(vars["id"])) {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].ItemName < assignments[j].ItemName
+	})
+	writePage(w, r, len(assignments), func(start int, end int) interface{} { return assignments[start:end] })
+}
+
+// assign 幂等分配，已存在时返回 200，否则返回 201
+func (handler *AdminHandler) assign(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	item, ok := handler.lookup(w, vars["item"], gorbac.NoneType)
+	if !ok {
+		return
+	}
+	userId := handler.userId(vars["id"])
+	if assignment := handler.manager.GetAssignment(item.GetName(), userId); assignment != nil {
+		writeJSON(w, nethttp.StatusOK, assignment)
+		return
+	}
+	assignment := handler.changes(r).Assign(item, userId)
+	if assignment == nil {
+		writeError(w, nethttp.StatusInternalServerError, "assign '%s' to user %v failed", item.GetName(), userId)
+		return
+	}
+	writeJSON(w, nethttp.StatusCreated, assignment)
+}
+
+func (handler *AdminHandler) revoke(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	userId := handler.userId(vars["id"])
+	item := handler.manager.GetItem(vars["item"])
+	if item == nil || handler.manager.GetAssignment(item.GetName(), userId) == nil {
+		writeError(w, nethttp.StatusNotFound, "user %v is not assigned to '%s'", userId, vars["item"])
+		return
+	}
+	if !handler.changes(r).Revoke(item, userId) {
+		writeError(w, nethttp.StatusInternalServerError, "revoke '%s' from user %v failed", item.GetName(), userId)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) revokeAll(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	userId := handler.userId(vars["id"])
+	if !handler.changes(r).RevokeAll(userId) {
+		writeError(w, nethttp.StatusInternalServerError, "revoke all assignments of user %v failed", userId)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) removeAllAssignments(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if !confirmed(w, r) {
+		return
+	}
+	handler.changes(r).RemoveAllAssignments()
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func (handler *AdminHandler) userRoles(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	roles := append(make([]*gorbac.Role, 0), handler.manager.GetRolesByUser(handler.userId(vars["id"]))...)
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	writePage(w, r, len(roles), func(start int, end int) interface{} { return roles[start:end] })
+}
+
+// userPermissions 有效权限：以 "param.<name>" 查询参数执行规则后过滤，?evaluate=false 时不执行规则
+func (handler *AdminHandler) userPermissions(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	userId := handler.userId(vars["id"])
+	evaluate := true
+	if value := r.URL.Query().Get("evaluate"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeValidation(w, map[string]string{"evaluate": "must be a boolean"})
+			return
+		}
+		evaluate = parsed
+	}
+	ctx := gorbac.WithParams(r.Context(), ruleParams(r))
+	permissions := make([]*gorbac.Permission, 0)
+	for _, permission := range handler.manager.GetPermissionsByUser(userId) {
+		if !evaluate || handler.manager.CheckAccess(ctx, userId, permission.Name) {
+			permissions = append(permissions, permission)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+	writePage(w, r, len(permissions), func(start int, end int) interface{} { return permissions[start:end] })
+}
+
+func requirePermission(w nethttp.ResponseWriter, r *nethttp.Request) (string, bool) {
+	permission := r.URL.Query().Get("permission")
+	if permission == "" {
+		writeValidation(w, map[string]string{"permission": "is required"})
+		return "", false
+	}
+	return permission, true
+}
+
+func (handler *AdminHandler) check(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	permission, ok := requirePermission(w, r)
+	if !ok {
+		return
+	}
+	userId := handler.userId(vars["id"])
+	ctx := gorbac.WithParams(r.Context(), ruleParams(r))
+	writeJSON(w, nethttp.StatusOK, map[string]interface{}{
+		"user_id":    userId,
+		"permission": permission,
+		"allowed":    handler.manager.CheckAccess(ctx, userId, permission),
+	})
+}
+
+func (handler *AdminHandler) explain(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	permission, ok := requirePermission(w, r)
+	if !ok {
+		return
+	}
+	ctx := gorbac.WithParams(r.Context(), ruleParams(r))
+	writeJSON(w, nethttp.StatusOK, handler.manager.Explain(ctx, handler.userId(vars["id"]), permission))
+}
+
+// ---------------------- Policy ---------------------------
+
+type defaultRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+func (handler *AdminHandler) registerPolicy() {
+	handler.handle(nethttp.MethodGet, "/v1/default-roles", handler.getDefaultRoles)
+	handler.handle(nethttp.MethodPut, "/v1/default-roles", handler.setDefaultRoles)
+	handler.handle(nethttp.MethodGet, "/v1/policy", handler.exportPolicy)
+	handler.handle(nethttp.MethodPost, "/v1/policy/plan", handler.syncPolicy(true))
+	handler.handle(nethttp.MethodPost, "/v1/policy/apply", handler.syncPolicy(false))
+	handler.handle(nethttp.MethodGet, "/v1/lint", handler.lint(false))
+	handler.handle(nethttp.MethodPost, "/v1/lint/fix", handler.lint(true))
+	handler.handle(nethttp.MethodGet, "/v1/snapshot", handler.exportSnapshot)
+	handler.handle(nethttp.MethodPost, "/v1/snapshot", handler.importSnapshot)
+	handler.handle(nethttp.MethodGet, "/v1/graph", handler.graph)
+	handler.handle(nethttp.MethodGet, "/v1/audit", handler.listAudit)
+}
+
+func (handler *AdminHandler) defaultRoleNames() []string {
+	names := make([]string, 0)
+	for _, role := range handler.manager.GetDefaultRoles() {
+		names = append(names, role.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (handler *AdminHandler) getDefaultRoles(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	names := handler.defaultRoleNames()
+	if notModified(w, r, names) {
+		return
+	}
+	writeResource(w, nethttp.StatusOK, names)
+}
+
+func (handler *AdminHandler) setDefaultRoles(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	if !handler.precondition(w, r, handler.defaultRoleNames()) {
+		return
+	}
+	var request defaultRolesRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	roles := make([]*gorbac.Role, 0, len(request.Roles))
+	for _, name := range request.Roles {
+		role := handler.manager.GetRole(name)
+		if role == nil {
+			writeValidation(w, map[string]string{"roles": "references an unknown role '" + name + "'"})
+			return
+		}
+		roles = append(roles, role)
+	}
+	handler.changes(r).SetDefaultRoles(roles...)
+	writeResource(w, nethttp.StatusOK, handler.defaultRoleNames())
+}
+
+// exportPolicy ?assignments=true 时包含用户分配，ETag 可用于 /v1/policy/apply 的 If-Match
+func (handler *AdminHandler) exportPolicy(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	policy, err := handler.manager.ExportPolicy(boolQuery(r, "assignments"))
+	if err != nil {
+		writeError(w, nethttp.StatusInternalServerError, "export policy: %v", err)
+		return
+	}
+	if notModified(w, r, policy) {
+		return
+	}
+	writeResource(w, nethttp.StatusOK, policy)
+}
+
+// syncPolicy 请求体为 JSON 策略文件，?keep_unmanaged=true 时保留未声明的数据
+func (handler *AdminHandler) syncPolicy(dryRun bool) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		if !dryRun {
+			current, err := handler.manager.ExportPolicy(boolQuery(r, "assignments"))
+			if err != nil {
+				writeError(w, nethttp.StatusInternalServerError, "export policy: %v", err)
+				return
+			}
+			if !handler.precondition(w, r, current) {
+				return
+			}
+		}
+		data, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+		policy, err := gorbac.ParsePolicy(data, "json")
+		if err != nil {
+			writeError(w, nethttp.StatusBadRequest, "%v", err)
+			return
+		}
+		options := gorbac.SyncOptions{DryRun: dryRun, KeepUnmanaged: boolQuery(r, "keep_unmanaged")}
+		var plan *gorbac.PolicyPlan
+		if dryRun {
+			plan, err = handler.manager.PlanPolicy(policy, options)
+		} else {
+			plan, err = handler.changes(r).ApplyPolicy(policy, options)
+		}
+		if err != nil {
+			writeValidation(w, map[string]string{"policy": err.Error()})
+			return
+		}
+		writeJSON(w, nethttp.StatusOK, plan)
+	}
+}
+
+func (handler *AdminHandler) lint(fix bool) adminFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+		findings, err := handler.changes(r).Lint(gorbac.LintOptions{Fix: fix})
+		if err != nil {
+			writeError(w, nethttp.StatusInternalServerError, "lint: %v", err)
+			return
+		}
+		writeJSON(w, nethttp.StatusOK, append(make([]gorbac.LintFinding, 0), findings...))
+	}
+}
+
+// exportSnapshot 直接写出快照；已开始写出后出错时响应缺少 end 记录，导入时会被拒绝
+func (handler *AdminHandler) exportSnapshot(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	out := &startedWriter{w: w}
+	if _, err := handler.manager.ExportSnapshot(out); err != nil && !out.started {
+		w.Header().Del("Content-Type")
+		writeError(w, nethttp.StatusInternalServerError, "export snapshot: %v", err)
+	}
+}
+
+// startedWriter 记录是否已开始写出响应
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.w.Write(p)
+}
+
+// importSnapshot ?mode=replace 时清空后导入，默认合并
+func (handler *AdminHandler) importSnapshot(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	options := gorbac.ImportOptions{Mode: gorbac.ImportMerge}
+	switch r.URL.Query().Get("mode") {
+	case "", "merge":
+	case "replace":
+		options.Mode = gorbac.ImportReplace
+	default:
+		writeValidation(w, map[string]string{"mode": "must be merge or replace"})
+		return
+	}
+	data, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	stats, err := handler.changes(r).ImportSnapshot(bytes.NewReader(data), options)
+	if err != nil {
+		writeValidation(w, map[string]string{"snapshot": err.Error()})
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, stats)
+}
+
+// graph ?format=dot|mermaid，?root= 与 ?user= 限定范围
+func (handler *AdminHandler) graph(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	query := r.URL.Query()
+	options := gorbac.GraphOptions{Root: query.Get("root")}
+	if user := query.Get("user"); user != "" {
+		options.UserId = handler.userId(user)
+	}
+	var buf bytes.Buffer
+	var err error
+	switch query.Get("format") {
+	case "", "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		err = handler.manager.ExportDOT(&buf, options)
+	case "mermaid":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err = handler.manager.ExportMermaid(&buf, options)
+	default:
+		writeValidation(w, map[string]string{"format": "must be dot or mermaid"})
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Type")
+		writeValidation(w, map[string]string{"root": err.Error()})
+		return
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// listAudit 按 ?actor=、?item=、?user=、?operation=、?since=、?until=（RFC 3339）查询审计记录，按时间倒序，?limit= 默认 50
+func (handler *AdminHandler) listAudit(w nethttp.ResponseWriter, r *nethttp.Request, vars map[string]string) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	filter := gorbac.AuditFilter{
+		Actor:     query.Get("actor"),
+		Item:      query.Get("item"),
+		Operation: gorbac.AuditOperation(query.Get("operation")),
+		Limit:     defaultPageLimit,
+	}
+	if user := query.Get("user"); user != "" {
+		filter.UserId = handler.userId(user)
+	}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				fields[name] = "must be an RFC 3339 timestamp"
+			}
+			*t = parsed
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > maxPageLimit {
+			fields["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxPageLimit)
+		} else {
+			filter.Limit = n
+		}
+	}
+	if len(fields) > 0 {
+		writeValidation(w, fields)
+		return
+	}
+	entries, err := handler.manager.QueryAudit(filter)
+	if err != nil {
+		writeError(w, nethttp.StatusInternalServerError, "query audit: %v", err)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, entries)
+}
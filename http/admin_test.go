@@ -0,0 +1,310 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+type namedExecutor string
+
+func (executor namedExecutor) Name() string {
+	return string(executor)
+}
+
+func (executor namedExecutor) Execute(ctx context.Context, userId interface{}, item gorbac.Item) bool {
+	return true
+}
+
+// slowRepository 更新前等待，放大 If-Match 校验与写入之间的窗口
+type slowRepository struct {
+	*gorbac.MemoryRepository
+}
+
+func (repo slowRepository) UpdateItem(itemName string, item gorbac.Item) error {
+	time.Sleep(20 * time.Millisecond)
+	return repo.MemoryRepository.UpdateItem(itemName, item)
+}
+
+// newTestAdmin 用户 1 拥有 rbac:read 与 rbac:write，用户 2 只有 rbac:read；注册执行器 gate 与 audit，
+// 已有权限 reports:view 绑定执行器 gate
+func newTestAdmin(t *testing.T) (*AdminHandler, *gorbac.RbacService) {
+	return newTestAdminWith(t, gorbac.NewMemoryRepository())
+}
+
+func newTestAdminWith(t *testing.T, repo gorbac.AuthRepository) (*AdminHandler, *gorbac.RbacService) {
+	t.Helper()
+	service := gorbac.NewRbacService(repo, true)
+	service.RegisterExecutor(namedExecutor("gate"), namedExecutor("audit"))
+	service.AddRole("rbac-admin", "", "")
+	service.AddPermission("rbac:read", "", "")
+	service.AddPermission("rbac:write", "", "")
+	if err := service.AssignChildren("rbac-admin", "rbac:read", "rbac:write"); err != nil {
+		t.Fatal(err)
+	}
+	service.Assign(1, "rbac-admin")
+	service.Assign(2, "rbac:read")
+
+	manager := service.GetAuthManager()
+	reports := manager.CreatePermission("reports:view")
+	reports.ExecuteName = "gate"
+	manager.Add(reports)
+
+	middleware, err := NewMiddleware(Options{
+		Manager: manager,
+		Resolver: func(r *nethttp.Request) (interface{}, bool) {
+			id, err := strconv.Atoi(r.Header.Get("X-User-Id"))
+			return id, err == nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewAdminHandler(service, AdminOptions{Middleware: middleware})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, service
+}
+
+func adminRequest(handler nethttp.Handler, method string, target string, userId string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if userId != "" {
+		r.Header.Set("X-User-Id", userId)
+	}
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminHandlerStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		userId string
+		body   string
+		status int
+	}{
+		{"list", "GET", "/v1/permissions", "2", "", nethttp.StatusOK},
+		{"unauthenticated", "GET", "/v1/permissions", "", "", nethttp.StatusUnauthorized},
+		{"read only user writes", "POST", "/v1/roles", "2", `{"name":"editor"}`, nethttp.StatusForbidden},
+		{"create", "POST", "/v1/roles", "1", `{"name":"editor"}`, nethttp.StatusCreated},
+		{"create with executor", "POST", "/v1/roles", "1", `{"name":"editor","execute_name":"audit"}`, nethttp.StatusCreated},
+		{"create with unknown executor", "POST", "/v1/roles", "1", `{"name":"editor","execute_name":"ghost"}`, nethttp.StatusUnprocessableEntity},
+		{"create with unknown rule", "POST", "/v1/roles", "1", `{"name":"editor","rule_name":"ghost"}`, nethttp.StatusUnprocessableEntity},
+		{"create conflict", "POST", "/v1/permissions", "1", `{"name":"rbac:read"}`, nethttp.StatusConflict},
+		{"malformed body", "POST", "/v1/roles", "1", `{"name":`, nethttp.StatusBadRequest},
+		{"wrong type", "GET", "/v1/roles/rbac:read", "1", "", nethttp.StatusNotFound},
+		{"unknown route", "GET", "/v2/roles", "1", "", nethttp.StatusNotFound},
+		{"method not allowed", "PATCH", "/v1/roles", "1", "", nethttp.StatusMethodNotAllowed},
+		{"remove all without confirm", "DELETE", "/v1/roles", "1", "", nethttp.StatusUnprocessableEntity},
+		{"body too large", "POST", "/v1/roles", "1", `{"name":"` + strings.Repeat("a", maxBodySize) + `"}`, nethttp.StatusRequestEntityTooLarge},
+		{"snapshot too large", "POST", "/v1/snapshot", "1", strings.Repeat(" ", maxBodySize+1), nethttp.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestAdmin(t)
+			if w := adminRequest(handler, tt.method, tt.target, tt.userId, tt.body); w.Code != tt.status {
+				t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.target, w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestAdminUpdateItemExecuteName(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		status      int
+		executeName string
+	}{
+		{"omitted keeps executor", `{"description":"monthly reports"}`, nethttp.StatusOK, "gate"},
+		{"replaced", `{"execute_name":"audit"}`, nethttp.StatusOK, "audit"},
+		{"cleared", `{"execute_name":""}`, nethttp.StatusOK, ""},
+		{"unregistered", `{"execute_name":"ghost"}`, nethttp.StatusUnprocessableEntity, "gate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, service := newTestAdmin(t)
+			w := adminRequest(handler, "PUT", "/v1/permissions/reports:view", "1", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			item := service.GetAuthManager().GetItem("reports:view")
+			if got := item.GetExecuteName(); got != tt.executeName {
+				t.Errorf("execute_name = %q, want %q", got, tt.executeName)
+			}
+			if w.Code == nethttp.StatusOK {
+				var body struct {
+					ExecuteName string `json:"execute_name"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.ExecuteName != tt.executeName {
+					t.Errorf("response execute_name = %q (%v), want %q", body.ExecuteName, err, tt.executeName)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminIfMatch(t *testing.T) {
+	handler, _ := newTestAdmin(t)
+	w := adminRequest(handler, "GET", "/v1/roles/rbac-admin", "1", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != nethttp.StatusOK || etag == "" {
+		t.Fatalf("GET status = %d, ETag = %q", w.Code, etag)
+	}
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		{"stale", `"stale"`, nethttp.StatusPreconditionFailed},
+		{"current", etag, nethttp.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/v1/roles/rbac-admin", strings.NewReader(`{"description":"admins"}`))
+		r.Header.Set("X-User-Id", "1")
+		r.Header.Set("If-Match", tt.ifMatch)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+}
+
+func TestAdminIfMatchConcurrent(t *testing.T) {
+	handler, service := newTestAdminWith(t, slowRepository{gorbac.NewMemoryRepository()})
+	etag := adminRequest(handler, "GET", "/v1/roles/rbac-admin", "1", "").Header().Get("ETag")
+
+	// 携带同一 If-Match 的并发写入只能有一个成功
+	const writers = 8
+	statuses := make(chan int, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := httptest.NewRequest("PUT", "/v1/roles/rbac-admin", strings.NewReader(`{"description":"writer `+strconv.Itoa(i)+`"}`))
+			r.Header.Set("X-User-Id", "1")
+			r.Header.Set("If-Match", etag)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			statuses <- w.Code
+		}(i)
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[nethttp.StatusOK] != 1 || counts[nethttp.StatusPreconditionFailed] != writers-1 {
+		t.Errorf("statuses = %v, want one 200 and %d 412", counts, writers-1)
+	}
+	if description := service.GetAuthManager().GetRole("rbac-admin").Description; !strings.HasPrefix(description, "writer ") {
+		t.Errorf("description = %q, want one writer's update", description)
+	}
+}
+
+func TestAdminRequireIfMatch(t *testing.T) {
+	base, service := newTestAdmin(t)
+	handler, err := NewAdminHandler(service, AdminOptions{Middleware: base.options.Middleware, RequireIfMatch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := adminRequest(handler, "GET", "/v1/policy", "1", "")
+	if policy.Code != nethttp.StatusOK {
+		t.Fatalf("GET /v1/policy status = %d: %s", policy.Code, policy.Body)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		ifMatch string
+		status  int
+	}{
+		{"update without If-Match", "PUT", "/v1/permissions/reports:view", `{"description":"reports"}`, "", nethttp.StatusPreconditionRequired},
+		{"delete without If-Match", "DELETE", "/v1/permissions/reports:view", "", "", nethttp.StatusPreconditionRequired},
+		{"default roles without If-Match", "PUT", "/v1/default-roles", `{"roles":[]}`, "", nethttp.StatusPreconditionRequired},
+		{"policy apply without If-Match", "POST", "/v1/policy/apply", policy.Body.String(), "", nethttp.StatusPreconditionRequired},
+		{"policy apply with stale If-Match", "POST", "/v1/policy/apply", policy.Body.String(), `"stale"`, nethttp.StatusPreconditionFailed},
+		{"policy plan without If-Match", "POST", "/v1/policy/plan", policy.Body.String(), "", nethttp.StatusOK},
+		{"policy apply with If-Match", "POST", "/v1/policy/apply", policy.Body.String(), policy.Header().Get("ETag"), nethttp.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		r.Header.Set("X-User-Id", "1")
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+	if service.GetAuthManager().GetPermission("reports:view") == nil {
+		t.Error("reports:view removed without If-Match")
+	}
+}
+
+func TestAdminSnapshot(t *testing.T) {
+	handler, _ := newTestAdmin(t)
+	w := adminRequest(handler, "GET", "/v1/snapshot", "1", "")
+	if w.Code != nethttp.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("export status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	snapshot := w.Body.String()
+	lines := 0
+	last := ""
+	scanner := bufio.NewScanner(strings.NewReader(snapshot))
+	for scanner.Scan() {
+		lines++
+		last = scanner.Text()
+	}
+	if lines < 2 || !strings.Contains(last, `"kind":"end"`) {
+		t.Fatalf("snapshot has %d lines, last %q", lines, last)
+	}
+
+	target, service := newTestAdmin(t)
+	adminRequest(target, "POST", "/v1/roles", "1", `{"name":"extra"}`)
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+		extra  bool
+	}{
+		{"invalid mode", "?mode=bogus", snapshot, nethttp.StatusUnprocessableEntity, true},
+		{"truncated", "", strings.Join(strings.SplitAfter(snapshot, "\n")[:lines-1], ""), nethttp.StatusUnprocessableEntity, true},
+		{"merge", "", snapshot, nethttp.StatusOK, true},
+		{"replace", "?mode=replace", snapshot, nethttp.StatusOK, false},
+	}
+	for _, tt := range tests {
+		w := adminRequest(target, "POST", "/v1/snapshot"+tt.query, "1", tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+		if got := service.GetAuthManager().GetRole("extra") != nil; got != tt.extra {
+			t.Errorf("%s: role extra present = %v, want %v", tt.name, got, tt.extra)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package http
+
+import (
+	nethttp "net/http"
+
+	"github.com/kordar/gorbac"
+)
+
+// DecisionCacheMiddleware 为每个请求挂上请求级判定缓存（见 gorbac.WithDecisionCache）
+func DecisionCacheMiddleware(next nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		next.ServeHTTP(w, r.WithContext(gorbac.WithDecisionCache(r.Context())))
+	})
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kordar/gorbac"
+)
+
+func TestDecisionCacheMiddleware(t *testing.T) {
+	var caches []*gorbac.DecisionCache
+	handler := DecisionCacheMiddleware(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		caches = append(caches, gorbac.DecisionCacheFromContext(r.Context()))
+	}))
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(nethttp.MethodGet, "/", nil))
+	}
+	if len(caches) != 2 || caches[0] == nil || caches[1] == nil {
+		t.Fatalf("caches = %v, want one per request", caches)
+	}
+	if caches[0] == caches[1] {
+		t.Error("requests share a decision cache")
+	}
+}
@@ -0,0 +1,196 @@
+// Package http 提供基于 gorbac 的 net/http 中间件：解析用户、校验权限，
+// 并将本次请求的主体（用户 ID 与有效权限集合）附加到请求 context。
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	nethttp "net/http"
+	"sort"
+	"sync"
+
+	"github.com/kordar/gorbac"
+)
+
+// UserResolver 从请求中解析用户 ID，ok 为 false 时返回 401
+type UserResolver func(r *nethttp.Request) (userId interface{}, ok bool)
+
+// ParamsResolver 从请求中提取规则参数，执行器通过 gorbac.ParamsFromContext 读取
+type ParamsResolver func(r *nethttp.Request) map[string]interface{}
+
+// DenyHandler 写出拒绝响应，status 为 401 或 403，denied 为未通过的权限（401 时为空）
+type DenyHandler func(w nethttp.ResponseWriter, r *nethttp.Request, status int, denied []string)
+
+type Options struct {
+	// Manager 权限管理器，必填
+	Manager gorbac.AuthManager
+	// Resolver 用户解析器，必填
+	Resolver UserResolver
+	// Params 规则参数解析器，可选
+	Params ParamsResolver
+	// Deny 拒绝响应，默认 DefaultDeny
+	Deny DenyHandler
+}
+
+// Middleware 权限校验中间件
+type Middleware struct {
+	options Options
+}
+
+func NewMiddleware(options Options) (*Middleware, error) {
+	if options.Manager == nil {
+		return nil, errors.New("gorbac/http: Options.Manager is required")
+	}
+	if options.Resolver == nil {
+		return nil, errors.New("gorbac/http: Options.Resolver is required")
+	}
+	if options.Deny == nil {
+		options.Deny = DefaultDeny
+	}
+	return &Middleware{options: options}, nil
+}
+
+// Require 要求用户同时拥有全部权限
+func (middleware *Middleware) Require(permissions ...string) func(nethttp.Handler) nethttp.Handler {
+	return middleware.wrap(permissions, false)
+}
+
+// RequireAny 要求用户拥有任意一个权限
+func (middleware *Middleware) RequireAny(permissions ...string) func(nethttp.Handler) nethttp.Handler {
+	return middleware.wrap(permissions, true)
+}
+
+func (middleware *Middleware) wrap(permissions []string, requireAny bool) func(nethttp.Handler) nethttp.Handler {
+	return func(next nethttp.Handler) nethttp.Handler {
+		return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			middleware.serve(w, r, next, permissions, requireAny, nil)
+		})
+	}
+}
+
+// serve 解析用户与规则参数并校验权限，通过后以附加了 Subject 与规则参数的请求调用 next
+func (middleware *Middleware) serve(w nethttp.ResponseWriter, r *nethttp.Request, next nethttp.Handler, permissions []string, requireAny bool, vars map[string]string) {
+	userId, ok := middleware.options.Resolver(r)
+	if !ok {
+		middleware.options.Deny(w, r, nethttp.StatusUnauthorized, nil)
+		return
+	}
+
+	ctx := r.Context()
+	if params := middleware.params(r, vars); len(params) > 0 {
+		ctx = gorbac.WithParams(ctx, params)
+	}
+
+	granted := make([]string, 0, len(permissions))
+	denied := make([]string, 0)
+	for _, permission := range permissions {
+		if middleware.options.Manager.CheckAccess(ctx, userId, permission) {
+			granted = append(granted, permission)
+			if requireAny {
+				break
+			}
+		} else {
+			denied = append(denied, permission)
+		}
+	}
+	if len(permissions) > 0 && ((requireAny && len(granted) == 0) || (!requireAny && len(denied) > 0)) {
+		middleware.options.Deny(w, r, nethttp.StatusForbidden, denied)
+		return
+	}
+
+	subject := &Subject{UserId: userId, Checked: granted, manager: middleware.options.Manager, ctx: ctx}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, subjectKey{}, subject)))
+}
+
+// params 合并 context 中已有的规则参数、路由变量与 ParamsResolver 的结果，后者优先
+func (middleware *Middleware) params(r *nethttp.Request, vars map[string]string) map[string]interface{} {
+	params := make(map[string]interface{})
+	for key, value := range gorbac.ParamsFromContext(r.Context()) {
+		params[key] = value
+	}
+	for key, value := range vars {
+		params[key] = value
+	}
+	if middleware.options.Params != nil {
+		for key, value := range middleware.options.Params(r) {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+type subjectKey struct{}
+
+// Subject 通过校验的请求主体
+type Subject struct {
+	UserId interface{}
+	// Checked 本次请求校验通过的权限
+	Checked []string
+
+	manager     gorbac.AuthManager
+	ctx         context.Context
+	once        sync.Once
+	permissions map[string]bool
+}
+
+// FromContext 返回中间件附加的 Subject，未经过中间件时返回 nil
+func FromContext(ctx context.Context) *Subject {
+	subject, _ := ctx.Value(subjectKey{}).(*Subject)
+	return subject
+}
+
+// Permissions 用户的有效权限集合：由分配、继承与默认角色得到，并以请求的规则参数执行规则后过滤，首次调用时计算
+func (subject *Subject) Permissions() []string {
+	subject.load()
+	names := make([]string, 0, len(subject.permissions))
+	for name := range subject.permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has 权限是否在有效权限集合中
+func (subject *Subject) Has(permission string) bool {
+	subject.load()
+	return subject.permissions[permission]
+}
+
+func (subject *Subject) load() {
+	subject.once.Do(func() {
+		subject.permissions = make(map[string]bool)
+		for _, permission := range subject.manager.GetPermissionsByUser(subject.UserId) {
+			if subject.manager.CheckAccess(subject.ctx, subject.UserId, permission.Name) {
+				subject.permissions[permission.Name] = true
+			}
+		}
+	})
+}
+
+// DefaultDeny 以 JSON 写出 {"error": "unauthorized"} 或 {"error": "forbidden", "denied": [...]}
+func DefaultDeny(w nethttp.ResponseWriter, r *nethttp.Request, status int, denied []string) {
+	body := map[string]interface{}{"error": "unauthorized"}
+	if status == nethttp.StatusForbidden {
+		if denied == nil {
+			denied = []string{}
+		}
+		body = map[string]interface{}{"error": "forbidden", "denied": denied}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// StaticDeny 以固定的内容写出 401 与 403 响应
+func StaticDeny(contentType string, unauthorized []byte, forbidden []byte) DenyHandler {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request, status int, denied []string) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		if status == nethttp.StatusUnauthorized {
+			_, _ = w.Write(unauthorized)
+		} else {
+			_, _ = w.Write(forbidden)
+		}
+	}
+}
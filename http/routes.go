@@ -0,0 +1,116 @@
+package http
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"path"
+	"strings"
+)
+
+// Route 路由与权限的映射。Pattern 以 "/" 分隔，"{name}" 匹配单段并作为规则参数 name 传给执行器，
+// 末尾的 "*" 匹配剩余路径；Method 为空时匹配任意方法；Permissions 为空的路由不做校验。
+type Route struct {
+	Method      string
+	Pattern     string
+	Permissions []string
+	// Any 为 true 时拥有任意一个权限即可
+	Any bool
+}
+
+// RouteTable 按顺序匹配，第一条匹配的路由生效
+type RouteTable struct {
+	Routes []Route
+	// AllowUnmatched 为 true 时没有匹配路由的请求直接放行，默认返回 403
+	AllowUnmatched bool
+}
+
+type compiledRoute struct {
+	route    Route
+	segments []string
+	wildcard bool
+}
+
+// match 匹配路径并返回路由变量
+func (route *compiledRoute) match(method string, urlPath string) (map[string]string, bool) {
+	if route.route.Method != "" && !strings.EqualFold(route.route.Method, method) {
+		return nil, false
+	}
+	return route.matchPath(urlPath)
+}
+
+// matchPath 忽略方法匹配路径。路径先经 path.Clean 规范化，"//"、"." 与 ".." 段不能绕过路由，
+// 与下游路由器看到的路径一致。
+func (route *compiledRoute) matchPath(urlPath string) (map[string]string, bool) {
+	segments := splitPath(path.Clean("/" + urlPath))
+	if len(segments) < len(route.segments) || (!route.wildcard && len(segments) != len(route.segments)) {
+		return nil, false
+	}
+	vars := make(map[string]string)
+	for i, segment := range route.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			vars[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func splitPath(urlPath string) []string {
+	urlPath = strings.Trim(urlPath, "/")
+	if urlPath == "" {
+		return nil
+	}
+	return strings.Split(urlPath, "/")
+}
+
+func compileRoutes(table RouteTable) ([]*compiledRoute, error) {
+	routes := make([]*compiledRoute, 0, len(table.Routes))
+	for _, route := range table.Routes {
+		if !strings.HasPrefix(route.Pattern, "/") {
+			return nil, fmt.Errorf("route pattern '%s' must start with /", route.Pattern)
+		}
+		compiled := &compiledRoute{route: route, segments: splitPath(route.Pattern)}
+		if n := len(compiled.segments); n > 0 && compiled.segments[n-1] == "*" {
+			compiled.segments = compiled.segments[:n-1]
+			compiled.wildcard = true
+		}
+		for _, segment := range compiled.segments {
+			if segment == "*" {
+				return nil, fmt.Errorf("route pattern '%s': * is only allowed as the last segment", route.Pattern)
+			}
+			if strings.HasPrefix(segment, "{") != strings.HasSuffix(segment, "}") || segment == "{}" {
+				return nil, fmt.Errorf("route pattern '%s': invalid segment '%s'", route.Pattern, segment)
+			}
+		}
+		routes = append(routes, compiled)
+	}
+	return routes, nil
+}
+
+// Routes 按路由表校验权限，路由变量与 ParamsResolver 的结果一起作为规则参数
+func (middleware *Middleware) Routes(table RouteTable) (func(nethttp.Handler) nethttp.Handler, error) {
+	routes, err := compileRoutes(table)
+	if err != nil {
+		return nil, err
+	}
+	return func(next nethttp.Handler) nethttp.Handler {
+		return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			for _, route := range routes {
+				if vars, ok := route.match(r.Method, r.URL.Path); ok {
+					if len(route.route.Permissions) == 0 {
+						next.ServeHTTP(w, r)
+						return
+					}
+					middleware.serve(w, r, next, route.route.Permissions, route.route.Any, vars)
+					return
+				}
+			}
+			if table.AllowUnmatched {
+				next.ServeHTTP(w, r)
+				return
+			}
+			middleware.options.Deny(w, r, nethttp.StatusForbidden, nil)
+		})
+	}, nil
+}
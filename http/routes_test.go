@@ -0,0 +1,124 @@
+package http
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+)
+
+// newTestMiddleware 用户 1 拥有 posts:read，用户 2 分配 admin 角色（含 posts:read 与 posts:edit），
+// 用户取自 X-User-Id 头
+func newTestMiddleware(t *testing.T) (*Middleware, *gorbac.DefaultManager) {
+	t.Helper()
+	now := time.Now()
+	manager := gorbac.NewDefaultManager(gorbac.NewMemoryRepository(), false)
+	admin := gorbac.NewRole("admin", "", "", "", now, now)
+	read := gorbac.NewPermission("posts:read", "", "", "", now, now)
+	edit := gorbac.NewPermission("posts:edit", "", "", "", now, now)
+	for _, item := range []gorbac.Item{admin, read, edit} {
+		manager.Add(item)
+	}
+	_ = manager.AddChild(admin, read)
+	_ = manager.AddChild(admin, edit)
+	manager.Assign(read, 1)
+	manager.Assign(admin, 2)
+
+	middleware, err := NewMiddleware(Options{
+		Manager: manager,
+		Resolver: func(r *nethttp.Request) (interface{}, bool) {
+			id, err := strconv.Atoi(r.Header.Get("X-User-Id"))
+			return id, err == nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return middleware, manager
+}
+
+func TestRoutes(t *testing.T) {
+	middleware, _ := newTestMiddleware(t)
+	routes := []Route{
+		{Pattern: "/public/*"},
+		{Method: "GET", Pattern: "/posts/{id}", Permissions: []string{"posts:read"}},
+		{Method: "PUT", Pattern: "/posts/{id}", Permissions: []string{"posts:edit"}},
+		{Pattern: "/admin/*", Permissions: []string{"posts:edit"}},
+	}
+
+	tests := []struct {
+		name           string
+		allowUnmatched bool
+		method         string
+		path           string
+		userId         string
+		status         int
+		id             string
+	}{
+		{"public", false, "GET", "/public/logo.png", "", nethttp.StatusOK, ""},
+		{"read allowed", false, "GET", "/posts/7", "1", nethttp.StatusOK, "7"},
+		{"edit denied", false, "PUT", "/posts/7", "1", nethttp.StatusForbidden, ""},
+		{"edit allowed", false, "PUT", "/posts/7", "2", nethttp.StatusOK, "7"},
+		{"no user", false, "GET", "/posts/7", "", nethttp.StatusUnauthorized, ""},
+		{"wildcard", false, "GET", "/admin/users/1", "1", nethttp.StatusForbidden, ""},
+
+		// 规范化后的路径参与匹配，不能借助 public 前缀或重复的 / 绕过 admin
+		{"dot dot", false, "GET", "/public/../admin/users", "1", nethttp.StatusForbidden, ""},
+		{"double slash", false, "GET", "//admin//users", "1", nethttp.StatusForbidden, ""},
+		{"dot segment", false, "GET", "/./admin/users", "1", nethttp.StatusForbidden, ""},
+		{"trailing slash", false, "GET", "/posts/7/", "1", nethttp.StatusOK, "7"},
+
+		// 未匹配的请求默认拒绝
+		{"unmatched denied", false, "GET", "/other", "2", nethttp.StatusForbidden, ""},
+		{"unmatched method denied", false, "DELETE", "/posts/7", "2", nethttp.StatusForbidden, ""},
+		{"unmatched allowed", true, "GET", "/other", "", nethttp.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrap, err := middleware.Routes(RouteTable{Routes: routes, AllowUnmatched: tt.allowUnmatched})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := ""
+			handler := wrap(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				if params := gorbac.ParamsFromContext(r.Context()); params != nil {
+					id, _ = params["id"].(string)
+				}
+			}))
+			r := httptest.NewRequest(tt.method, "http://example.com"+tt.path, nil)
+			if tt.userId != "" {
+				r.Header.Set("X-User-Id", tt.userId)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, w.Code, tt.status)
+			}
+			if id != tt.id {
+				t.Errorf("%s %s: id param = %q, want %q", tt.method, tt.path, id, tt.id)
+			}
+		})
+	}
+}
+
+func TestCompileRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"relative", "posts"},
+		{"inner wildcard", "/posts/*/edit"},
+		{"unclosed variable", "/posts/{id"},
+		{"empty variable", "/posts/{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRoutes(RouteTable{Routes: []Route{{Pattern: tt.pattern}}}); err == nil {
+				t.Errorf("compileRoutes(%q) succeeded, want error", tt.pattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// LintSeverity 检查结果的严重程度
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// 检查项
+const (
+	LintOrphanPermission     = "orphan-permission"     // 权限没有父级也没有分配，任何用户都无法获得
+	LintUnusedRole           = "unused-role"           // 角色及其祖先都没有分配，也不是默认角色
+	LintRedundantEdge        = "redundant-edge"        // A→C 且存在 A→B→…→C
+	LintMissingRule          = "missing-rule"          // item 或组合规则引用了不存在的规则
+	LintUnregisteredExecutor = "unregistered-executor" // 规则或 item 引用了未注册的执行器
+	LintDanglingChild        = "dangling-child"        // auth_item_child 中的 parent 或 child 不存在
+	LintDanglingAssignment   = "dangling-assignment"   // 分配给了不存在的 item
+)
+
+// LintFinding 一条检查结果，Fixable 的问题可由 LintOptions.Fix 自动修复
+type LintFinding struct {
+	Code     string       `json:"code"`
+	Severity LintSeverity `json:"severity"`
+	Item     string       `json:"item"`
+	Message  string       `json:"message"`
+	Fixable  bool         `json:"fixable"`
+	Fixed    bool         `json:"fixed"`
+
+	fix func() error
+}
+
+type LintOptions struct {
+	// Fix 自动修复安全的问题：删除冗余的继承关系、悬空的 auth_item_child 记录与悬空的分配
+	Fix bool
+}
+
+// Lint 扫描仓库中的 item、继承关系、规则与分配，按严重程度返回检查结果
+func (manager *DefaultManager) Lint(options LintOptions) ([]LintFinding, error) {
+	return manager.lint(context.Background(), options)
+}
+
+func (manager *DefaultManager) lint(ctx context.Context, options LintOptions) ([]LintFinding, error) {
+	items, err := manager.mapper.FindAllItems()
+	if err != nil {
+		return nil, fmt.Errorf("find all items: %v", err)
+	}
+	edges, err := manager.mapper.FindChildrenList()
+	if err != nil {
+		return nil, fmt.Errorf("find children list: %v", err)
+	}
+	rules, err := manager.mapper.GetRules()
+	if err != nil {
+		return nil, fmt.Errorf("get rules: %v", err)
+	}
+	assignments, err := manager.mapper.GetAllAssignment()
+	if err != nil {
+		return nil, fmt.Errorf("get all assignment: %v", err)
+	}
+
+	findings := make([]LintFinding, 0)
+	add := func(finding LintFinding) {
+		finding.Fixable = finding.fix != nil
+		findings = append(findings, finding)
+	}
+
+	itemMap := make(map[string]Item, len(items))
+	for _, item := range items {
+		itemMap[item.GetName()] = item
+	}
+	ruleMap := make(map[string]*Rule, len(rules))
+	for _, rule := range rules {
+		ruleMap[rule.Name] = rule
+	}
+
+	// 悬空的继承关系与分配
+	children := make(map[string][]string)
+	parents := make(map[string][]string)
+	for _, edge := range edges {
+		if itemMap[edge.Parent] == nil || itemMap[edge.Child] == nil {
+			parent, child := edge.Parent, edge.Child
+			add(LintFinding{Code: LintDanglingChild, Severity: LintError, Item: parent,
+				Message: fmt.Sprintf("child relation %s -> %s references a missing item", parent, child),
+				fix:     func() error { return manager.mapper.RemoveChild(parent, child) }})
+			continue
+		}
+		children[edge.Parent] = append(children[edge.Parent], edge.Child)
+		parents[edge.Child] = append(parents[edge.Child], edge.Parent)
+	}
+	assigned := make(map[string]bool)
+	for _, assignment := range assignments {
+		if itemMap[assignment.ItemName] == nil {
+			userId, name := assignment.UserId, assignment.ItemName
+			add(LintFinding{Code: LintDanglingAssignment, Severity: LintError, Item: name,
+				Message: fmt.Sprintf("user %v is assigned to missing item %s", userId, name),
+				fix:     func() error { return manager.mapper.RemoveAssignment(userId, name) }})
+			continue
+		}
+		assigned[assignment.ItemName] = true
+	}
+
+	// 冗余的继承关系：只在中间经过的 item 都未绑定规则或执行器时才认为冗余，否则两条路径的判定不同
+	reachable := func(parent string, target string) bool {
+		visited := map[string]bool{parent: true}
+		queue := make([]string, 0)
+		for _, child := range children[parent] {
+			if child != target && !visited[child] && !isGated(itemMap[child]) {
+				visited[child] = true
+				queue = append(queue, child)
+			}
+		}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			for _, child := range children[name] {
+				if child == target {
+					return true
+				}
+				if !visited[child] && !isGated(itemMap[child]) {
+					visited[child] = true
+					queue = append(queue, child)
+				}
+			}
+		}
+		return false
+	}
+	for _, parent := range sortedNames(mapKeys(children)) {
+		for _, child := range sortedNames(children[parent]) {
+			if !reachable(parent, child) {
+				continue
+			}
+			p, c := parent, child
+			add(LintFinding{Code: LintRedundantEdge, Severity: LintWarning, Item: p,
+				Message: fmt.Sprintf("%s -> %s is already inherited through another child of %s", p, c, p),
+				fix: func() error {
+					// 前面的修复可能已经删掉了另一条路径
+					if !reachable(p, c) {
+						return fmt.Errorf("no longer redundant")
+					}
+					if err := manager.mapper.RemoveChild(p, c); err != nil {
+						return err
+					}
+					children[p] = removeName(children[p], c)
+					return nil
+				}})
+		}
+	}
+
+	// 无人持有的角色与无法获得的权限
+	defaultRoles := manager.cache.snapshot().defaultRoles
+	for _, item := range items {
+		name := item.GetName()
+		switch item.GetType() {
+		case PermissionType:
+			if len(parents[name]) == 0 && !assigned[name] {
+				add(LintFinding{Code: LintOrphanPermission, Severity: LintWarning, Item: name,
+					Message: fmt.Sprintf("permission %s has no parent and is not assigned", name)})
+			}
+		case RoleType:
+			held := false
+			manager.walkHierarchy(name, func(n string) []string { return parents[n] }, 0, func(n string, depth int) {
+				held = held || assigned[n] || defaultRoles[n] != nil
+			})
+			if !held && !assigned[name] && defaultRoles[name] == nil {
+				add(LintFinding{Code: LintUnusedRole, Severity: LintInfo, Item: name,
+					Message: fmt.Sprintf("role %s is not held by any user", name)})
+			}
+		}
+	}
+
+	// 规则与执行器引用
+	registry := manager.GetExecutorRegistry()
+	for _, item := range items {
+		if item.GetRuleName() != "" && ruleMap[item.GetRuleName()] == nil {
+			add(LintFinding{Code: LintMissingRule, Severity: LintError, Item: item.GetName(),
+				Message: fmt.Sprintf("item %s references missing rule %s", item.GetName(), item.GetRuleName())})
+		}
+		if item.GetExecuteName() != "" && registry.GetExecutor(item.GetExecuteName()) == nil {
+			add(LintFinding{Code: LintUnregisteredExecutor, Severity: LintError, Item: item.GetName(),
+				Message: fmt.Sprintf("item %s references unregistered executor %s", item.GetName(), item.GetExecuteName())})
+		}
+	}
+	for _, rule := range rules {
+		if !IsCompositeRule(rule) {
+			if rule.ExecuteName != "" && registry.GetExecutor(rule.ExecuteName) == nil {
+				add(LintFinding{Code: LintUnregisteredExecutor, Severity: LintError, Item: rule.Name,
+					Message: fmt.Sprintf("rule %s references unregistered executor %s", rule.Name, rule.ExecuteName)})
+			}
+			continue
+		}
+		operands, err := ParseCompositeOperands(rule.Data)
+		if err != nil {
+			add(LintFinding{Code: LintMissingRule, Severity: LintError, Item: rule.Name,
+				Message: fmt.Sprintf("composite rule %s has invalid operands: %v", rule.Name, err)})
+			continue
+		}
+		for _, operand := range operands {
+			if operand.Rule && ruleMap[operand.Name] == nil {
+				add(LintFinding{Code: LintMissingRule, Severity: LintError, Item: rule.Name,
+					Message: fmt.Sprintf("composite rule %s references missing rule %s", rule.Name, operand.Name)})
+			}
+			if !operand.Rule && registry.GetExecutor(operand.Name) == nil {
+				add(LintFinding{Code: LintUnregisteredExecutor, Severity: LintError, Item: rule.Name,
+					Message: fmt.Sprintf("composite rule %s references unregistered executor %s", rule.Name, operand.Name)})
+			}
+		}
+	}
+
+	if options.Fix {
+		fixed := false
+		for i := range findings {
+			if findings[i].fix == nil {
+				continue
+			}
+			if err := findings[i].fix(); err != nil {
+				findings[i].Message += fmt.Sprintf(" (fix failed: %v)", err)
+				continue
+			}
+			findings[i].Fixed = true
+			fixed = true
+			manager.audit(ctx, AuditEntry{Operation: AuditLintFix, Item: findings[i].Item}, nil, findings[i])
+		}
+		if fixed {
+			manager.resetAllCache()
+		}
+	}
+
+	severity := map[LintSeverity]int{LintError: 0, LintWarning: 1, LintInfo: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return severity[findings[i].Severity] < severity[findings[j].Severity]
+		}
+		if findings[i].Code != findings[j].Code {
+			return findings[i].Code < findings[j].Code
+		}
+		return findings[i].Item < findings[j].Item
+	})
+	return findings, nil
+}
+
+func removeName(names []string, name string) []string {
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func mapKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,89 @@
+package gorbac
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newLintManager 在 newTestManager 的层级上构造每一类问题
+func newLintManager(t *testing.T) (*DefaultManager, *MemoryRepository) {
+	t.Helper()
+	now := time.Now()
+	repo := NewMemoryRepository()
+	manager := newTestManagerWith(t, repo, true)
+
+	// admin -> posts:edit 已经经 editor 继承
+	_ = repo.AddItemChild(*NewItemChild("admin", "posts:edit"))
+	// 经绑定规则的 item 继承时不算冗余
+	_ = repo.AddItem(NewRole("gated", "", "", "yes", now, now))
+	_ = repo.AddItemChild(*NewItemChild("admin", "gated"))
+	_ = repo.AddItem(NewPermission("posts:secret", "", "", "", now, now))
+	_ = repo.AddItemChild(*NewItemChild("gated", "posts:secret"))
+	_ = repo.AddItemChild(*NewItemChild("admin", "posts:secret"))
+
+	_ = repo.AddItem(NewPermission("orphan", "", "", "", now, now))
+	_ = repo.AddItem(NewRole("unused", "", "ghost_rule", "", now, now))
+	_ = repo.AddItem(NewPermission("executed", "", "", "ghost_executor", now, now))
+	_ = repo.AddItemChild(*NewItemChild("unused", "executed"))
+	_ = repo.AddRule(*composite("combo", CompositeAnd, "rule:missing", "executor:absent"))
+	_ = repo.AddItemChild(*NewItemChild("admin", "deleted"))
+	_ = repo.Assign(*NewAssignment(7, "deleted"))
+	return manager, repo
+}
+
+func findingKeys(findings []LintFinding) []string {
+	keys := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		key := finding.Code + ":" + finding.Item
+		if finding.Fixed {
+			key += "(fixed)"
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		fix      bool
+		findings string
+		after    string
+	}{
+		{"report", false,
+			"[dangling-assignment:deleted dangling-child:admin missing-rule:combo missing-rule:unused unregistered-executor:combo unregistered-executor:executed unregistered-executor:gated orphan-permission:orphan redundant-edge:admin unused-role:unused]",
+			"[dangling-assignment:deleted dangling-child:admin missing-rule:combo missing-rule:unused unregistered-executor:combo unregistered-executor:executed unregistered-executor:gated orphan-permission:orphan redundant-edge:admin unused-role:unused]"},
+		{"fix", true,
+			"[dangling-assignment:deleted(fixed) dangling-child:admin(fixed) missing-rule:combo missing-rule:unused unregistered-executor:combo unregistered-executor:executed unregistered-executor:gated orphan-permission:orphan redundant-edge:admin(fixed) unused-role:unused]",
+			"[missing-rule:combo missing-rule:unused unregistered-executor:combo unregistered-executor:executed unregistered-executor:gated orphan-permission:orphan unused-role:unused]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, repo := newLintManager(t)
+			findings, err := manager.Lint(LintOptions{Fix: tt.fix})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(findingKeys(findings)); got != tt.findings {
+				t.Errorf("findings = %s\nwant       %s", got, tt.findings)
+			}
+			for _, finding := range findings {
+				if finding.Fixable != (finding.Code == LintRedundantEdge || finding.Code == LintDanglingChild || finding.Code == LintDanglingAssignment) {
+					t.Errorf("%s: Fixable = %v", finding.Code, finding.Fixable)
+				}
+			}
+
+			findings, err = manager.Lint(LintOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(findingKeys(findings)); got != tt.after {
+				t.Errorf("findings after = %s\nwant             %s", got, tt.after)
+			}
+			if tt.fix && (repo.HasChild("admin", "posts:edit") || !repo.HasChild("admin", "posts:secret") || !repo.HasChild("editor", "posts:edit")) {
+				t.Error("fix removed the wrong child relation")
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package gorbac
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Logger 结构化日志接口，args 为交替的键值对（key1, value1, key2, value2...），与 log/slog 一致，
+// *slog.Logger 可直接使用：
+//
+//	manager.SetLogger(slog.Default())
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// LogLevel 日志级别，取值与 slog.Level 相同
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+func (level LogLevel) String() string {
+	switch {
+	case level < LevelInfo:
+		return "DEBUG"
+	case level < LevelWarn:
+		return "INFO"
+	case level < LevelError:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// StdLogger 以 "LEVEL msg key=value..." 的格式写入标准库 *log.Logger，低于 Level 的日志被丢弃
+type StdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger logger 为 nil 时使用标准库默认 Logger（log.Default()）
+func NewStdLogger(logger *log.Logger, level LogLevel) *StdLogger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &StdLogger{logger: logger, level: level}
+}
+
+func (l *StdLogger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+func (l *StdLogger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+func (l *StdLogger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+func (l *StdLogger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+func (l *StdLogger) log(level LogLevel, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(level.String())
+	sb.WriteByte(' ')
+	sb.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		sb.WriteByte(' ')
+		if i+1 == len(args) {
+			// 落单的值与 slog 一致记为 !BADKEY
+			sb.WriteString("!BADKEY=")
+			sb.WriteString(logValue(args[i]))
+			break
+		}
+		sb.WriteString(fmt.Sprint(args[i]))
+		sb.WriteByte('=')
+		sb.WriteString(logValue(args[i+1]))
+	}
+	l.logger.Output(3, sb.String())
+}
+
+// logValue 含空白、引号或等号的值加引号
+func logValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " \t\r\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// NopLogger 丢弃所有日志
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, args ...interface{}) {}
+
+func (NopLogger) Info(msg string, args ...interface{}) {}
+
+func (NopLogger) Warn(msg string, args ...interface{}) {}
+
+func (NopLogger) Error(msg string, args ...interface{}) {}
+
+type loggerHolder struct {
+	logger Logger
+}
+
+var defaultLogger atomic.Value // *loggerHolder
+
+func init() {
+	defaultLogger.Store(&loggerHolder{logger: NewStdLogger(nil, LevelInfo)})
+}
+
+// SetDefaultLogger 设置未单独配置日志的管理器、执行器与判定日志使用的日志，
+// 默认以 Info 级别写入标准库默认 Logger；nil 丢弃所有日志。
+func SetDefaultLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	defaultLogger.Store(&loggerHolder{logger: logger})
+}
+
+// DefaultLogger 返回 SetDefaultLogger 设置的日志
+func DefaultLogger() Logger {
+	return defaultLogger.Load().(*loggerHolder).logger
+}
+
+// loggerOrDefault 读取 value 中的 *loggerHolder，未设置时返回 DefaultLogger
+func loggerOrDefault(value *atomic.Value) Logger {
+	if holder, ok := value.Load().(*loggerHolder); ok && holder.logger != nil {
+		return holder.logger
+	}
+	return DefaultLogger()
+}
+
+// ---------------------- Manager ---------------------------
+
+// SetLogger 为管理器单独设置日志，nil 时使用 DefaultLogger。
+// 执行器（如 ExpressionExecutor）不随管理器配置，见 ExpressionExecutor.SetLogger。
+func (manager *DefaultManager) SetLogger(logger Logger) {
+	manager.logger.Store(&loggerHolder{logger: logger})
+}
+
+func (manager *DefaultManager) log() Logger {
+	return loggerOrDefault(&manager.logger)
+}
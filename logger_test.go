@@ -0,0 +1,123 @@
+package gorbac
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LevelInfo)
+
+	tests := []struct {
+		name string
+		log  func()
+		want string
+	}{
+		{"below level dropped", func() { logger.Debug("hidden", "key", 1) }, ""},
+		{"info", func() { logger.Info("[rbac] loaded", "items", 3, "cache", true) }, "INFO [rbac] loaded items=3 cache=true\n"},
+		{"warn", func() { logger.Warn("slow") }, "WARN slow\n"},
+		{"quoted values", func() { logger.Error("failed", "err", `item "a" not found`, "empty", "", "expr", "a=b") },
+			`ERROR failed err="item \"a\" not found" empty="" expr="a=b"` + "\n"},
+		{"non string key", func() { logger.Info("x", 1, 2) }, "INFO x 1=2\n"},
+		{"odd args", func() { logger.Warn("x", "key", "value", "dangling") }, "WARN x key=value !BADKEY=dangling\n"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		tt.log()
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: output = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	buf.Reset()
+	debug := NewStdLogger(log.New(&buf, "", 0), LevelDebug)
+	debug.Debug("visible")
+	if got := buf.String(); got != "DEBUG visible\n" {
+		t.Errorf("debug level output = %q", got)
+	}
+	buf.Reset()
+	errorsOnly := NewStdLogger(log.New(&buf, "", 0), LevelError)
+	errorsOnly.Warn("hidden")
+	errorsOnly.Error("shown")
+	if got := buf.String(); got != "ERROR shown\n" {
+		t.Errorf("error level output = %q", got)
+	}
+}
+
+func TestLogLevelString(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		want  string
+	}{
+		{LevelDebug, "DEBUG"},
+		{LevelInfo - 1, "DEBUG"},
+		{LevelInfo, "INFO"},
+		{LevelWarn - 1, "INFO"},
+		{LevelWarn, "WARN"},
+		{LevelError, "ERROR"},
+		{LevelError + 4, "ERROR"},
+	}
+	for _, tt := range tests {
+		if got := tt.level.String(); got != tt.want {
+			t.Errorf("LogLevel(%d).String() = %q, want %q", int(tt.level), got, tt.want)
+		}
+	}
+}
+
+// withDefaultLogger 在测试期间替换 DefaultLogger
+func withDefaultLogger(t *testing.T, logger Logger) {
+	t.Helper()
+	previous := DefaultLogger()
+	SetDefaultLogger(logger)
+	t.Cleanup(func() { SetDefaultLogger(previous) })
+}
+
+func TestDefaultLogger(t *testing.T) {
+	var global, own bytes.Buffer
+	withDefaultLogger(t, NewStdLogger(log.New(&global, "", 0), LevelWarn))
+	// 执行器未注册时记录 Warn
+	manager := newGateManager(t, NewExecutorRegistry())
+	ctx := context.Background()
+
+	manager.CheckAccess(ctx, 1, "doc:read")
+	if !strings.Contains(global.String(), "WARN [rbac] executor failed executor=gate item=doc:read rule=check outcome=missing") {
+		t.Errorf("default logger output = %q", global.String())
+	}
+	if DefaultLogger() != manager.log() {
+		t.Error("manager without its own logger does not use DefaultLogger")
+	}
+
+	// 管理器单独设置的日志优先于 DefaultLogger
+	global.Reset()
+	manager.SetLogger(NewStdLogger(log.New(&own, "", 0), LevelInfo))
+	manager.CheckAccess(ctx, 1, "doc:read")
+	if global.Len() != 0 {
+		t.Errorf("default logger written after SetLogger: %q", global.String())
+	}
+	if !strings.Contains(own.String(), "WARN [rbac] executor failed") {
+		t.Errorf("manager logger output = %q", own.String())
+	}
+
+	// SetLogger(nil) 恢复使用 DefaultLogger
+	own.Reset()
+	manager.SetLogger(nil)
+	manager.CheckAccess(ctx, 1, "doc:read")
+	if own.Len() != 0 || global.Len() == 0 {
+		t.Errorf("after SetLogger(nil): manager logger %q, default logger %q", own.String(), global.String())
+	}
+
+	// SetDefaultLogger(nil) 丢弃所有日志
+	SetDefaultLogger(nil)
+	if _, ok := DefaultLogger().(NopLogger); !ok {
+		t.Fatalf("DefaultLogger after SetDefaultLogger(nil) = %T, want NopLogger", DefaultLogger())
+	}
+	global.Reset()
+	manager.CheckAccess(ctx, 1, "doc:read")
+	if global.Len() != 0 {
+		t.Errorf("output after SetDefaultLogger(nil) = %q", global.String())
+	}
+}
@@ -4,30 +4,53 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	logger "github.com/kordar/gologger"
 )
 
 type DefaultManager struct {
 	mapper AuthRepository
-	cache  *DefaultCache
+	// cache holds the immutable policy snapshot, including the default roles:
 	// a list of role names that are assigned to every user automatically without calling [[assign()]].
 	// Note that these roles are applied to users, regardless of their state of authentication.
-	defaultRoles            map[string]*Role
+	cache *DefaultCache
+	// mu guards _checkAccessAssignments and assignmentVersion, policy reads go through cache snapshots
 	_checkAccessAssignments map[interface{}]map[string]*Assignment
+	assignmentVersion       uint64
 	mu                      sync.RWMutex
+	// loads deduplicates concurrent policy loads (by generation) and assignment loads (by user)
+	loads           flightGroup
+	assignmentLoads flightGroup
+	refreshMu       sync.Mutex
+	refreshEvents   chan struct{}
+	// compile enables the precomputed evaluation engine, see compiledPolicy
+	compile          int32
+	executors        atomic.Value // *ExecutorRegistry
+	execution        executionConfig
+	auditSink        atomic.Value // *auditHolder
+	decisionLogger   atomic.Value // *decisionLoggerHolder
+	observer         atomic.Value // *observerHolder
+	logger           atomic.Value // *loggerHolder
+	userIdNormalizer atomic.Value // *userIdNormalizerHolder
 }
 
 func NewDefaultManager(mapper AuthRepository, cache bool) *DefaultManager {
-	defaultCache := NewDefaultCache(cache)
-	defaultCache.invalidateCache()
-	return &DefaultManager{
+	manager := &DefaultManager{
 		mapper:                  mapper,
-		cache:                   defaultCache,
+		cache:                   NewDefaultCache(cache),
 		_checkAccessAssignments: make(map[interface{}]map[string]*Assignment),
-		defaultRoles:            make(map[string]*Role),
 	}
+	manager.executors.Store(ExecuteManager)
+	return manager
+}
+
+// SetExecutorRegistry 为管理器配置独立的执行器注册表，默认使用全局 ExecuteManager
+func (manager *DefaultManager) SetExecutorRegistry(registry *ExecutorRegistry) {
+	manager.executors.Store(registry)
+}
+
+func (manager *DefaultManager) GetExecutorRegistry() *ExecutorRegistry {
+	return manager.executors.Load().(*ExecutorRegistry)
 }
 
 func (manager *DefaultManager) GetItem(name string) Item {
@@ -43,7 +66,7 @@ func (manager *DefaultManager) GetItem(name string) Item {
 func (manager *DefaultManager) getItems(itemType ItemType) []Item {
 	items, err := manager.mapper.GetItemsByType(itemType)
 	if err != nil {
-		logger.Warnf("Error getting items: %s", err.Error())
+		manager.log().Error("[rbac] get items failed", "type", itemType, "err", err)
 		return make([]Item, 0)
 	}
 	return items
@@ -90,11 +113,38 @@ func (manager *DefaultManager) addItem(item Item) bool {
 }
 
 func (manager *DefaultManager) AddRule(rule Rule) bool {
-	err := manager.mapper.AddRule(rule)
-	if err == nil {
-		manager.resetAllCache()
+	return manager.addRule(context.Background(), rule)
+}
+
+func (manager *DefaultManager) addRule(ctx context.Context, rule Rule) bool {
+	if err := manager.validateRule(rule.Name, rule); err != nil {
+		manager.log().Warn("[rbac] add rule rejected", "rule", rule.Name, "err", err)
+		return false
 	}
-	return err == nil
+	if err := manager.mapper.AddRule(rule); err != nil {
+		return false
+	}
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditAddRule, Item: rule.Name}, nil, rule)
+	return true
+}
+
+// ValidateRule 校验规则：组合规则检查操作数与环，其余由规则的执行器校验（见 RuleValidator），
+// 例如解析并类型检查表达式
+func (manager *DefaultManager) ValidateRule(rule Rule) error {
+	return manager.validateRule(rule.Name, rule)
+}
+
+// validateRule oldName 为更新前的规则名，新增时与 rule.Name 相同
+func (manager *DefaultManager) validateRule(oldName string, rule Rule) error {
+	if IsCompositeRule(&rule) {
+		return manager.validateComposite(oldName, rule)
+	}
+	executor := rule.GetExecutorFrom(manager.GetExecutorRegistry())
+	if validator, ok := executor.(RuleValidator); ok {
+		return validator.ValidateRule(rule)
+	}
+	return nil
 }
 
 func (manager *DefaultManager) removeItem(item Item) bool {
@@ -104,8 +154,14 @@ func (manager *DefaultManager) removeItem(item Item) bool {
 }
 
 func (manager *DefaultManager) RemoveRule(rule Rule) bool {
+	return manager.removeRule(context.Background(), rule)
+}
+
+func (manager *DefaultManager) removeRule(ctx context.Context, rule Rule) bool {
+	before := manager.auditBefore(func() interface{} { return manager.getRuleItem(rule.Name) })
 	_ = manager.mapper.RemoveRule(rule.Name)
 	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveRule, Item: rule.Name}, before, nil)
 	return true
 }
 
@@ -118,15 +174,26 @@ func (manager *DefaultManager) updateItem(name string, item Item) bool {
 }
 
 func (manager *DefaultManager) UpdateRule(name string, rule Rule) bool {
-	err := manager.mapper.UpdateRule(name, rule)
-	if err == nil {
-		manager.resetAllCache()
+	return manager.updateRule(context.Background(), name, rule)
+}
+
+func (manager *DefaultManager) updateRule(ctx context.Context, name string, rule Rule) bool {
+	if err := manager.validateRule(name, rule); err != nil {
+		manager.log().Warn("[rbac] update rule rejected", "rule", name, "err", err)
+		return false
 	}
-	return err == nil
+	before := manager.auditBefore(func() interface{} { return manager.getRuleItem(name) })
+	if err := manager.mapper.UpdateRule(name, rule); err != nil {
+		return false
+	}
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditUpdateRule, Item: name}, before, rule)
+	return true
 }
 
 // GetRolesByUser 获取用户角色列表
 func (manager *DefaultManager) GetRolesByUser(userId interface{}) []*Role {
+	userId = manager.userId(userId)
 	data, err := manager.mapper.FindRolesByUser(userId)
 	if err != nil {
 		return nil
@@ -146,7 +213,7 @@ func (manager *DefaultManager) GetRolesByUser(userId interface{}) []*Role {
 func (manager *DefaultManager) GetChildRoles(roleName string) []*Role {
 	role := manager.GetRole(roleName)
 	if role == nil {
-		logger.Infof("[rbac] Role %s not found manager.", roleName)
+		manager.log().Debug("[rbac] role not found", "role", roleName)
 		return nil
 	}
 
@@ -178,7 +245,7 @@ func (manager *DefaultManager) getChildrenList() map[string][]string {
 	m := make(map[string][]string)
 	list, err := manager.mapper.FindChildrenList()
 	if err != nil {
-		logger.Warnf("[rbac] get children list err=%v", err)
+		manager.log().Error("[rbac] find children list failed", "err", err)
 		return m
 	}
 
@@ -219,6 +286,7 @@ func (manager *DefaultManager) GetPermissionsByRole(roleName string) []*Permissi
 }
 
 func (manager *DefaultManager) GetPermissionsByUser(userId interface{}) []*Permission {
+	userId = manager.userId(userId)
 	directPermissions := manager.getDirectPermissionsByUser(userId)
 	inheritedPermissions := manager.getInheritedPermissionsByUser(userId)
 	// 合并默认角色下的权限节点
@@ -295,6 +363,10 @@ func (manager *DefaultManager) detectLoop(parent Item, child Item) bool {
 }
 
 func (manager *DefaultManager) AddChild(parent Item, child Item) error {
+	return manager.addChild(context.Background(), parent, child)
+}
+
+func (manager *DefaultManager) addChild(ctx context.Context, parent Item, child Item) error {
 
 	if parent.GetName() == child.GetName() {
 		return fmt.Errorf("cannot add '%s' as a child of itself", parent.GetName())
@@ -309,25 +381,47 @@ func (manager *DefaultManager) AddChild(parent Item, child Item) error {
 	}
 
 	itemChild := NewItemChild(parent.GetName(), child.GetName())
-	err := manager.mapper.AddItemChild(*itemChild)
-	if err == nil {
-		manager.resetAllCache()
+	if err := manager.mapper.AddItemChild(*itemChild); err != nil {
+		return err
 	}
-	return err
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditAddChild, Item: parent.GetName(), Child: child.GetName()}, nil, itemChild)
+	return nil
 
 }
 
 func (manager *DefaultManager) RemoveChild(parent Item, child Item) bool {
-	err := manager.mapper.RemoveChildren(parent.GetName())
-	if err == nil {
-		manager.resetAllCache()
+	return manager.removeChild(context.Background(), parent, child)
+}
+
+func (manager *DefaultManager) removeChild(ctx context.Context, parent Item, child Item) bool {
+	if err := manager.mapper.RemoveChild(parent.GetName(), child.GetName()); err != nil {
+		return false
 	}
-	return err == nil
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveChild, Item: parent.GetName(), Child: child.GetName()},
+		NewItemChild(parent.GetName(), child.GetName()), nil)
+	return true
 }
 
 func (manager *DefaultManager) RemoveChildren(parent Item) bool {
-	err := manager.mapper.RemoveChildren(parent.GetName())
-	return err == nil
+	return manager.removeChildren(context.Background(), parent)
+}
+
+func (manager *DefaultManager) removeChildren(ctx context.Context, parent Item) bool {
+	before := manager.auditBefore(func() interface{} {
+		children := make([]*ItemChild, 0)
+		for _, child := range manager.GetChildren(parent.GetName()) {
+			children = append(children, NewItemChild(parent.GetName(), child.GetName()))
+		}
+		return auditState{Children: children}
+	})
+	if err := manager.mapper.RemoveChildren(parent.GetName()); err != nil {
+		return false
+	}
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveChildren, Item: parent.GetName()}, before, nil)
+	return true
 }
 
 func (manager *DefaultManager) HasChild(parent Item, child Item) bool {
@@ -346,15 +440,20 @@ func (manager *DefaultManager) GetChildren(name string) []Item {
 }
 
 func (manager *DefaultManager) Assign(item Item, userId interface{}) *Assignment {
+	return manager.assign(context.Background(), item, userId)
+}
+
+func (manager *DefaultManager) assign(ctx context.Context, item Item, userId interface{}) *Assignment {
+	userId = manager.userId(userId)
 	assignment := NewAssignment(userId, item.GetName())
+	// 写入后再清缓存：先清缓存时，写入完成前并发的校验会把旧分配重新读入缓存
 	err := manager.mapper.Assign(*assignment)
-	if err == nil {
-		manager.mu.Lock()
-		delete(manager._checkAccessAssignments, userId)
-		manager.mu.Unlock()
-		return assignment
+	manager.forgetUser(userId)
+	if err != nil {
+		return nil
 	}
-	return nil
+	manager.audit(ctx, AuditEntry{Operation: AuditAssign, Item: item.GetName(), UserId: userId}, nil, assignment)
+	return assignment
 }
 
 func (manager *DefaultManager) UniqueStrings(names []string) []string {
@@ -372,38 +471,65 @@ func (manager *DefaultManager) UniqueStrings(names []string) []string {
 }
 
 func (manager *DefaultManager) Assigns(userId interface{}, name ...string) []*Assignment {
+	return manager.assigns(context.Background(), userId, name...)
+}
+
+func (manager *DefaultManager) assigns(ctx context.Context, userId interface{}, name ...string) []*Assignment {
+	userId = manager.userId(userId)
 	name = manager.UniqueStrings(name)
 	assignments := make([]*Assignment, 0, len(name))
 	for _, n := range name {
 		assignments = append(assignments, NewAssignment(userId, n))
 	}
 	err := manager.mapper.Assigns(assignments...)
-	if err == nil {
-		manager.mu.Lock()
-		delete(manager._checkAccessAssignments, userId)
-		manager.mu.Unlock()
-		return assignments
+	manager.forgetUser(userId)
+	if err != nil {
+		return nil
 	}
-	return nil
+	for _, assignment := range assignments {
+		manager.audit(ctx, AuditEntry{Operation: AuditAssign, Item: assignment.ItemName, UserId: userId}, nil, assignment)
+	}
+	return assignments
 }
 
 func (manager *DefaultManager) Revoke(item Item, userId interface{}) bool {
-	manager.mu.Lock()
-	delete(manager._checkAccessAssignments, userId)
-	manager.mu.Unlock()
+	return manager.revoke(context.Background(), item, userId)
+}
+
+func (manager *DefaultManager) revoke(ctx context.Context, item Item, userId interface{}) bool {
+	userId = manager.userId(userId)
+	before := manager.auditBefore(func() interface{} { return manager.GetAssignment(item.GetName(), userId) })
 	err := manager.mapper.RemoveAssignment(userId, item.GetName())
-	return err == nil
+	manager.forgetUser(userId)
+	if err != nil {
+		return false
+	}
+	manager.audit(ctx, AuditEntry{Operation: AuditRevoke, Item: item.GetName(), UserId: userId}, before, nil)
+	return true
 }
 
 func (manager *DefaultManager) RevokeAll(userId interface{}) bool {
-	manager.mu.Lock()
-	delete(manager._checkAccessAssignments, userId)
-	manager.mu.Unlock()
+	return manager.revokeAll(context.Background(), userId) == nil
+}
+
+// revokeAll 撤销用户的全部分配，RevokeAll 与 RemoveAllAssignmentByUser 共用
+func (manager *DefaultManager) revokeAll(ctx context.Context, userId interface{}) error {
+	userId = manager.userId(userId)
+	before := manager.auditBefore(func() interface{} {
+		assignments, _ := manager.mapper.GetAssignments(userId)
+		return auditState{Assignments: assignments}
+	})
 	err := manager.mapper.RemoveAllAssignmentByUser(userId)
-	return err == nil
+	manager.forgetUser(userId)
+	if err != nil {
+		return err
+	}
+	manager.audit(ctx, AuditEntry{Operation: AuditRevokeAll, UserId: userId}, before, nil)
+	return nil
 }
 
 func (manager *DefaultManager) GetAssignment(roleName string, userId interface{}) *Assignment {
+	userId = manager.userId(userId)
 	if assignment, err := manager.mapper.GetAssignment(userId, roleName); err == nil {
 		return assignment
 	} else {
@@ -412,6 +538,7 @@ func (manager *DefaultManager) GetAssignment(roleName string, userId interface{}
 }
 
 func (manager *DefaultManager) GetAssignments(userId interface{}) map[string]*Assignment {
+	userId = manager.userId(userId)
 	assignments := make(map[string]*Assignment)
 	authAssignments, err := manager.mapper.GetAssignments(userId)
 	if err != nil {
@@ -428,31 +555,44 @@ func (manager *DefaultManager) GetUserIdsByRole(roleName string) []interface{} {
 	users := make([]interface{}, 0)
 	authAssignments, err := manager.mapper.GetAssignmentsByItem(roleName)
 	if err != nil {
-		logger.Warnf("[rbac] GetUserIdsByRole err = %v", err)
+		manager.log().Error("[rbac] get assignments by role failed", "role", roleName, "err", err)
 		return users
 	}
 
 	for _, authAssignment := range authAssignments {
-		users = append(users, authAssignment.UserId)
+		users = append(users, manager.userId(authAssignment.UserId))
 	}
 
 	return users
 }
 
 func (manager *DefaultManager) RemoveAll() {
+	manager.removeAll(context.Background())
+}
+
+func (manager *DefaultManager) removeAll(ctx context.Context) {
+	before := manager.auditBefore(func() interface{} {
+		state := auditState{}
+		state.Rules, _ = manager.mapper.GetRules()
+		state.Items, _ = manager.mapper.FindAllItems()
+		state.Children, _ = manager.mapper.FindChildrenList()
+		state.Assignments, _ = manager.mapper.GetAllAssignment()
+		return state
+	})
 	_ = manager.mapper.RemoveAll()
 	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveAll}, before, nil)
 }
 
 func (manager *DefaultManager) RemoveAllPermissions() {
-	manager.removeAllItems(PermissionType)
+	manager.removeAllItems(context.Background(), PermissionType)
 }
 
 func (manager *DefaultManager) RemoveAllRoles() {
-	manager.removeAllItems(RoleType)
+	manager.removeAllItems(context.Background(), RoleType)
 }
 
-func (manager *DefaultManager) removeAllItems(itemType ItemType) {
+func (manager *DefaultManager) removeAllItems(ctx context.Context, itemType ItemType) {
 	items := manager.getItems(itemType)
 	if len(items) == 0 {
 		return
@@ -466,21 +606,43 @@ func (manager *DefaultManager) removeAllItems(itemType ItemType) {
 	_ = manager.mapper.RemoveChildByNames(itemType, names)
 	_ = manager.mapper.RemoveAssignmentByNames(names)
 	_ = manager.mapper.RemoveItemByType(itemType)
-	manager.cache.invalidateCache()
+	manager.resetAllCache()
 	manager.loadFromCache()
+
+	operation := AuditRemoveAllRoles
+	if itemType == PermissionType {
+		operation = AuditRemoveAllPermissions
+	}
+	manager.audit(ctx, AuditEntry{Operation: operation}, auditState{Items: items}, nil)
 }
 
 func (manager *DefaultManager) RemoveAllRules() {
+	manager.removeAllRules(context.Background())
+}
+
+func (manager *DefaultManager) removeAllRules(ctx context.Context) {
+	before := manager.auditBefore(func() interface{} {
+		rules, _ := manager.mapper.GetRules()
+		return auditState{Rules: rules}
+	})
 	_ = manager.mapper.RemoveAllRules()
-	manager.cache.invalidateCache()
+	manager.resetAllCache()
 	manager.loadFromCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveAllRules}, before, nil)
 }
 
 func (manager *DefaultManager) RemoveAllAssignments() {
-	manager.mu.Lock()
-	manager._checkAccessAssignments = make(map[interface{}]map[string]*Assignment)
-	manager.mu.Unlock()
+	manager.removeAllAssignments(context.Background())
+}
+
+func (manager *DefaultManager) removeAllAssignments(ctx context.Context) {
+	before := manager.auditBefore(func() interface{} {
+		assignments, _ := manager.mapper.GetAllAssignment()
+		return auditState{Assignments: assignments}
+	})
 	_ = manager.mapper.RemoveAllAssignments()
+	manager.resetAllCache()
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveAllAssignments}, before, nil)
 }
 
 func (manager *DefaultManager) CheckAccess(
@@ -488,39 +650,84 @@ func (manager *DefaultManager) CheckAccess(
 	userId interface{},
 	permissionName string,
 ) bool {
+	userId = manager.userId(userId)
+	observer := manager.getObserver()
+	if observer == nil {
+		return manager.checkAccessLogging(ctx, userId, permissionName)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	ctx = observer.CheckStart(ctx, userId, permissionName)
+	allowed := manager.checkAccessLogging(ctx, userId, permissionName)
+	observer.CheckEnd(ctx, userId, permissionName, allowed, time.Since(start))
+	return allowed
+}
+
+func (manager *DefaultManager) checkAccessLogging(ctx context.Context, userId interface{}, permissionName string) bool {
+	if decisionLogger := manager.getDecisionLogger(); decisionLogger != nil {
+		if sampled, matched := decisionLogger.sample(permissionName); matched {
+			return manager.checkAccessLogged(ctx, userId, permissionName, decisionLogger, sampled)
+		}
+	}
+	return manager.checkAccess(ctx, userId, permissionName, nil)
+}
 
+// checkAccess trace 非 nil 时记录授权路径与规则执行结果（见 Explain），并跳过预计算引擎与请求级判定缓存
+func (manager *DefaultManager) checkAccess(ctx context.Context, userId interface{}, permissionName string, trace *decisionTrace) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if trace == nil {
+		if cache := DecisionCacheFromContext(ctx); cache != nil {
+			key := decisionKey(ctx, userId, permissionName)
+			allowed, ok := cache.decision(key)
+			manager.observeCache(CacheDecision, ok)
+			if ok {
+				return allowed
+			}
+			allowed = manager.decide(ctx, userId, permissionName, nil)
+			cache.storeDecision(key, allowed)
+			return allowed
+		}
+	}
+	return manager.decide(ctx, userId, permissionName, trace)
+}
+
+func (manager *DefaultManager) decide(ctx context.Context, userId interface{}, permissionName string, trace *decisionTrace) bool {
 	var assignments map[string]*Assignment
+	var version uint64
 
 	manager.mu.RLock()
 	if manager._checkAccessAssignments[userId] != nil {
 		assignments = manager._checkAccessAssignments[userId]
+		version = manager.assignmentVersion
 		manager.mu.RUnlock()
+		manager.observeCache(CacheAssignments, true)
 	} else {
 		manager.mu.RUnlock()
-
-		assignments = manager.GetAssignments(userId)
-
-		if len(assignments) > 0 {
-			manager.mu.Lock()
-			manager._checkAccessAssignments[userId] = assignments
-			manager.mu.Unlock()
-		}
+		manager.observeCache(CacheAssignments, false)
+		assignments, version = manager.loadAssignments(userId)
 	}
 
 	if manager.hasNoAssignments(assignments) {
 		return false
 	}
 
-	manager.loadFromCache()
+	snapshot := manager.loadFromCache()
 
-	manager.mu.RLock()
-	hasCache := len(manager.cache.items) > 0
-	manager.mu.RUnlock()
-
-	if hasCache {
-		return manager.checkAccessFromCache(ctx, userId, permissionName, assignments)
+	if snapshot.loaded {
+		if trace == nil && atomic.LoadInt32(&manager.compile) == 1 {
+			compiled := snapshot.compiledPolicy()
+			if allowed, decided := compiled.checkAccess(userId, permissionName, version, assignments); decided {
+				return allowed
+			}
+		}
+		return manager.checkAccessFromCache(ctx, snapshot, userId, permissionName, assignments, trace)
 	}
-	return manager.checkAccessRecursive(ctx, userId, permissionName, assignments)
+	return manager.checkAccessRecursive(ctx, snapshot, userId, permissionName, assignments, trace)
 }
 
 //func (manager *DefaultManager) CheckAccess(ctx context.Context, userId interface{}, permissionName string) bool {
@@ -547,148 +754,184 @@ func (manager *DefaultManager) CheckAccess(
 //	}
 //}
 
-func (manager *DefaultManager) loadFromCache() {
+// loadFromCache 返回当前策略快照，缓存为空时从仓库构建新快照并原子替换
+func (manager *DefaultManager) loadFromCache() *policySnapshot {
+	snapshot := manager.cache.snapshot()
 	if !manager.cache.enable {
-		logger.Warn("[rbac] load from cache skip!")
-		return
+		manager.log().Debug("[rbac] policy cache disabled, loading from repository")
+		return snapshot
 	}
 
-	manager.mu.RLock()
-	if len(manager.cache.items) > 0 {
-		manager.mu.RUnlock()
-		return
+	manager.observeCache(CachePolicy, snapshot.loaded)
+	if snapshot.loaded {
+		return snapshot
 	}
-	manager.mu.RUnlock()
 
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
+	return manager.loads.do(snapshot.generation, func() interface{} {
+		built := manager.buildObserved(snapshot.generation)
+		if built == nil {
+			return snapshot
+		}
+		return manager.cache.publish(built)
+	}).(*policySnapshot)
+}
 
-	if len(manager.cache.items) > 0 {
-		return
-	}
+// loadAssignments 读取用户分配并写入判定缓存，同一用户的并发读取只访问一次仓库。
+// 同时返回读取前的分配版本，供预计算引擎判断据此计算的授权位图是否仍然有效。
+func (manager *DefaultManager) loadAssignments(userId interface{}) (map[string]*Assignment, uint64) {
+	loaded := manager.assignmentLoads.do(userId, func() interface{} {
+		manager.mu.RLock()
+		version := manager.assignmentVersion
+		manager.mu.RUnlock()
 
-	//manager.mu.Lock()
-	//defer manager.mu.Unlock()
-	//
-	//if len(manager.cache.items) > 0 {
-	//	return
-	//}
+		assignments := manager.GetAssignments(userId)
 
-	manager.cache.invalidateCache()
+		if len(assignments) > 0 {
+			manager.mu.Lock()
+			// 读取期间发生过分配变更则不写缓存，避免覆盖成旧数据
+			if manager.assignmentVersion == version {
+				manager._checkAccessAssignments[userId] = assignments
+			}
+			manager.mu.Unlock()
+		}
+		return &loadedAssignments{assignments: assignments, version: version}
+	}).(*loadedAssignments)
+	return loaded.assignments, loaded.version
+}
+
+type loadedAssignments struct {
+	assignments map[string]*Assignment
+	version     uint64
+}
+
+func (manager *DefaultManager) buildSnapshot(generation uint64) (*policySnapshot, error) {
+	snapshot := &policySnapshot{
+		generation: generation,
+		loaded:     true,
+		items:      make(map[string]Item),
+		rules:      make(map[string]*Rule),
+		parents:    make(map[string][]string),
+		children:   make(map[string][]string),
+	}
 
 	rules, err2 := manager.mapper.GetRules()
 	if err2 == nil {
 		for _, rule := range rules {
-			manager.cache.rules[rule.Name] = NewRule(rule.Name, rule.ExecuteName, rule.CreateTime, rule.UpdateTime)
+			copied := *rule
+			snapshot.rules[rule.Name] = &copied
 		}
 	}
 
 	authItems, err := manager.mapper.FindAllItems()
 	if err != nil {
-		logger.Warnf("[rbac] LoadFromCache [findAllItems err] = %v", err)
-		return
+		manager.log().Error("[rbac] load policy failed", "step", "FindAllItems", "err", err)
+		return nil, err
 	}
 
 	for _, item := range authItems {
-		manager.cache.items[item.GetName()] = item
+		snapshot.items[item.GetName()] = item
 	}
 
 	authItemChildren, err := manager.mapper.FindChildrenList()
 	if err != nil {
-		logger.Warnf("[rbac] LoadFromCache [FindChildrenList err] = %v", err)
-		return
+		manager.log().Error("[rbac] load policy failed", "step", "FindChildrenList", "err", err)
+		return nil, err
 	}
 
 	for _, authItemChild := range authItemChildren {
 		child := authItemChild.Child
-		if manager.cache.items[child] != nil {
-			if manager.cache.parents[child] == nil {
-				manager.cache.parents[child] = make([]string, 0)
-			}
-			manager.cache.parents[child] = append(manager.cache.parents[child], authItemChild.Parent)
+		if snapshot.items[child] != nil {
+			snapshot.parents[child] = append(snapshot.parents[child], authItemChild.Parent)
+			snapshot.children[authItemChild.Parent] = append(snapshot.children[authItemChild.Parent], child)
 		}
 	}
+
+	return snapshot, nil
 }
 
-func (manager *DefaultManager) checkAccessFromCache(ctx context.Context, userId interface{}, itemName string, assignments map[string]*Assignment) bool {
-	if manager.cache.items[itemName] == nil {
+func (manager *DefaultManager) checkAccessFromCache(ctx context.Context, snapshot *policySnapshot, userId interface{}, itemName string, assignments map[string]*Assignment, trace *decisionTrace) bool {
+	item := snapshot.items[itemName]
+	if item == nil {
 		return false
 	}
 
-	manager.mu.RLock()
-	item := manager.cache.items[itemName]
-	manager.mu.RUnlock()
+	if allowed, handled := manager.checkRule(ctx, userId, item, snapshot.rule, trace); handled {
+		trace.grant(itemName, allowed)
+		return allowed
+	}
 
-	if item.GetExecuteName() != "" {
-		executor := ExecuteManager.GetExecutor(item.GetExecuteName())
-		if executor != nil {
-			return executor.Execute(ctx, userId, item)
+	if assignments[itemName] != nil || snapshot.defaultRoles[itemName] != nil {
+		trace.grant(itemName, true)
+		return true
+	}
+
+	for _, parent := range snapshot.parents[itemName] {
+		if manager.checkAccessFromCache(ctx, snapshot, userId, parent, assignments, trace) {
+			trace.grant(itemName, true)
+			return true
 		}
 	}
 
+	return false
+}
+
+// checkRule 执行 item 绑定的执行器或规则，rules 按名称查找规则（快照或仓库）。
+// handled 为 false 表示 item 没有生效的执行器，继续按分配与继承关系判定。
+func (manager *DefaultManager) checkRule(ctx context.Context, userId interface{}, item Item, rules func(name string) *Rule, trace *decisionTrace) (allowed bool, handled bool) {
+	if item.GetExecuteName() != "" {
+		return manager.decideRule(item, "", manager.runExecutor(ctx, userId, item, nil, item.GetExecuteName(), trace)), true
+	}
+
 	if item.GetRuleName() != "" {
-		rule := manager.GetRule(item.GetRuleName())
+		rule := rules(item.GetRuleName())
 		if rule == nil {
-			logger.Warnf("the rule named '%s' does not exist.", item.GetRuleName())
-			return false
+			manager.log().Warn("[rbac] rule does not exist", "item", item.GetName(), "rule", item.GetRuleName())
+			trace.rule(RuleOutcome{Item: item.GetName(), Rule: item.GetRuleName(), Outcome: OutcomeMissing, Error: "rule does not exist"})
+			return false, true
 		}
-		executor := rule.GetExecutor()
-		if executor != nil {
-			return executor.Execute(ctx, userId, item)
+		result, handled := manager.executeRule(ctx, userId, item, rule, rules, 0, trace)
+		if !handled {
+			return false, false
 		}
+		return manager.decideRule(item, rule.Name, result), true
 	}
 
-	if assignments[itemName] != nil || manager.defaultRoles[itemName] != nil {
-		return true
-	}
-
-	manager.mu.RLock()
-	parents := manager.cache.parents[itemName]
-	manager.mu.RUnlock()
+	return false, false
+}
 
-	for _, parent := range parents {
-		if manager.checkAccessFromCache(ctx, userId, parent, assignments) {
-			return true
-		}
+// executeRule 规则未配置执行器时 handled 为 false
+func (manager *DefaultManager) executeRule(ctx context.Context, userId interface{}, item Item, rule *Rule, rules func(name string) *Rule, depth int, trace *decisionTrace) (result ruleResult, handled bool) {
+	if IsCompositeRule(rule) {
+		return manager.executeComposite(ctx, userId, item, rule, rules, depth, trace), true
 	}
-
-	return false
+	if rule.ExecuteName == "" {
+		return ruleDenied, false
+	}
+	return manager.runExecutor(ctx, userId, item, rule, rule.ExecuteName, trace), true
 }
 
-func (manager *DefaultManager) checkAccessRecursive(ctx context.Context, userId interface{}, itemName string, assignments map[string]*Assignment) bool {
+func (manager *DefaultManager) checkAccessRecursive(ctx context.Context, snapshot *policySnapshot, userId interface{}, itemName string, assignments map[string]*Assignment, trace *decisionTrace) bool {
 
 	item, err2 := manager.mapper.GetItem(itemName)
 	if err2 != nil {
-		logger.Warnf("the item named '%s' does not exist.", itemName)
+		manager.log().Debug("[rbac] item does not exist", "item", itemName, "err", err2)
 		return false
 	}
 
-	if item.GetExecuteName() != "" {
-		executor := ExecuteManager.GetExecutor(item.GetExecuteName())
-		if executor != nil {
-			return executor.Execute(ctx, userId, item)
-		}
+	if allowed, handled := manager.checkRule(ctx, userId, item, manager.GetRule, trace); handled {
+		trace.grant(itemName, allowed)
+		return allowed
 	}
 
-	if item.GetRuleName() != "" {
-		rule := manager.GetRule(item.GetRuleName())
-		if rule == nil {
-			logger.Warnf("the rule named '%s' does not exist.", item.GetRuleName())
-			return false
-		}
-		executor := rule.GetExecutor()
-		if executor != nil {
-			return executor.Execute(ctx, userId, item)
-		}
-	}
-
-	if assignments[itemName] != nil || manager.defaultRoles[itemName] != nil {
+	if assignments[itemName] != nil || snapshot.defaultRoles[itemName] != nil {
+		trace.grant(itemName, true)
 		return true
 	}
 
 	if authChildren, err := manager.mapper.FindChildrenFormChild(itemName); err == nil {
 		for _, authChild := range authChildren {
-			if manager.checkAccessRecursive(ctx, userId, authChild.Parent, assignments) {
+			if manager.checkAccessRecursive(ctx, snapshot, userId, authChild.Parent, assignments, trace) {
+				trace.grant(itemName, true)
 				return true
 			}
 		}
@@ -706,27 +949,49 @@ func (manager *DefaultManager) CreatePermission(name string) *Permission {
 }
 
 func (manager *DefaultManager) Add(item Item) bool {
+	return manager.add(context.Background(), item)
+}
+
+func (manager *DefaultManager) add(ctx context.Context, item Item) bool {
 	// TODO if the rule of the object is not alive in the system, then to create it to the system
-	manager.checkRuleExits(item.GetRuleName())
-	return manager.addItem(item)
+	manager.checkRuleExits(ctx, item.GetRuleName())
+	if !manager.addItem(item) {
+		return false
+	}
+	manager.audit(ctx, AuditEntry{Operation: AuditAddItem, Item: item.GetName()}, nil, item)
+	return true
 }
 
 func (manager *DefaultManager) Remove(item Item) bool {
-	return manager.removeItem(item)
+	return manager.remove(context.Background(), item)
 }
 
-func (manager *DefaultManager) RemoveAllAssignmentByUser(userId interface{}) error {
-	err := manager.mapper.RemoveAllAssignmentByUser(userId)
-	if err == nil {
-		manager.resetAllCache()
+func (manager *DefaultManager) remove(ctx context.Context, item Item) bool {
+	before := manager.auditBefore(func() interface{} { return manager.GetItem(item.GetName()) })
+	if !manager.removeItem(item) {
+		return false
 	}
-	return err
+	manager.audit(ctx, AuditEntry{Operation: AuditRemoveItem, Item: item.GetName()}, before, nil)
+	return true
+}
+
+func (manager *DefaultManager) RemoveAllAssignmentByUser(userId interface{}) error {
+	return manager.revokeAll(context.Background(), userId)
 }
 
 func (manager *DefaultManager) Update(name string, item Item) bool {
+	return manager.update(context.Background(), name, item)
+}
+
+func (manager *DefaultManager) update(ctx context.Context, name string, item Item) bool {
 	// TODO if the rule of the object is not alive in the system, then to create it to the system
-	manager.checkRuleExits(item.GetRuleName())
-	return manager.updateItem(name, item)
+	manager.checkRuleExits(ctx, item.GetRuleName())
+	before := manager.auditBefore(func() interface{} { return manager.GetItem(name) })
+	if !manager.updateItem(name, item) {
+		return false
+	}
+	manager.audit(ctx, AuditEntry{Operation: AuditUpdateItem, Item: name}, before, item)
+	return true
 }
 
 func (manager *DefaultManager) GetRole(name string) *Role {
@@ -775,45 +1040,73 @@ func (manager *DefaultManager) GetPermissions() []*Permission {
 	return permissions
 }
 
-func (manager *DefaultManager) checkRuleExits(name string) {
+func (manager *DefaultManager) checkRuleExits(ctx context.Context, name string) {
 	if name != "" && manager.GetRule(name) == nil {
 		rule := NewRule(name, "", time.Now(), time.Now())
-		manager.AddRule(*rule)
+		manager.addRule(ctx, *rule)
 	}
 }
 
 func (manager *DefaultManager) SetDefaultRoles(roles ...*Role) {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
-	for _, role := range roles {
-		manager.defaultRoles[role.GetName()] = role
-	}
+	manager.setDefaultRoles(context.Background(), roles)
+}
+
+func (manager *DefaultManager) setDefaultRoles(ctx context.Context, roles []*Role) {
+	before := manager.auditBefore(func() interface{} { return roleNames(manager.GetDefaultRoles()) })
+	manager.cache.setDefaultRoles(roles, false)
+	manager.audit(ctx, AuditEntry{Operation: AuditSetDefaultRoles}, before, roleNames(roles))
 }
 
 func (manager *DefaultManager) GetDefaultRoles() []*Role {
-	manager.mu.RLock()
-	defer manager.mu.RUnlock()
-	roles := make([]*Role, 0, len(manager.defaultRoles))
-	for _, role := range manager.defaultRoles {
+	defaultRoles := manager.cache.snapshot().defaultRoles
+	roles := make([]*Role, 0, len(defaultRoles))
+	for _, role := range defaultRoles {
 		roles = append(roles, role)
 	}
 	return roles
 }
 
 func (manager *DefaultManager) hasNoAssignments(assignments map[string]*Assignment) bool {
-	//return len(assignments) == 0 && len(manager.defaultRoles) == 0
-	manager.mu.RLock()
-	defer manager.mu.RUnlock()
-	return len(assignments) == 0 && len(manager.defaultRoles) == 0
+	return len(assignments) == 0 && len(manager.cache.snapshot().defaultRoles) == 0
 }
 
 func (manager *DefaultManager) resetAllCache() {
 	manager.mu.Lock()
-	defer manager.mu.Unlock()
-
 	// 清用户权限判定缓存
 	manager._checkAccessAssignments = make(map[interface{}]map[string]*Assignment)
+	manager.assignmentVersion++
+	manager.mu.Unlock()
+
+	// 后台刷新开启时保留旧快照继续服务，由刷新协程重建后替换
+	if manager.refreshing() {
+		if compiled := manager.cache.snapshot().peekCompiled(); compiled != nil {
+			compiled.forgetAll()
+		}
+		manager.Refresh()
+		return
+	}
 
 	// 清 RBAC 结构缓存
 	manager.cache.invalidateCache()
 }
+
+// forgetUser 清除用户的分配缓存与授权位图，须在仓库写入之后调用（写入失败也调用，写入可能已部分生效）
+func (manager *DefaultManager) forgetUser(userId interface{}) {
+	manager.mu.Lock()
+	delete(manager._checkAccessAssignments, userId)
+	manager.assignmentVersion++
+	manager.mu.Unlock()
+	if compiled := manager.cache.snapshot().peekCompiled(); compiled != nil {
+		compiled.forget(userId)
+	}
+}
+
+// EnableCompiled 开启预计算的权限判定引擎，仅在开启缓存时生效。
+// 不涉及规则的校验直接查位图，涉及规则的 item 仍回退到递归遍历。
+func (manager *DefaultManager) EnableCompiled(enable bool) {
+	var flag int32
+	if enable {
+		flag = 1
+	}
+	atomic.StoreInt32(&manager.compile, flag)
+}
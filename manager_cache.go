@@ -1,25 +1,80 @@
 package gorbac
 
-type DefaultCache struct {
-	enable bool
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// policySnapshot 不可变的策略快照。
+// 快照一旦发布就不再修改：读者通过原子指针无锁读取，写者构建新快照后整体替换。
+type policySnapshot struct {
+	generation uint64
+	// loaded reports whether items, rules and parents were read from the repository
+	loaded bool
 	// all auth items (name => Item)
 	items map[string]Item
 	// all auth rules (name => Rule)
 	rules map[string]*Rule
 	// auth item parent-child relationships (childName => list of parents)
 	parents map[string][]string
+	// auth item parent-child relationships (parentName => list of children)
+	children map[string][]string
+	// a list of role names that are assigned to every user automatically without calling [[assign()]].
+	defaultRoles map[string]*Role
+
+	compileMu sync.Mutex
+	compiled  atomic.Value // *compiledPolicy
+}
+
+// compiledPolicy 返回快照对应的预计算引擎，首次调用时构建
+func (snapshot *policySnapshot) compiledPolicy() *compiledPolicy {
+	if compiled := snapshot.peekCompiled(); compiled != nil {
+		return compiled
+	}
+	snapshot.compileMu.Lock()
+	defer snapshot.compileMu.Unlock()
+	if compiled := snapshot.peekCompiled(); compiled != nil {
+		return compiled
+	}
+	compiled := compilePolicy(snapshot.generation, snapshot.items, snapshot.parents, snapshot.defaultRoles)
+	snapshot.compiled.Store(compiled)
+	return compiled
+}
+
+func (snapshot *policySnapshot) rule(name string) *Rule {
+	return snapshot.rules[name]
+}
+
+func (snapshot *policySnapshot) peekCompiled() *compiledPolicy {
+	compiled, _ := snapshot.compiled.Load().(*compiledPolicy)
+	return compiled
+}
+
+type DefaultCache struct {
+	enable  bool
+	current atomic.Value // *policySnapshot
+	// mu serialises writers, readers never take it
+	mu sync.Mutex
 }
 
 func NewDefaultCache(cache bool) *DefaultCache {
-	return &DefaultCache{enable: cache}
+	defaultCache := &DefaultCache{enable: cache}
+	defaultCache.current.Store(&policySnapshot{defaultRoles: make(map[string]*Role)})
+	return defaultCache
+}
+
+func (manager *DefaultCache) snapshot() *policySnapshot {
+	return manager.current.Load().(*policySnapshot)
 }
 
 func (manager *DefaultCache) invalidateCache() {
-	if manager.enable {
-		manager.items = make(map[string]Item)
-		manager.rules = make(map[string]*Rule)
-		manager.parents = make(map[string][]string)
-	}
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	previous := manager.snapshot()
+	manager.current.Store(&policySnapshot{
+		generation:   previous.generation + 1,
+		defaultRoles: previous.defaultRoles,
+	})
 }
 
 func (manager *DefaultCache) refreshInvalidateCache(operator bool) bool {
@@ -30,44 +85,88 @@ func (manager *DefaultCache) refreshInvalidateCache(operator bool) bool {
 	return false
 }
 
+// publish 发布 loader 构建的快照；若构建期间发生过写入（代数变化）则放弃，返回当前快照
+func (manager *DefaultCache) publish(snapshot *policySnapshot) *policySnapshot {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	previous := manager.snapshot()
+	if previous.generation != snapshot.generation || previous.loaded {
+		return previous
+	}
+	snapshot.defaultRoles = previous.defaultRoles
+	manager.current.Store(snapshot)
+	return snapshot
+}
+
+// replace 无条件发布后台刷新构建的快照
+func (manager *DefaultCache) replace(snapshot *policySnapshot) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	previous := manager.snapshot()
+	snapshot.generation = previous.generation + 1
+	snapshot.defaultRoles = previous.defaultRoles
+	manager.current.Store(snapshot)
+}
+
+// setDefaultRoles replace 为 true 时替换全部默认角色，否则追加
+func (manager *DefaultCache) setDefaultRoles(roles []*Role, replace bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	previous := manager.snapshot()
+	defaultRoles := make(map[string]*Role, len(previous.defaultRoles)+len(roles))
+	if !replace {
+		for name, role := range previous.defaultRoles {
+			defaultRoles[name] = role
+		}
+	}
+	for _, role := range roles {
+		defaultRoles[role.GetName()] = role
+	}
+	manager.current.Store(&policySnapshot{
+		generation:   previous.generation + 1,
+		loaded:       previous.loaded,
+		items:        previous.items,
+		rules:        previous.rules,
+		parents:      previous.parents,
+		children:     previous.children,
+		defaultRoles: defaultRoles,
+	})
+}
+
 func (manager *DefaultCache) GetItem(name string, f func(n string) Item) Item {
 	if name == "" {
 		return nil
 	}
-	if len(manager.items) > 0 {
-		return manager.items[name]
+	if snapshot := manager.snapshot(); snapshot.loaded {
+		return snapshot.items[name]
 	}
 	return f(name)
 }
 
 func (manager *DefaultCache) GetRule(name string, f func(n string) *Rule) *Rule {
-	if manager.rules == nil {
-		return nil
+	snapshot := manager.snapshot()
+	if !snapshot.loaded {
+		return f(name)
 	}
-	rule := f(name)
+	rule := snapshot.rules[name]
 	if rule == nil {
 		return nil
 	}
-	manager.rules[name] = rule
-	return manager.rules[name]
+	// 返回副本，保持快照不可变
+	copied := *rule
+	return &copied
 }
 
 func (manager *DefaultCache) GetRules(f func() []*Rule) []*Rule {
-	if manager.rules == nil {
-		return make([]*Rule, 0)
-	}
-
-	if len(manager.rules) == 0 {
-		rules := f()
-		for _, rule := range rules {
-			manager.rules[rule.Name] = rule
-		}
-		return rules
+	snapshot := manager.snapshot()
+	if !snapshot.loaded {
+		return f()
 	}
 
-	rules := make([]*Rule, 0, len(manager.rules))
-	for _, rule := range manager.rules {
-		rules = append(rules, rule)
+	rules := make([]*Rule, 0, len(snapshot.rules))
+	for _, rule := range snapshot.rules {
+		copied := *rule
+		rules = append(rules, &copied)
 	}
 	return rules
 }
@@ -0,0 +1,83 @@
+package gorbac
+
+import (
+	"context"
+	"time"
+)
+
+// StartRefresher 启动后台刷新协程，按 interval 周期（<=0 表示不定时）或收到 Refresh 通知时
+// 从仓库重建策略快照并原子替换，ctx 结束时退出。启动时同步预热一次，之后的 CheckAccess
+// 不会再因为缓存为空而阻塞；本管理器上的写操作也只会触发重建，重建完成前继续使用旧快照。
+// 仅在开启缓存时生效，重复调用无效。
+func (manager *DefaultManager) StartRefresher(ctx context.Context, interval time.Duration) {
+	if !manager.cache.enable {
+		return
+	}
+
+	manager.refreshMu.Lock()
+	if manager.refreshEvents != nil {
+		manager.refreshMu.Unlock()
+		return
+	}
+	events := make(chan struct{}, 1)
+	manager.refreshEvents = events
+	manager.refreshMu.Unlock()
+
+	manager.rebuild()
+
+	go func() {
+		defer func() {
+			manager.refreshMu.Lock()
+			manager.refreshEvents = nil
+			manager.refreshMu.Unlock()
+		}()
+
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			case <-events:
+			}
+			manager.rebuild()
+		}
+	}()
+}
+
+// Refresh 通知策略已变更（例如其他进程修改了仓库）。
+// 后台刷新开启时异步重建快照，否则直接失效缓存，下一次校验时重新加载。
+func (manager *DefaultManager) Refresh() {
+	manager.refreshMu.Lock()
+	events := manager.refreshEvents
+	manager.refreshMu.Unlock()
+
+	if events == nil {
+		manager.cache.invalidateCache()
+		return
+	}
+
+	select {
+	case events <- struct{}{}:
+	default:
+		// 已有待处理的刷新，合并
+	}
+}
+
+func (manager *DefaultManager) refreshing() bool {
+	manager.refreshMu.Lock()
+	defer manager.refreshMu.Unlock()
+	return manager.refreshEvents != nil
+}
+
+func (manager *DefaultManager) rebuild() {
+	if built := manager.buildObserved(0); built != nil {
+		manager.cache.replace(built)
+	}
+}
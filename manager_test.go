@@ -0,0 +1,227 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestManager 创建基于 MemoryRepository 的管理器：
+// admin -> editor -> posts:edit，admin -> viewer -> posts:view，用户 1 分配 admin
+func newTestManager(t *testing.T, cache bool) *DefaultManager {
+	t.Helper()
+	return newTestManagerWith(t, NewMemoryRepository(), cache)
+}
+
+func newTestManagerWith(t *testing.T, repo AuthRepository, cache bool) *DefaultManager {
+	t.Helper()
+	now := time.Now()
+	manager := NewDefaultManager(repo, cache)
+	admin := NewRole("admin", "", "", "", now, now)
+	editor := NewRole("editor", "", "", "", now, now)
+	viewer := NewRole("viewer", "", "", "", now, now)
+	edit := NewPermission("posts:edit", "", "", "", now, now)
+	view := NewPermission("posts:view", "", "", "", now, now)
+	for _, item := range []Item{admin, editor, viewer, edit, view} {
+		if !manager.Add(item) {
+			t.Fatalf("add %s failed", item.GetName())
+		}
+	}
+	for _, pair := range [][2]Item{{admin, editor}, {admin, viewer}, {editor, edit}, {viewer, view}} {
+		if err := manager.AddChild(pair[0], pair[1]); err != nil {
+			t.Fatalf("add child %s -> %s: %v", pair[0].GetName(), pair[1].GetName(), err)
+		}
+	}
+	if manager.Assign(admin, 1) == nil {
+		t.Fatal("assign admin failed")
+	}
+	return manager
+}
+
+func childNames(manager *DefaultManager, name string) []string {
+	names := make([]string, 0)
+	for _, item := range manager.GetChildren(name) {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRemoveChildKeepsSiblings(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		manager := newTestManager(t, cache)
+		ctx := context.Background()
+		if !manager.CheckAccess(ctx, 1, "posts:edit") || !manager.CheckAccess(ctx, 1, "posts:view") {
+			t.Fatalf("cache=%v: expected access before RemoveChild", cache)
+		}
+
+		admin := NewRole("admin", "", "", "", time.Now(), time.Now())
+		editor := NewRole("editor", "", "", "", time.Now(), time.Now())
+		if !manager.RemoveChild(admin, editor) {
+			t.Fatalf("cache=%v: RemoveChild failed", cache)
+		}
+
+		if got := childNames(manager, "admin"); len(got) != 1 || got[0] != "viewer" {
+			t.Errorf("cache=%v: children of admin = %v, want [viewer]", cache, got)
+		}
+		if manager.CheckAccess(ctx, 1, "posts:edit") {
+			t.Errorf("cache=%v: posts:edit still allowed after RemoveChild", cache)
+		}
+		if !manager.CheckAccess(ctx, 1, "posts:view") {
+			t.Errorf("cache=%v: posts:view denied after removing a sibling", cache)
+		}
+	}
+}
+
+func TestRemoveChildrenResetsCache(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		manager := newTestManager(t, cache)
+		ctx := context.Background()
+		if !manager.CheckAccess(ctx, 1, "posts:edit") {
+			t.Fatalf("cache=%v: expected access before RemoveChildren", cache)
+		}
+
+		if !manager.RemoveChildren(NewRole("admin", "", "", "", time.Now(), time.Now())) {
+			t.Fatalf("cache=%v: RemoveChildren failed", cache)
+		}
+
+		if got := childNames(manager, "admin"); len(got) != 0 {
+			t.Errorf("cache=%v: children of admin = %v, want none", cache, got)
+		}
+		for _, permission := range []string{"posts:edit", "posts:view"} {
+			if manager.CheckAccess(ctx, 1, permission) {
+				t.Errorf("cache=%v: %s still allowed after RemoveChildren", cache, permission)
+			}
+		}
+	}
+}
+
+// hookRepository 在分配写入之前调用 beforeWrite，模拟与写入并发的校验
+type hookRepository struct {
+	*MemoryRepository
+	beforeWrite func()
+}
+
+func (repo *hookRepository) Assign(assignment Assignment) error {
+	repo.beforeWrite()
+	return repo.MemoryRepository.Assign(assignment)
+}
+
+func (repo *hookRepository) Assigns(assignments ...*Assignment) error {
+	repo.beforeWrite()
+	return repo.MemoryRepository.Assigns(assignments...)
+}
+
+func (repo *hookRepository) RemoveAssignment(userId interface{}, name string) error {
+	repo.beforeWrite()
+	return repo.MemoryRepository.RemoveAssignment(userId, name)
+}
+
+func (repo *hookRepository) RemoveAllAssignmentByUser(userId interface{}) error {
+	repo.beforeWrite()
+	return repo.MemoryRepository.RemoveAllAssignmentByUser(userId)
+}
+
+func TestAssignmentWriteInvalidatesAfterWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		userId  int
+		mutate  func(manager *DefaultManager)
+		allowed bool
+	}{
+		{"Assign", 2, func(manager *DefaultManager) { manager.Assign(manager.GetRole("editor"), 2) }, true},
+		{"Assigns", 2, func(manager *DefaultManager) { manager.Assigns(2, "editor") }, true},
+		{"Revoke", 1, func(manager *DefaultManager) { manager.Revoke(manager.GetRole("admin"), 1) }, false},
+		{"RevokeAll", 1, func(manager *DefaultManager) { manager.RevokeAll(1) }, false},
+		{"RemoveAllAssignmentByUser", 1, func(manager *DefaultManager) { _ = manager.RemoveAllAssignmentByUser(1) }, false},
+	}
+	for _, tt := range tests {
+		for _, compiled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/compiled=%v", tt.name, compiled), func(t *testing.T) {
+				ctx := context.Background()
+				repo := &hookRepository{MemoryRepository: NewMemoryRepository(), beforeWrite: func() {}}
+				manager := newTestManagerWith(t, repo, true)
+				manager.EnableCompiled(compiled)
+				manager.Assign(manager.GetRole("viewer"), 2)
+
+				// 写入前的校验读到旧分配并写入缓存，写入完成后缓存必须失效
+				repo.beforeWrite = func() { manager.CheckAccess(ctx, tt.userId, "posts:edit") }
+				tt.mutate(manager)
+				repo.beforeWrite = func() {}
+
+				if got := manager.CheckAccess(ctx, tt.userId, "posts:edit"); got != tt.allowed {
+					t.Errorf("CheckAccess after %s = %v, want %v", tt.name, got, tt.allowed)
+				}
+			})
+		}
+	}
+}
+
+// 用 -race 运行：校验与写入、resetAllCache 并发时读到的快照始终完整
+func TestCheckAccessConcurrentWrites(t *testing.T) {
+	for _, compiled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compiled=%v", compiled), func(t *testing.T) {
+			manager := newTestManager(t, true)
+			manager.EnableCompiled(compiled)
+			ctx := context.Background()
+			now := time.Now()
+			viewer := manager.GetRole("viewer")
+			editor := manager.GetRole("editor")
+
+			const rounds = 200
+			var wg sync.WaitGroup
+			done := make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(done)
+				for i := 0; i < rounds; i++ {
+					draft := NewPermission(fmt.Sprintf("posts:draft-%d", i), "", "", "", now, now)
+					manager.Add(draft)
+					_ = manager.AddChild(viewer, draft)
+					manager.Assign(editor, 2)
+					manager.resetAllCache()
+					manager.Revoke(editor, 2)
+					_ = manager.RemoveChild(viewer, draft)
+					manager.Remove(draft)
+				}
+			}()
+			for reader := 0; reader < 4; reader++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						// 用户 1 的授权路径不受写入影响
+						if !manager.CheckAccess(ctx, 1, "posts:edit") || !manager.CheckAccess(ctx, 1, "posts:view") {
+							t.Error("user 1 lost access during concurrent writes")
+							return
+						}
+						if manager.CheckAccess(ctx, 2, "posts:view") {
+							t.Error("user 2 gained posts:view that is never granted")
+							return
+						}
+						manager.CheckAccess(ctx, 2, "posts:edit")
+						manager.CheckAccess(ctx, 1, "posts:draft-0")
+					}
+				}()
+			}
+			wg.Wait()
+
+			// 写入结束后的校验反映最终状态
+			if manager.CheckAccess(ctx, 2, "posts:edit") {
+				t.Error("user 2 still has posts:edit after the final Revoke")
+			}
+			if manager.CheckAccess(ctx, 1, "posts:draft-0") {
+				t.Error("removed permission still granted")
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package gorbac
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryRepository 内存实现的 AuthRepository，适合测试、单机场景以及作为快照导入的目标。
+// 用户 ID 按 == 比较，1 与 int64(1) 视为不同用户。
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	txMu        sync.Mutex
+	items       map[string]Item
+	rules       map[string]*Rule
+	children    []*ItemChild
+	assignments []*Assignment
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{items: make(map[string]Item), rules: make(map[string]*Rule)}
+}
+
+// Transaction fn 返回错误时恢复到执行前的数据。事务之间串行执行，事务期间其他非事务写入在回滚时会一并丢失。
+func (repo *MemoryRepository) Transaction(fn func(repo AuthRepository) error) error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	repo.mu.RLock()
+	items := make(map[string]Item, len(repo.items))
+	for name, item := range repo.items {
+		items[name] = item
+	}
+	rules := make(map[string]*Rule, len(repo.rules))
+	for name, rule := range repo.rules {
+		rules[name] = rule
+	}
+	children := append([]*ItemChild(nil), repo.children...)
+	assignments := append([]*Assignment(nil), repo.assignments...)
+	repo.mu.RUnlock()
+
+	if err := fn(repo); err != nil {
+		repo.mu.Lock()
+		repo.items, repo.rules, repo.children, repo.assignments = items, rules, children, assignments
+		repo.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (repo *MemoryRepository) AddItem(item Item) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.items[item.GetName()] != nil {
+		return fmt.Errorf("item '%s' already exists", item.GetName())
+	}
+	repo.items[item.GetName()] = item
+	return nil
+}
+
+func (repo *MemoryRepository) GetItem(name string) (Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	if item := repo.items[name]; item != nil {
+		return item, nil
+	}
+	return nil, fmt.Errorf("item '%s' not found", name)
+}
+
+func (repo *MemoryRepository) GetItemsByType(itemType ItemType) ([]Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.sortedItems(func(item Item) bool { return item.GetType() == itemType }), nil
+}
+
+func (repo *MemoryRepository) RemoveItemByType(itemType ItemType) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for name, item := range repo.items {
+		if item.GetType() == itemType {
+			delete(repo.items, name)
+		}
+	}
+	return nil
+}
+
+func (repo *MemoryRepository) FindAllItems() ([]Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.sortedItems(func(item Item) bool { return true }), nil
+}
+
+func (repo *MemoryRepository) AddRule(rule Rule) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.rules[rule.Name] != nil {
+		return fmt.Errorf("rule '%s' already exists", rule.Name)
+	}
+	repo.rules[rule.Name] = &rule
+	return nil
+}
+
+func (repo *MemoryRepository) GetRule(name string) (*Rule, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	if rule := repo.rules[name]; rule != nil {
+		copied := *rule
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("rule '%s' not found", name)
+}
+
+func (repo *MemoryRepository) GetRules() ([]*Rule, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	rules := make([]*Rule, 0, len(repo.rules))
+	for _, rule := range repo.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// RemoveItem 同时删除 item 的继承关系与分配
+func (repo *MemoryRepository) RemoveItem(name string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	delete(repo.items, name)
+	repo.filterChildren(func(itemChild *ItemChild) bool { return itemChild.Parent != name && itemChild.Child != name })
+	repo.filterAssignments(func(assignment *Assignment) bool { return assignment.ItemName != name })
+	return nil
+}
+
+// RemoveRule 同时清除引用该规则的 item 的规则名
+func (repo *MemoryRepository) RemoveRule(ruleName string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	delete(repo.rules, ruleName)
+	for name, item := range repo.items {
+		if item.GetRuleName() == ruleName {
+			repo.items[name] = withRuleName(item, "")
+		}
+	}
+	return nil
+}
+
+// UpdateItem 改名时同步更新继承关系与分配
+func (repo *MemoryRepository) UpdateItem(itemName string, item Item) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.items[itemName] == nil {
+		return fmt.Errorf("item '%s' not found", itemName)
+	}
+	newName := item.GetName()
+	if newName != itemName {
+		if repo.items[newName] != nil {
+			return fmt.Errorf("item '%s' already exists", newName)
+		}
+		// 替换而不是原地修改，事务回滚时保留的旧切片不受影响
+		children := make([]*ItemChild, 0, len(repo.children))
+		for _, itemChild := range repo.children {
+			renamed := NewItemChild(itemChild.Parent, itemChild.Child)
+			if renamed.Parent == itemName {
+				renamed.Parent = newName
+			}
+			if renamed.Child == itemName {
+				renamed.Child = newName
+			}
+			children = append(children, renamed)
+		}
+		repo.children = children
+		assignments := make([]*Assignment, 0, len(repo.assignments))
+		for _, assignment := range repo.assignments {
+			renamed := *assignment
+			if renamed.ItemName == itemName {
+				renamed.ItemName = newName
+			}
+			assignments = append(assignments, &renamed)
+		}
+		repo.assignments = assignments
+		delete(repo.items, itemName)
+	}
+	repo.items[newName] = item
+	return nil
+}
+
+// UpdateRule 改名时同步更新引用该规则的 item
+func (repo *MemoryRepository) UpdateRule(ruleName string, rule Rule) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.rules[ruleName] == nil {
+		return fmt.Errorf("rule '%s' not found", ruleName)
+	}
+	if rule.Name != ruleName {
+		if repo.rules[rule.Name] != nil {
+			return fmt.Errorf("rule '%s' already exists", rule.Name)
+		}
+		for name, item := range repo.items {
+			if item.GetRuleName() == ruleName {
+				repo.items[name] = withRuleName(item, rule.Name)
+			}
+		}
+		delete(repo.rules, ruleName)
+	}
+	repo.rules[rule.Name] = &rule
+	return nil
+}
+
+func (repo *MemoryRepository) FindRolesByUser(userId interface{}) ([]Item, error) {
+	return repo.findItemsByUser(userId, RoleType), nil
+}
+
+func (repo *MemoryRepository) FindChildrenList() ([]*ItemChild, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	list := make([]*ItemChild, 0, len(repo.children))
+	for _, itemChild := range repo.children {
+		list = append(list, NewItemChild(itemChild.Parent, itemChild.Child))
+	}
+	return list, nil
+}
+
+func (repo *MemoryRepository) FindChildrenFormChild(child string) ([]*ItemChild, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	list := make([]*ItemChild, 0)
+	for _, itemChild := range repo.children {
+		if itemChild.Child == child {
+			list = append(list, NewItemChild(itemChild.Parent, itemChild.Child))
+		}
+	}
+	return list, nil
+}
+
+func (repo *MemoryRepository) GetItemList(t int32, names []string) ([]Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	list := make([]Item, 0, len(names))
+	for _, name := range names {
+		if item := repo.items[name]; item != nil && item.GetType().Value() == t {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+func (repo *MemoryRepository) FindPermissionsByUser(userId interface{}) ([]Item, error) {
+	return repo.findItemsByUser(userId, PermissionType), nil
+}
+
+func (repo *MemoryRepository) FindAssignmentsByUser(userId interface{}) ([]*Assignment, error) {
+	return repo.GetAssignments(userId)
+}
+
+func (repo *MemoryRepository) AddItemChild(itemChild ItemChild) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.hasChild(itemChild.Parent, itemChild.Child) {
+		return fmt.Errorf("child '%s' of '%s' already exists", itemChild.Child, itemChild.Parent)
+	}
+	repo.children = append(repo.children, NewItemChild(itemChild.Parent, itemChild.Child))
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveChild(parent string, child string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.filterChildren(func(itemChild *ItemChild) bool { return itemChild.Parent != parent || itemChild.Child != child })
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveChildren(parent string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.filterChildren(func(itemChild *ItemChild) bool { return itemChild.Parent != parent })
+	return nil
+}
+
+func (repo *MemoryRepository) HasChild(parent string, child string) bool {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.hasChild(parent, child)
+}
+
+func (repo *MemoryRepository) FindChildren(name string) ([]Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	items := make([]Item, 0)
+	for _, itemChild := range repo.children {
+		if item := repo.items[itemChild.Child]; itemChild.Parent == name && item != nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (repo *MemoryRepository) Assign(assignment Assignment) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.assign(assignment)
+}
+
+func (repo *MemoryRepository) Assigns(assignments ...*Assignment) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, assignment := range assignments {
+		if err := repo.assign(*assignment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveAssignment(userId interface{}, name string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.filterAssignments(func(assignment *Assignment) bool { return assignment.UserId != userId || assignment.ItemName != name })
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveAllAssignmentByUser(userId interface{}) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.filterAssignments(func(assignment *Assignment) bool { return assignment.UserId != userId })
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveAllAssignments() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.assignments = nil
+	return nil
+}
+
+func (repo *MemoryRepository) GetAssignment(userId interface{}, name string) (*Assignment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	for _, assignment := range repo.assignments {
+		if assignment.UserId == userId && assignment.ItemName == name {
+			copied := *assignment
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("assignment '%s' of user %v not found", name, userId)
+}
+
+func (repo *MemoryRepository) GetAssignmentsByItem(name string) ([]*Assignment, error) {
+	return repo.findAssignments(func(assignment *Assignment) bool { return assignment.ItemName == name }), nil
+}
+
+func (repo *MemoryRepository) GetAssignments(userId interface{}) ([]*Assignment, error) {
+	return repo.findAssignments(func(assignment *Assignment) bool { return assignment.UserId == userId }), nil
+}
+
+func (repo *MemoryRepository) GetAllAssignment() ([]*Assignment, error) {
+	return repo.findAssignments(func(assignment *Assignment) bool { return true }), nil
+}
+
+func (repo *MemoryRepository) RemoveAll() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.items = make(map[string]Item)
+	repo.rules = make(map[string]*Rule)
+	repo.children = nil
+	repo.assignments = nil
+	return nil
+}
+
+// RemoveChildByNames 删除父级或子级在 names 中的继承关系
+func (repo *MemoryRepository) RemoveChildByNames(t ItemType, names []string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	repo.filterChildren(func(itemChild *ItemChild) bool { return !set[itemChild.Parent] && !set[itemChild.Child] })
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveAssignmentByNames(names []string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	repo.filterAssignments(func(assignment *Assignment) bool { return !set[assignment.ItemName] })
+	return nil
+}
+
+func (repo *MemoryRepository) RemoveAllRules() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.rules = make(map[string]*Rule)
+	for name, item := range repo.items {
+		if item.GetRuleName() != "" {
+			repo.items[name] = withRuleName(item, "")
+		}
+	}
+	return nil
+}
+
+func (repo *MemoryRepository) sortedItems(filter func(item Item) bool) []Item {
+	items := make([]Item, 0, len(repo.items))
+	for _, item := range repo.items {
+		if filter(item) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].GetName() < items[j].GetName() })
+	return items
+}
+
+func (repo *MemoryRepository) findItemsByUser(userId interface{}, itemType ItemType) []Item {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	items := make([]Item, 0)
+	for _, assignment := range repo.assignments {
+		if item := repo.items[assignment.ItemName]; assignment.UserId == userId && item != nil && item.GetType() == itemType {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (repo *MemoryRepository) findAssignments(filter func(assignment *Assignment) bool) []*Assignment {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	assignments := make([]*Assignment, 0)
+	for _, assignment := range repo.assignments {
+		if filter(assignment) {
+			copied := *assignment
+			assignments = append(assignments, &copied)
+		}
+	}
+	return assignments
+}
+
+func (repo *MemoryRepository) assign(assignment Assignment) error {
+	for _, existing := range repo.assignments {
+		if existing.UserId == assignment.UserId && existing.ItemName == assignment.ItemName {
+			return fmt.Errorf("user %v is already assigned to '%s'", assignment.UserId, assignment.ItemName)
+		}
+	}
+	repo.assignments = append(repo.assignments, &assignment)
+	return nil
+}
+
+func (repo *MemoryRepository) hasChild(parent string, child string) bool {
+	for _, itemChild := range repo.children {
+		if itemChild.Parent == parent && itemChild.Child == child {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *MemoryRepository) filterChildren(keep func(itemChild *ItemChild) bool) {
+	children := make([]*ItemChild, 0, len(repo.children))
+	for _, itemChild := range repo.children {
+		if keep(itemChild) {
+			children = append(children, itemChild)
+		}
+	}
+	repo.children = children
+}
+
+func (repo *MemoryRepository) filterAssignments(keep func(assignment *Assignment) bool) {
+	assignments := make([]*Assignment, 0, len(repo.assignments))
+	for _, assignment := range repo.assignments {
+		if keep(assignment) {
+			assignments = append(assignments, assignment)
+		}
+	}
+	repo.assignments = assignments
+}
+
+func withRuleName(item Item, ruleName string) Item {
+	if item.GetType() == RoleType {
+		return NewRole(item.GetName(), item.GetDescription(), ruleName, item.GetExecuteName(), item.GetCreateTime(), item.GetUpdateTime())
+	}
+	return NewPermission(item.GetName(), item.GetDescription(), ruleName, item.GetExecuteName(), item.GetCreateTime(), item.GetUpdateTime())
+}
@@ -0,0 +1,147 @@
+package gorbac
+
+import (
+	"context"
+	"time"
+)
+
+// CacheKind 观测的缓存类型
+type CacheKind string
+
+const (
+	// CachePolicy 策略快照（items、rules 与继承关系），仅在开启缓存时观测
+	CachePolicy CacheKind = "policy"
+	// CacheAssignments 用户分配缓存
+	CacheAssignments CacheKind = "assignments"
+	// CacheDecision 请求级判定缓存中的最终判定
+	CacheDecision CacheKind = "decision"
+	// CacheExecution 请求级判定缓存中的执行器结果
+	CacheExecution CacheKind = "execution"
+)
+
+// Observer 校验、缓存、执行器与仓库调用的观测钩子，用于指标与链路追踪，需并发安全。
+// 只需部分钩子时可嵌入 NopObserver。
+type Observer interface {
+	// CheckStart CheckAccess 开始时调用，返回的 ctx 传给执行器与 CheckEnd，可用于挂载 span
+	CheckStart(ctx context.Context, userId interface{}, permission string) context.Context
+	CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration)
+	CacheHit(kind CacheKind)
+	CacheMiss(kind CacheKind)
+	// CacheReload 从仓库构建策略快照（loadFromCache 与后台刷新）的耗时，err 非 nil 表示构建失败
+	CacheReload(duration time.Duration, err error)
+	// ExecutorDone 执行器调用结束，请求级缓存命中时不调用
+	ExecutorDone(ctx context.Context, executor string, outcome ExecutionOutcome, duration time.Duration)
+	// RepositoryCall 仓库方法调用结束，需通过 ObserveRepository 包装仓库
+	RepositoryCall(method string, duration time.Duration, err error)
+}
+
+// NopObserver 空实现
+type NopObserver struct{}
+
+func (NopObserver) CheckStart(ctx context.Context, userId interface{}, permission string) context.Context {
+	return ctx
+}
+
+func (NopObserver) CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration) {
+}
+
+func (NopObserver) CacheHit(kind CacheKind) {}
+
+func (NopObserver) CacheMiss(kind CacheKind) {}
+
+func (NopObserver) CacheReload(duration time.Duration, err error) {}
+
+func (NopObserver) ExecutorDone(ctx context.Context, executor string, outcome ExecutionOutcome, duration time.Duration) {
+}
+
+func (NopObserver) RepositoryCall(method string, duration time.Duration, err error) {}
+
+// MultiObserver 按顺序调用多个 Observer，CheckStart 返回的 ctx 依次传递
+type MultiObserver []Observer
+
+func (observers MultiObserver) CheckStart(ctx context.Context, userId interface{}, permission string) context.Context {
+	for _, observer := range observers {
+		ctx = observer.CheckStart(ctx, userId, permission)
+	}
+	return ctx
+}
+
+func (observers MultiObserver) CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration) {
+	for _, observer := range observers {
+		observer.CheckEnd(ctx, userId, permission, allowed, duration)
+	}
+}
+
+func (observers MultiObserver) CacheHit(kind CacheKind) {
+	for _, observer := range observers {
+		observer.CacheHit(kind)
+	}
+}
+
+func (observers MultiObserver) CacheMiss(kind CacheKind) {
+	for _, observer := range observers {
+		observer.CacheMiss(kind)
+	}
+}
+
+func (observers MultiObserver) CacheReload(duration time.Duration, err error) {
+	for _, observer := range observers {
+		observer.CacheReload(duration, err)
+	}
+}
+
+func (observers MultiObserver) ExecutorDone(ctx context.Context, executor string, outcome ExecutionOutcome, duration time.Duration) {
+	for _, observer := range observers {
+		observer.ExecutorDone(ctx, executor, outcome, duration)
+	}
+}
+
+func (observers MultiObserver) RepositoryCall(method string, duration time.Duration, err error) {
+	for _, observer := range observers {
+		observer.RepositoryCall(method, duration, err)
+	}
+}
+
+// ---------------------- Manager ---------------------------
+
+type observerHolder struct {
+	observer Observer
+}
+
+// SetObserver 设置观测钩子，nil 关闭。未设置时各钩子点只有一次原子读取，不计时也不分配内存。
+// 仓库调用需另外通过 ObserveRepository 包装仓库后传给 NewDefaultManager。
+func (manager *DefaultManager) SetObserver(observer Observer) {
+	manager.observer.Store(&observerHolder{observer: observer})
+}
+
+func (manager *DefaultManager) getObserver() Observer {
+	if holder, ok := manager.observer.Load().(*observerHolder); ok {
+		return holder.observer
+	}
+	return nil
+}
+
+func (manager *DefaultManager) observeCache(kind CacheKind, hit bool) {
+	observer := manager.getObserver()
+	if observer == nil {
+		return
+	}
+	if hit {
+		observer.CacheHit(kind)
+	} else {
+		observer.CacheMiss(kind)
+	}
+}
+
+// buildObserved 构建策略快照并上报耗时
+func (manager *DefaultManager) buildObserved(generation uint64) *policySnapshot {
+	observer := manager.getObserver()
+	if observer == nil {
+		built, _ := manager.buildSnapshot(generation)
+		return built
+	}
+	start := time.Now()
+	built, err := manager.buildSnapshot(generation)
+	observer.CacheReload(time.Since(start), err)
+	return built
+}
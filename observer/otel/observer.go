@@ -0,0 +1,108 @@
+// Package otel 将 gorbac.Observer 的钩子记录为 OpenTelemetry span：每次 CheckAccess 一个 span，
+// 执行器调用作为其子 span；仓库调用与策略快照重建不带 ctx，可选地记录为独立 span。
+package otel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kordar/gorbac"
+	otellib "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/kordar/gorbac/observer/otel"
+
+type Options struct {
+	// TracerProvider 默认 otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+	// UserIdAttribute 为 true 时在校验 span 上记录 rbac.user_id
+	UserIdAttribute bool
+	// BackgroundSpans 为 true 时为仓库调用与策略快照重建创建独立的根 span
+	BackgroundSpans bool
+}
+
+// Observer 实现 gorbac.Observer。span 名称与属性：
+//
+//	gorbac.CheckAccess       rbac.permission、rbac.allowed，可选 rbac.user_id；拒绝不视为错误
+//	gorbac.Executor          rbac.executor、rbac.outcome，outcome 不为 ok 时状态为 Error
+//	gorbac.CacheReload       策略快照重建，需开启 BackgroundSpans
+//	gorbac.Repository        rbac.repository.method，需开启 BackgroundSpans
+//
+// 缓存命中与未命中不带 ctx，不做记录，命中率请使用 Prometheus 适配器。
+type Observer struct {
+	tracer          trace.Tracer
+	userIdAttribute bool
+	backgroundSpans bool
+}
+
+func NewObserver(options Options) *Observer {
+	if options.TracerProvider == nil {
+		options.TracerProvider = otellib.GetTracerProvider()
+	}
+	return &Observer{
+		tracer:          options.TracerProvider.Tracer(instrumentationName),
+		userIdAttribute: options.UserIdAttribute,
+		backgroundSpans: options.BackgroundSpans,
+	}
+}
+
+func (observer *Observer) CheckStart(ctx context.Context, userId interface{}, permission string) context.Context {
+	attributes := []attribute.KeyValue{attribute.String("rbac.permission", permission)}
+	if observer.userIdAttribute {
+		attributes = append(attributes, attribute.String("rbac.user_id", fmt.Sprint(userId)))
+	}
+	ctx, _ = observer.tracer.Start(ctx, "gorbac.CheckAccess", trace.WithAttributes(attributes...))
+	return ctx
+}
+
+func (observer *Observer) CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Bool("rbac.allowed", allowed))
+	span.End()
+}
+
+func (observer *Observer) CacheHit(kind gorbac.CacheKind) {}
+
+func (observer *Observer) CacheMiss(kind gorbac.CacheKind) {}
+
+func (observer *Observer) CacheReload(duration time.Duration, err error) {
+	if !observer.backgroundSpans {
+		return
+	}
+	observer.completed(context.Background(), "gorbac.CacheReload", duration, err)
+}
+
+func (observer *Observer) ExecutorDone(ctx context.Context, executor string, outcome gorbac.ExecutionOutcome, duration time.Duration) {
+	var err error
+	if outcome != gorbac.OutcomeOK {
+		err = fmt.Errorf("executor %s", outcome)
+	}
+	observer.completed(ctx, "gorbac.Executor", duration, err,
+		attribute.String("rbac.executor", executor),
+		attribute.String("rbac.outcome", string(outcome)))
+}
+
+func (observer *Observer) RepositoryCall(method string, duration time.Duration, err error) {
+	if !observer.backgroundSpans {
+		return
+	}
+	observer.completed(context.Background(), "gorbac.Repository", duration, err,
+		attribute.String("rbac.repository.method", method))
+}
+
+// completed 为刚结束、耗时 duration 的操作补建 span
+func (observer *Observer) completed(ctx context.Context, name string, duration time.Duration, err error, attributes ...attribute.KeyValue) {
+	end := time.Now()
+	_, span := observer.tracer.Start(ctx, name,
+		trace.WithTimestamp(end.Add(-duration)),
+		trace.WithAttributes(attributes...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
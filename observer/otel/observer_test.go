@@ -0,0 +1,140 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecordedObserver(options Options) (*Observer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	options.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewObserver(options), recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestObserverSpans(t *testing.T) {
+	observer, recorder := newRecordedObserver(Options{UserIdAttribute: true})
+	ctx := observer.CheckStart(context.Background(), 42, "doc:read")
+	observer.ExecutorDone(ctx, "owner", gorbac.OutcomeOK, time.Millisecond)
+	observer.ExecutorDone(ctx, "stuck", gorbac.OutcomeTimeout, 5*time.Millisecond)
+	observer.CheckEnd(ctx, 42, "doc:read", false, 10*time.Millisecond)
+	// 未开启 BackgroundSpans 时不记录
+	observer.CacheReload(time.Millisecond, nil)
+	observer.RepositoryCall("GetItem", time.Millisecond, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("ended spans = %d, want 3", len(spans))
+	}
+	ok, timeout, check := spans[0], spans[1], spans[2]
+
+	if check.Name() != "gorbac.CheckAccess" {
+		t.Fatalf("last span = %s, want gorbac.CheckAccess", check.Name())
+	}
+	checkAttributes := attributes(check)
+	if checkAttributes["rbac.permission"].AsString() != "doc:read" || checkAttributes["rbac.user_id"].AsString() != "42" {
+		t.Errorf("check attributes = %v", check.Attributes())
+	}
+	if allowed, found := checkAttributes["rbac.allowed"]; !found || allowed.AsBool() {
+		t.Errorf("rbac.allowed = %v, want false", allowed)
+	}
+	// 拒绝不视为错误
+	if check.Status().Code == codes.Error {
+		t.Error("denied check has error status")
+	}
+
+	for _, span := range []sdktrace.ReadOnlySpan{ok, timeout} {
+		if span.Name() != "gorbac.Executor" {
+			t.Errorf("span = %s, want gorbac.Executor", span.Name())
+		}
+		if span.Parent().SpanID() != check.SpanContext().SpanID() {
+			t.Errorf("executor span parent = %v, want the check span", span.Parent().SpanID())
+		}
+	}
+	if attributes(ok)["rbac.executor"].AsString() != "owner" || attributes(ok)["rbac.outcome"].AsString() != "ok" || ok.Status().Code == codes.Error {
+		t.Errorf("ok executor span attributes = %v, status = %v", ok.Attributes(), ok.Status())
+	}
+	if attributes(timeout)["rbac.outcome"].AsString() != "timeout" || timeout.Status().Code != codes.Error {
+		t.Errorf("timeout executor span attributes = %v, status = %v", timeout.Attributes(), timeout.Status())
+	}
+	if got := timeout.EndTime().Sub(timeout.StartTime()); got != 5*time.Millisecond {
+		t.Errorf("timeout executor span duration = %v, want 5ms", got)
+	}
+}
+
+func TestObserverUserIdAttribute(t *testing.T) {
+	observer, recorder := newRecordedObserver(Options{})
+	ctx := observer.CheckStart(context.Background(), 42, "doc:read")
+	observer.CheckEnd(ctx, 42, "doc:read", true, time.Millisecond)
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	if _, found := attributes(spans[0])["rbac.user_id"]; found {
+		t.Error("rbac.user_id recorded without UserIdAttribute")
+	}
+}
+
+func TestObserverBackgroundSpans(t *testing.T) {
+	observer, recorder := newRecordedObserver(Options{BackgroundSpans: true})
+	observer.CacheReload(time.Millisecond, nil)
+	observer.RepositoryCall("FindAllItems", time.Millisecond, errors.New("connection lost"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	reload, repository := spans[0], spans[1]
+	if reload.Name() != "gorbac.CacheReload" || reload.Status().Code == codes.Error || reload.Parent().IsValid() {
+		t.Errorf("reload span = %s, status = %v, parent = %v", reload.Name(), reload.Status(), reload.Parent())
+	}
+	if repository.Name() != "gorbac.Repository" || attributes(repository)["rbac.repository.method"].AsString() != "FindAllItems" {
+		t.Errorf("repository span = %s, attributes = %v", repository.Name(), repository.Attributes())
+	}
+	if repository.Status().Code != codes.Error || repository.Status().Description != "connection lost" {
+		t.Errorf("repository span status = %v, want error connection lost", repository.Status())
+	}
+}
+
+func TestObserverWithManager(t *testing.T) {
+	observer, recorder := newRecordedObserver(Options{})
+	now := time.Now()
+	manager := gorbac.NewDefaultManager(gorbac.NewMemoryRepository(), true)
+	registry := gorbac.NewExecutorRegistry()
+	registry.AddExecutor(gorbac.NewExpressionExecutor())
+	manager.SetExecutorRegistry(registry)
+	manager.SetObserver(observer)
+	manager.AddRule(*gorbac.NewExpressionRule("owner", "params.owner_id == user.id", now, now))
+	edit := gorbac.NewPermission("posts:edit", "", "owner", "", now, now)
+	manager.Add(edit)
+	manager.Assign(edit, 5)
+
+	if !manager.CheckAccess(gorbac.WithParams(context.Background(), map[string]interface{}{"owner_id": 5}), 5, "posts:edit") {
+		t.Fatal("owner denied")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "gorbac.Executor" || spans[1].Name() != "gorbac.CheckAccess" {
+		t.Fatalf("spans = %v, want an executor span inside the check span", spans)
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("executor span is not a child of the check span")
+	}
+	if !attributes(spans[1])["rbac.allowed"].AsBool() {
+		t.Error("rbac.allowed = false on an allowed check")
+	}
+}
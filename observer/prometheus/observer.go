@@ -0,0 +1,146 @@
+// Package prometheus 将 gorbac.Observer 的钩子记录为 Prometheus 计数器与直方图：
+// 校验次数与耗时、缓存命中与未命中、策略快照重建、执行器与仓库调用耗时。
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/kordar/gorbac"
+	promlib "github.com/prometheus/client_golang/prometheus"
+)
+
+type Options struct {
+	// Namespace 指标名前缀，默认 "gorbac"
+	Namespace string
+	// Registerer 注册指标的位置，默认 prometheus.DefaultRegisterer
+	Registerer promlib.Registerer
+	// Buckets 耗时直方图的桶（秒），默认 prometheus.DefBuckets
+	Buckets []float64
+	// PermissionLabel 为 true 时校验指标带 permission 标签，权限较多时注意标签基数
+	PermissionLabel bool
+}
+
+// Observer 实现 gorbac.Observer，指标：
+//
+//	gorbac_checks_total{result}                          校验次数，result 为 allowed 或 denied
+//	gorbac_check_duration_seconds{result}                校验耗时
+//	gorbac_cache_requests_total{cache,result}            缓存访问，result 为 hit 或 miss
+//	gorbac_cache_reload_duration_seconds{result}         策略快照重建耗时，result 为 ok 或 error
+//	gorbac_executor_duration_seconds{executor,outcome}   执行器耗时
+//	gorbac_repository_duration_seconds{method,result}    仓库调用耗时，result 为 ok 或 error
+//
+// 开启 PermissionLabel 时前两项额外带 permission 标签。
+type Observer struct {
+	permissionLabel    bool
+	checks             *promlib.CounterVec
+	checkDuration      *promlib.HistogramVec
+	cache              *promlib.CounterVec
+	reloadDuration     *promlib.HistogramVec
+	executorDuration   *promlib.HistogramVec
+	repositoryDuration *promlib.HistogramVec
+}
+
+// NewObserver 创建并注册指标，指标已注册时返回错误
+func NewObserver(options Options) (*Observer, error) {
+	if options.Namespace == "" {
+		options.Namespace = "gorbac"
+	}
+	if options.Registerer == nil {
+		options.Registerer = promlib.DefaultRegisterer
+	}
+	if options.Buckets == nil {
+		options.Buckets = promlib.DefBuckets
+	}
+
+	checkLabels := []string{"result"}
+	if options.PermissionLabel {
+		checkLabels = []string{"permission", "result"}
+	}
+	histogram := func(name string, help string, labels ...string) *promlib.HistogramVec {
+		return promlib.NewHistogramVec(promlib.HistogramOpts{
+			Namespace: options.Namespace,
+			Name:      name,
+			Help:      help,
+			Buckets:   options.Buckets,
+		}, labels)
+	}
+
+	observer := &Observer{
+		permissionLabel: options.PermissionLabel,
+		checks: promlib.NewCounterVec(promlib.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "checks_total",
+			Help:      "Number of CheckAccess calls.",
+		}, checkLabels),
+		checkDuration: histogram("check_duration_seconds", "CheckAccess latency in seconds.", checkLabels...),
+		cache: promlib.NewCounterVec(promlib.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by cache and result.",
+		}, []string{"cache", "result"}),
+		reloadDuration:     histogram("cache_reload_duration_seconds", "Policy snapshot reload latency in seconds.", "result"),
+		executorDuration:   histogram("executor_duration_seconds", "Rule executor latency in seconds.", "executor", "outcome"),
+		repositoryDuration: histogram("repository_duration_seconds", "Repository call latency in seconds.", "method", "result"),
+	}
+
+	collectors := []promlib.Collector{
+		observer.checks,
+		observer.checkDuration,
+		observer.cache,
+		observer.reloadDuration,
+		observer.executorDuration,
+		observer.repositoryDuration,
+	}
+	for _, collector := range collectors {
+		if err := options.Registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return observer, nil
+}
+
+func (observer *Observer) CheckStart(ctx context.Context, userId interface{}, permission string) context.Context {
+	return ctx
+}
+
+func (observer *Observer) CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration) {
+	result := "denied"
+	if allowed {
+		result = "allowed"
+	}
+	if observer.permissionLabel {
+		observer.checks.WithLabelValues(permission, result).Inc()
+		observer.checkDuration.WithLabelValues(permission, result).Observe(duration.Seconds())
+		return
+	}
+	observer.checks.WithLabelValues(result).Inc()
+	observer.checkDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+func (observer *Observer) CacheHit(kind gorbac.CacheKind) {
+	observer.cache.WithLabelValues(string(kind), "hit").Inc()
+}
+
+func (observer *Observer) CacheMiss(kind gorbac.CacheKind) {
+	observer.cache.WithLabelValues(string(kind), "miss").Inc()
+}
+
+func (observer *Observer) CacheReload(duration time.Duration, err error) {
+	observer.reloadDuration.WithLabelValues(errorResult(err)).Observe(duration.Seconds())
+}
+
+func (observer *Observer) ExecutorDone(ctx context.Context, executor string, outcome gorbac.ExecutionOutcome, duration time.Duration) {
+	observer.executorDuration.WithLabelValues(executor, string(outcome)).Observe(duration.Seconds())
+}
+
+func (observer *Observer) RepositoryCall(method string, duration time.Duration, err error) {
+	observer.repositoryDuration.WithLabelValues(method, errorResult(err)).Observe(duration.Seconds())
+}
+
+func errorResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,146 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kordar/gorbac"
+	promlib "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sample 返回指标 name 中标签完全匹配 labels 的计数器值或直方图样本数，不存在时返回 -1
+func sample(t *testing.T, registry *promlib.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if matchLabels(metric, labels) {
+				if histogram := metric.GetHistogram(); histogram != nil {
+					return float64(histogram.GetSampleCount())
+				}
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return -1
+}
+
+func matchLabels(metric *dto.Metric, labels map[string]string) bool {
+	if len(metric.GetLabel()) != len(labels) {
+		return false
+	}
+	for _, label := range metric.GetLabel() {
+		if value, ok := labels[label.GetName()]; !ok || value != label.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestObserverMetrics(t *testing.T) {
+	registry := promlib.NewRegistry()
+	observer, err := NewObserver(Options{Registerer: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := observer.CheckStart(context.Background(), 1, "doc:read")
+	observer.CheckEnd(ctx, 1, "doc:read", true, time.Millisecond)
+	observer.CheckEnd(ctx, 1, "doc:read", false, time.Millisecond)
+	observer.CheckEnd(ctx, 2, "doc:write", false, time.Millisecond)
+	observer.CacheHit(gorbac.CachePolicy)
+	observer.CacheHit(gorbac.CachePolicy)
+	observer.CacheMiss(gorbac.CacheAssignments)
+	observer.CacheReload(time.Millisecond, nil)
+	observer.CacheReload(time.Millisecond, errors.New("connection lost"))
+	observer.ExecutorDone(ctx, "owner", gorbac.OutcomeTimeout, time.Millisecond)
+	observer.RepositoryCall("GetItem", time.Millisecond, nil)
+	observer.RepositoryCall("GetItem", time.Millisecond, errors.New("not found"))
+	observer.RepositoryCall("GetItem", time.Millisecond, errors.New("not found"))
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"gorbac_checks_total", map[string]string{"result": "allowed"}, 1},
+		{"gorbac_checks_total", map[string]string{"result": "denied"}, 2},
+		{"gorbac_check_duration_seconds", map[string]string{"result": "denied"}, 2},
+		{"gorbac_cache_requests_total", map[string]string{"cache": "policy", "result": "hit"}, 2},
+		{"gorbac_cache_requests_total", map[string]string{"cache": "assignments", "result": "miss"}, 1},
+		{"gorbac_cache_requests_total", map[string]string{"cache": "policy", "result": "miss"}, -1},
+		{"gorbac_cache_reload_duration_seconds", map[string]string{"result": "ok"}, 1},
+		{"gorbac_cache_reload_duration_seconds", map[string]string{"result": "error"}, 1},
+		{"gorbac_executor_duration_seconds", map[string]string{"executor": "owner", "outcome": "timeout"}, 1},
+		{"gorbac_repository_duration_seconds", map[string]string{"method": "GetItem", "result": "ok"}, 1},
+		{"gorbac_repository_duration_seconds", map[string]string{"method": "GetItem", "result": "error"}, 2},
+	}
+	for _, tt := range tests {
+		if got := sample(t, registry, tt.name, tt.labels); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+
+	if _, err := NewObserver(Options{Registerer: registry}); err == nil {
+		t.Error("registering the metrics twice succeeded")
+	}
+}
+
+func TestObserverOptions(t *testing.T) {
+	registry := promlib.NewRegistry()
+	observer, err := NewObserver(Options{Namespace: "authz", Registerer: registry, PermissionLabel: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	observer.CheckEnd(context.Background(), 1, "doc:read", true, time.Millisecond)
+	if got := sample(t, registry, "authz_checks_total", map[string]string{"permission": "doc:read", "result": "allowed"}); got != 1 {
+		t.Errorf("authz_checks_total{permission=doc:read,result=allowed} = %v, want 1", got)
+	}
+	if got := sample(t, registry, "authz_check_duration_seconds", map[string]string{"permission": "doc:read", "result": "allowed"}); got != 1 {
+		t.Errorf("authz_check_duration_seconds{permission=doc:read,result=allowed} = %v, want 1", got)
+	}
+}
+
+func TestObserverWithManager(t *testing.T) {
+	registry := promlib.NewRegistry()
+	observer, err := NewObserver(Options{Registerer: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := gorbac.NewDefaultManager(gorbac.ObserveRepository(gorbac.NewMemoryRepository(), observer), true)
+	manager.SetObserver(observer)
+	read := manager.CreatePermission("doc:read")
+	manager.Add(read)
+	manager.Assign(read, 1)
+
+	ctx := context.Background()
+	manager.CheckAccess(ctx, 1, "doc:read")
+	manager.CheckAccess(ctx, 1, "doc:read")
+	manager.CheckAccess(ctx, 1, "doc:write")
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"gorbac_checks_total", map[string]string{"result": "allowed"}, 2},
+		{"gorbac_checks_total", map[string]string{"result": "denied"}, 1},
+		{"gorbac_cache_requests_total", map[string]string{"cache": "policy", "result": "miss"}, 1},
+		{"gorbac_cache_requests_total", map[string]string{"cache": "policy", "result": "hit"}, 2},
+		{"gorbac_cache_reload_duration_seconds", map[string]string{"result": "ok"}, 1},
+		{"gorbac_repository_duration_seconds", map[string]string{"method": "AddItem", "result": "ok"}, 1},
+	}
+	for _, tt := range tests {
+		if got := sample(t, registry, tt.name, tt.labels); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
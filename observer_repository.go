@@ -0,0 +1,286 @@
+package gorbac
+
+import "time"
+
+// ObserveRepository 包装仓库，每次方法调用结束后以方法名调用 observer.RepositoryCall。
+// repo 实现 TransactionalRepository 时返回值同样实现，事务内的调用也会被观测。
+//
+//	manager := gorbac.NewDefaultManager(gorbac.ObserveRepository(repo, observer), true)
+//	manager.SetObserver(observer)
+func ObserveRepository(repo AuthRepository, observer Observer) AuthRepository {
+	observed := &observedRepository{AuthRepository: repo, observer: observer}
+	if _, ok := repo.(TransactionalRepository); ok {
+		return &observedTransactionalRepository{observed}
+	}
+	return observed
+}
+
+type observedRepository struct {
+	AuthRepository
+	observer Observer
+}
+
+type observedTransactionalRepository struct {
+	*observedRepository
+}
+
+func (repo *observedTransactionalRepository) Transaction(fn func(repo AuthRepository) error) error {
+	start := time.Now()
+	err := repo.AuthRepository.(TransactionalRepository).Transaction(func(tx AuthRepository) error {
+		return fn(&observedRepository{AuthRepository: tx, observer: repo.observer})
+	})
+	repo.observer.RepositoryCall("Transaction", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) AddItem(item Item) error {
+	start := time.Now()
+	err := repo.AuthRepository.AddItem(item)
+	repo.observer.RepositoryCall("AddItem", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) GetItem(name string) (Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetItem(name)
+	repo.observer.RepositoryCall("GetItem", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetItemsByType(itemType ItemType) ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetItemsByType(itemType)
+	repo.observer.RepositoryCall("GetItemsByType", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) RemoveItemByType(itemType ItemType) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveItemByType(itemType)
+	repo.observer.RepositoryCall("RemoveItemByType", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) FindAllItems() ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindAllItems()
+	repo.observer.RepositoryCall("FindAllItems", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) AddRule(rule Rule) error {
+	start := time.Now()
+	err := repo.AuthRepository.AddRule(rule)
+	repo.observer.RepositoryCall("AddRule", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) GetRule(name string) (*Rule, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetRule(name)
+	repo.observer.RepositoryCall("GetRule", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetRules() ([]*Rule, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetRules()
+	repo.observer.RepositoryCall("GetRules", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) RemoveItem(name string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveItem(name)
+	repo.observer.RepositoryCall("RemoveItem", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveRule(ruleName string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveRule(ruleName)
+	repo.observer.RepositoryCall("RemoveRule", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) UpdateItem(itemName string, item Item) error {
+	start := time.Now()
+	err := repo.AuthRepository.UpdateItem(itemName, item)
+	repo.observer.RepositoryCall("UpdateItem", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) UpdateRule(ruleName string, rule Rule) error {
+	start := time.Now()
+	err := repo.AuthRepository.UpdateRule(ruleName, rule)
+	repo.observer.RepositoryCall("UpdateRule", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) FindRolesByUser(userId interface{}) ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindRolesByUser(userId)
+	repo.observer.RepositoryCall("FindRolesByUser", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) FindChildrenList() ([]*ItemChild, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindChildrenList()
+	repo.observer.RepositoryCall("FindChildrenList", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) FindChildrenFormChild(child string) ([]*ItemChild, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindChildrenFormChild(child)
+	repo.observer.RepositoryCall("FindChildrenFormChild", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetItemList(t int32, names []string) ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetItemList(t, names)
+	repo.observer.RepositoryCall("GetItemList", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) FindPermissionsByUser(userId interface{}) ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindPermissionsByUser(userId)
+	repo.observer.RepositoryCall("FindPermissionsByUser", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) FindAssignmentsByUser(userId interface{}) ([]*Assignment, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindAssignmentsByUser(userId)
+	repo.observer.RepositoryCall("FindAssignmentsByUser", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) AddItemChild(itemChild ItemChild) error {
+	start := time.Now()
+	err := repo.AuthRepository.AddItemChild(itemChild)
+	repo.observer.RepositoryCall("AddItemChild", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveChild(parent string, child string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveChild(parent, child)
+	repo.observer.RepositoryCall("RemoveChild", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveChildren(parent string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveChildren(parent)
+	repo.observer.RepositoryCall("RemoveChildren", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) HasChild(parent string, child string) bool {
+	start := time.Now()
+	ok := repo.AuthRepository.HasChild(parent, child)
+	repo.observer.RepositoryCall("HasChild", time.Since(start), nil)
+	return ok
+}
+
+func (repo *observedRepository) FindChildren(name string) ([]Item, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.FindChildren(name)
+	repo.observer.RepositoryCall("FindChildren", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) Assign(assignment Assignment) error {
+	start := time.Now()
+	err := repo.AuthRepository.Assign(assignment)
+	repo.observer.RepositoryCall("Assign", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) Assigns(assignment ...*Assignment) error {
+	start := time.Now()
+	err := repo.AuthRepository.Assigns(assignment...)
+	repo.observer.RepositoryCall("Assigns", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveAssignment(userId interface{}, name string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAssignment(userId, name)
+	repo.observer.RepositoryCall("RemoveAssignment", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveAllAssignmentByUser(userId interface{}) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAllAssignmentByUser(userId)
+	repo.observer.RepositoryCall("RemoveAllAssignmentByUser", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveAllAssignments() error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAllAssignments()
+	repo.observer.RepositoryCall("RemoveAllAssignments", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) GetAssignment(userId interface{}, name string) (*Assignment, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetAssignment(userId, name)
+	repo.observer.RepositoryCall("GetAssignment", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetAssignmentsByItem(name string) ([]*Assignment, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetAssignmentsByItem(name)
+	repo.observer.RepositoryCall("GetAssignmentsByItem", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetAssignments(userId interface{}) ([]*Assignment, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetAssignments(userId)
+	repo.observer.RepositoryCall("GetAssignments", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) GetAllAssignment() ([]*Assignment, error) {
+	start := time.Now()
+	result, err := repo.AuthRepository.GetAllAssignment()
+	repo.observer.RepositoryCall("GetAllAssignment", time.Since(start), err)
+	return result, err
+}
+
+func (repo *observedRepository) RemoveAll() error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAll()
+	repo.observer.RepositoryCall("RemoveAll", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveChildByNames(t ItemType, names []string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveChildByNames(t, names)
+	repo.observer.RepositoryCall("RemoveChildByNames", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveAssignmentByNames(names []string) error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAssignmentByNames(names)
+	repo.observer.RepositoryCall("RemoveAssignmentByNames", time.Since(start), err)
+	return err
+}
+
+func (repo *observedRepository) RemoveAllRules() error {
+	start := time.Now()
+	err := repo.AuthRepository.RemoveAllRules()
+	repo.observer.RepositoryCall("RemoveAllRules", time.Since(start), err)
+	return err
+}
@@ -0,0 +1,260 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type observerContextKey struct{}
+
+// recordingObserver 按调用顺序记录钩子，CheckStart 在 ctx 中挂载标记以验证传递
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (observer *recordingObserver) record(format string, args ...interface{}) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	observer.events = append(observer.events, fmt.Sprintf(format, args...))
+}
+
+// take 返回并清空已记录的钩子
+func (observer *recordingObserver) take() []string {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	events := observer.events
+	observer.events = nil
+	return events
+}
+
+func (observer *recordingObserver) CheckStart(ctx context.Context, userId interface{}, permission string) context.Context {
+	observer.record("check start %v %s", userId, permission)
+	return context.WithValue(ctx, observerContextKey{}, permission)
+}
+
+func (observer *recordingObserver) CheckEnd(ctx context.Context, userId interface{}, permission string, allowed bool, duration time.Duration) {
+	observer.record("check end %v %s allowed=%v ctx=%v", userId, permission, allowed, ctx.Value(observerContextKey{}))
+}
+
+func (observer *recordingObserver) CacheHit(kind CacheKind) {
+	observer.record("hit %s", kind)
+}
+
+func (observer *recordingObserver) CacheMiss(kind CacheKind) {
+	observer.record("miss %s", kind)
+}
+
+func (observer *recordingObserver) CacheReload(duration time.Duration, err error) {
+	observer.record("reload err=%v", err)
+}
+
+func (observer *recordingObserver) ExecutorDone(ctx context.Context, executor string, outcome ExecutionOutcome, duration time.Duration) {
+	observer.record("executor %s %s ctx=%v", executor, outcome, ctx.Value(observerContextKey{}))
+}
+
+func (observer *recordingObserver) RepositoryCall(method string, duration time.Duration, err error) {
+	observer.record("repository %s err=%v", method, err != nil)
+}
+
+func TestObserverCheckAccess(t *testing.T) {
+	registry := NewExecutorRegistry()
+	registry.AddExecutor(gate(true))
+	registry.AddExecutor(&testExecutor{name: "stuck", execute: func(ctx context.Context) bool {
+		<-ctx.Done()
+		return true
+	}})
+	manager := newGateManager(t, registry)
+	observer := &recordingObserver{}
+	manager.SetObserver(observer)
+	ctx := context.Background()
+
+	steps := []struct {
+		name  string
+		check func() bool
+		want  []string
+	}{
+		{"first check rebuilds policy", func() bool { return manager.CheckAccess(ctx, 1, "doc:read") }, []string{
+			"check start 1 doc:read",
+			"miss assignments",
+			"miss policy",
+			"reload err=<nil>",
+			"executor gate ok ctx=doc:read",
+			"check end 1 doc:read allowed=true ctx=doc:read",
+		}},
+		{"second check hits policy", func() bool { return manager.CheckAccess(ctx, 1, "doc:read") }, []string{
+			"check start 1 doc:read",
+			"hit assignments",
+			"hit policy",
+			"executor gate ok ctx=doc:read",
+			"check end 1 doc:read allowed=true ctx=doc:read",
+		}},
+		{"unknown user misses assignments", func() bool { return !manager.CheckAccess(ctx, 2, "doc:read") }, []string{
+			"check start 2 doc:read",
+			"miss assignments",
+			"check end 2 doc:read allowed=false ctx=doc:read",
+		}},
+	}
+	for _, step := range steps {
+		if !step.check() {
+			t.Fatalf("%s: unexpected decision", step.name)
+		}
+		if got := observer.take(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: events =\n%v\nwant\n%v", step.name, got, step.want)
+		}
+	}
+
+	// 请求级判定缓存：第二次校验命中最终判定，不再调用执行器
+	cached := WithDecisionCache(ctx)
+	manager.CheckAccess(cached, 1, "doc:read")
+	manager.CheckAccess(cached, 1, "doc:read")
+	want := []string{
+		"check start 1 doc:read",
+		"miss decision",
+		"hit assignments",
+		"hit policy",
+		"miss execution",
+		"executor gate ok ctx=doc:read",
+		"check end 1 doc:read allowed=true ctx=doc:read",
+		"check start 1 doc:read",
+		"hit decision",
+		"check end 1 doc:read allowed=true ctx=doc:read",
+	}
+	if got := observer.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("decision cache events =\n%v\nwant\n%v", got, want)
+	}
+
+	// 执行器超时以 outcome 上报
+	now := time.Now()
+	manager.AddRule(*NewRule("slow", "stuck", now, now))
+	manager.SetExecutorTimeout("stuck", time.Millisecond)
+	write := NewPermission("doc:write", "", "slow", "", now, now)
+	manager.Add(write)
+	manager.Assign(write, 1)
+	observer.take()
+	if manager.CheckAccess(ctx, 1, "doc:write") {
+		t.Error("timed out executor allowed")
+	}
+	if !containsEvent(observer.take(), "executor stuck timeout ctx=doc:write") {
+		t.Error("timeout outcome not reported")
+	}
+
+	manager.SetObserver(nil)
+	manager.CheckAccess(ctx, 1, "doc:read")
+	if got := observer.take(); len(got) != 0 {
+		t.Errorf("events after SetObserver(nil) = %v", got)
+	}
+}
+
+func containsEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// flakyRepository 开启 fail 后读取 items 失败
+type flakyRepository struct {
+	AuthRepository
+	mu   sync.Mutex
+	fail bool
+}
+
+func (repo *flakyRepository) setFail(fail bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.fail = fail
+}
+
+func (repo *flakyRepository) FindAllItems() ([]Item, error) {
+	repo.mu.Lock()
+	fail := repo.fail
+	repo.mu.Unlock()
+	if fail {
+		return nil, errors.New("connection lost")
+	}
+	return repo.AuthRepository.FindAllItems()
+}
+
+func TestObserverCacheReloadError(t *testing.T) {
+	repo := &flakyRepository{AuthRepository: NewMemoryRepository()}
+	manager := newTestManagerWith(t, repo, true)
+	observer := &recordingObserver{}
+	manager.SetObserver(observer)
+
+	repo.setFail(true)
+	manager.CheckAccess(context.Background(), 1, "posts:view")
+	if !containsEvent(observer.take(), "reload err=connection lost") {
+		t.Error("failed reload not reported")
+	}
+	repo.setFail(false)
+	manager.CheckAccess(context.Background(), 1, "posts:view")
+	if !containsEvent(observer.take(), "reload err=<nil>") {
+		t.Error("reload after recovery not reported")
+	}
+}
+
+func TestObserveRepository(t *testing.T) {
+	observer := &recordingObserver{}
+	memory := NewMemoryRepository()
+	repo := ObserveRepository(memory, observer)
+	if _, ok := repo.(TransactionalRepository); !ok {
+		t.Fatal("observed MemoryRepository is not transactional")
+	}
+	if _, ok := ObserveRepository(struct{ AuthRepository }{memory}, observer).(TransactionalRepository); ok {
+		t.Error("observed non-transactional repository implements TransactionalRepository")
+	}
+
+	now := time.Now()
+	_ = repo.AddItem(NewRole("admin", "", "", "", now, now))
+	_, _ = repo.GetItem("admin")
+	_, _ = repo.GetItem("missing")
+	_ = repo.(TransactionalRepository).Transaction(func(tx AuthRepository) error {
+		_, _ = tx.FindAllItems()
+		return errors.New("rollback")
+	})
+	want := []string{
+		"repository AddItem err=false",
+		"repository GetItem err=false",
+		"repository GetItem err=true",
+		"repository FindAllItems err=false",
+		"repository Transaction err=true",
+	}
+	if got := observer.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%v\nwant\n%v", got, want)
+	}
+
+	// 经管理器的调用同样被观测
+	manager := NewDefaultManager(repo, true)
+	manager.GetRoles()
+	if got := observer.take(); !containsEvent(got, "repository GetItemsByType err=false") {
+		t.Errorf("GetRoles events = %v, want GetItemsByType", got)
+	}
+}
+
+func TestMultiObserver(t *testing.T) {
+	first, second := &recordingObserver{}, &recordingObserver{}
+	multi := MultiObserver{first, NopObserver{}, second}
+	ctx := multi.CheckStart(context.Background(), 1, "doc:read")
+	multi.CacheHit(CachePolicy)
+	multi.ExecutorDone(ctx, "gate", OutcomeOK, time.Millisecond)
+	multi.CheckEnd(ctx, 1, "doc:read", true, time.Millisecond)
+	want := []string{
+		"check start 1 doc:read",
+		"hit policy",
+		"executor gate ok ctx=doc:read",
+		"check end 1 doc:read allowed=true ctx=doc:read",
+	}
+	for i, observer := range []*recordingObserver{first, second} {
+		if got := observer.take(); !reflect.DeepEqual(got, want) {
+			t.Errorf("observer %d events =\n%v\nwant\n%v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package gorbac
+
+import "context"
+
+type paramsKey struct{}
+
+// WithParams 为 CheckAccess 附加规则参数，执行器通过 ParamsFromContext 读取
+func WithParams(ctx context.Context, params map[string]interface{}) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// ParamsFromContext 返回 WithParams 附加的规则参数，未附加时返回 nil
+func ParamsFromContext(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	params, _ := ctx.Value(paramsKey{}).(map[string]interface{})
+	return params
+}
+
+// ContextKey 规则表达式中 ctx.<name> 读取的 context key，
+// 例如 context.WithValue(ctx, gorbac.ContextKey("tenant"), "acme") 对应 ctx.tenant
+type ContextKey string
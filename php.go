@@ -0,0 +1,489 @@
+package gorbac
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// phpArray PHP 有序数组，键为 int64 或 string
+type phpArray struct {
+	keys   []interface{}
+	values []interface{}
+}
+
+func (array *phpArray) set(key interface{}, value interface{}) {
+	for i, k := range array.keys {
+		if k == key {
+			array.values[i] = value
+			return
+		}
+	}
+	array.keys = append(array.keys, key)
+	array.values = append(array.values, value)
+}
+
+func (array *phpArray) get(key string) interface{} {
+	for i, k := range array.keys {
+		if k == key {
+			return array.values[i]
+		}
+	}
+	return nil
+}
+
+// phpObject 反序列化得到的对象，私有与受保护属性名已去掉前缀
+type phpObject struct {
+	class string
+	props *phpArray
+}
+
+// phpNative 转换为 JSON 可编码的值：列表转为切片，关联数组转为 map
+func phpNative(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *phpArray:
+		list := true
+		for i, key := range v.keys {
+			if key != int64(i) {
+				list = false
+				break
+			}
+		}
+		if list {
+			result := make([]interface{}, len(v.values))
+			for i, item := range v.values {
+				result[i] = phpNative(item)
+			}
+			return result
+		}
+		result := make(map[string]interface{}, len(v.keys))
+		for i, key := range v.keys {
+			result[fmt.Sprint(key)] = phpNative(v.values[i])
+		}
+		return result
+	case *phpObject:
+		return phpNative(v.props)
+	}
+	return value
+}
+
+// parsePHPReturn 解析 Yii2 PhpManager 生成的 "<?php return [...];" 文件，只支持字面量
+func parsePHPReturn(src string) (interface{}, error) {
+	parser := &phpParser{src: src}
+	parser.skip()
+	if strings.HasPrefix(parser.src[parser.pos:], "<?php") {
+		parser.pos += len("<?php")
+	}
+	parser.skip()
+	if !parser.keyword("return") {
+		return nil, parser.errorf("expected return")
+	}
+	value, err := parser.value()
+	if err != nil {
+		return nil, err
+	}
+	parser.skip()
+	if parser.pos < len(parser.src) && parser.src[parser.pos] == ';' {
+		parser.pos++
+	}
+	parser.skip()
+	if parser.pos < len(parser.src) && !strings.HasPrefix(parser.src[parser.pos:], "?>") {
+		return nil, parser.errorf("unexpected trailing content")
+	}
+	return value, nil
+}
+
+type phpParser struct {
+	src string
+	pos int
+}
+
+func (parser *phpParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(parser.src[:parser.pos], "\n") + 1
+	return fmt.Errorf("php line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip 跳过空白与注释
+func (parser *phpParser) skip() {
+	for parser.pos < len(parser.src) {
+		rest := parser.src[parser.pos:]
+		switch {
+		case unicode.IsSpace(rune(rest[0])):
+			parser.pos++
+		case strings.HasPrefix(rest, "//") || rest[0] == '#':
+			if i := strings.IndexByte(rest, '\n'); i >= 0 {
+				parser.pos += i + 1
+			} else {
+				parser.pos = len(parser.src)
+			}
+		case strings.HasPrefix(rest, "/*"):
+			if i := strings.Index(rest[2:], "*/"); i >= 0 {
+				parser.pos += i + 4
+			} else {
+				parser.pos = len(parser.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword 不区分大小写匹配关键字
+func (parser *phpParser) keyword(word string) bool {
+	parser.skip()
+	end := parser.pos + len(word)
+	if end > len(parser.src) || !strings.EqualFold(parser.src[parser.pos:end], word) {
+		return false
+	}
+	if end < len(parser.src) && isPHPIdentifier(parser.src[end]) {
+		return false
+	}
+	parser.pos = end
+	return true
+}
+
+func (parser *phpParser) symbol(s string) bool {
+	parser.skip()
+	if strings.HasPrefix(parser.src[parser.pos:], s) {
+		parser.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (parser *phpParser) value() (interface{}, error) {
+	parser.skip()
+	if parser.pos >= len(parser.src) {
+		return nil, parser.errorf("unexpected end of file")
+	}
+	switch c := parser.src[parser.pos]; {
+	case c == '[':
+		parser.pos++
+		return parser.array("]")
+	case c == '\'' || c == '"':
+		return parser.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return parser.number()
+	}
+	switch {
+	case parser.keyword("array"):
+		if !parser.symbol("(") {
+			return nil, parser.errorf("expected ( after array")
+		}
+		return parser.array(")")
+	case parser.keyword("null"):
+		return nil, nil
+	case parser.keyword("true"):
+		return true, nil
+	case parser.keyword("false"):
+		return false, nil
+	}
+	return nil, parser.errorf("unexpected %q", parser.src[parser.pos])
+}
+
+func (parser *phpParser) array(closing string) (interface{}, error) {
+	array := &phpArray{}
+	next := int64(0)
+	for {
+		if parser.symbol(closing) {
+			return array, nil
+		}
+		value, err := parser.value()
+		if err != nil {
+			return nil, err
+		}
+		key := interface{}(nil)
+		if parser.symbol("=>") {
+			switch k := value.(type) {
+			case int64:
+				key = k
+			case string:
+				// PHP 将十进制整数字符串键转换为整数
+				if n, err := strconv.ParseInt(k, 10, 64); err == nil && strconv.FormatInt(n, 10) == k {
+					key = n
+				} else {
+					key = k
+				}
+			case bool:
+				key = map[bool]int64{false: 0, true: 1}[k]
+			default:
+				return nil, parser.errorf("unsupported array key %v", value)
+			}
+			if value, err = parser.value(); err != nil {
+				return nil, err
+			}
+		} else {
+			key = next
+		}
+		if n, ok := key.(int64); ok && n >= next {
+			next = n + 1
+		}
+		array.set(key, value)
+		if !parser.symbol(",") {
+			if parser.symbol(closing) {
+				return array, nil
+			}
+			return nil, parser.errorf("expected , or %s", closing)
+		}
+	}
+}
+
+func (parser *phpParser) string() (interface{}, error) {
+	quote := parser.src[parser.pos]
+	parser.pos++
+	var sb strings.Builder
+	for parser.pos < len(parser.src) {
+		c := parser.src[parser.pos]
+		parser.pos++
+		if c == quote {
+			return sb.String(), nil
+		}
+		if c != '\\' || parser.pos >= len(parser.src) {
+			sb.WriteByte(c)
+			continue
+		}
+		escaped := parser.src[parser.pos]
+		if quote == '\'' {
+			// 单引号字符串只转义 \\ 与 \'
+			if escaped == '\\' || escaped == '\'' {
+				sb.WriteByte(escaped)
+				parser.pos++
+			} else {
+				sb.WriteByte('\\')
+			}
+			continue
+		}
+		parser.pos++
+		switch escaped {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'f':
+			sb.WriteByte('\f')
+		case '0':
+			sb.WriteByte(0)
+		case '\\', '"', '$':
+			sb.WriteByte(escaped)
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(escaped)
+		}
+	}
+	return nil, parser.errorf("unterminated string")
+}
+
+func (parser *phpParser) number() (interface{}, error) {
+	start := parser.pos
+	if c := parser.src[parser.pos]; c == '-' || c == '+' {
+		parser.pos++
+	}
+	for parser.pos < len(parser.src) && strings.IndexByte("0123456789.eE+-xXabcdefABCDEF_", parser.src[parser.pos]) >= 0 {
+		// 指数之外的 +/- 不属于数字
+		if c := parser.src[parser.pos]; (c == '+' || c == '-') && !strings.ContainsAny(parser.src[parser.pos-1:parser.pos], "eE") {
+			break
+		}
+		parser.pos++
+	}
+	text := strings.ReplaceAll(parser.src[start:parser.pos], "_", "")
+	if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return nil, parser.errorf("invalid number %q", text)
+}
+
+func isPHPIdentifier(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// phpUnserialize 解析 PHP serialize() 的结果，不支持引用（r/R）与自定义序列化（C）
+func phpUnserialize(data string) (interface{}, error) {
+	decoder := &phpDecoder{data: data}
+	value, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, fmt.Errorf("unserialize: trailing data at offset %d", decoder.pos)
+	}
+	return value, nil
+}
+
+type phpDecoder struct {
+	data string
+	pos  int
+}
+
+func (decoder *phpDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("unserialize at offset %d: %s", decoder.pos, fmt.Sprintf(format, args...))
+}
+
+func (decoder *phpDecoder) expect(s string) error {
+	if !strings.HasPrefix(decoder.data[decoder.pos:], s) {
+		return decoder.errorf("expected %q", s)
+	}
+	decoder.pos += len(s)
+	return nil
+}
+
+// until 读取到分隔符 delimiter 之前的内容并跳过分隔符
+func (decoder *phpDecoder) until(delimiter byte) (string, error) {
+	i := strings.IndexByte(decoder.data[decoder.pos:], delimiter)
+	if i < 0 {
+		return "", decoder.errorf("expected %q", delimiter)
+	}
+	text := decoder.data[decoder.pos : decoder.pos+i]
+	decoder.pos += i + 1
+	return text, nil
+}
+
+func (decoder *phpDecoder) length() (int, error) {
+	text, err := decoder.until(':')
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, decoder.errorf("invalid length %q", text)
+	}
+	return n, nil
+}
+
+// quoted 读取 "<n 字节>"
+func (decoder *phpDecoder) quoted(n int) (string, error) {
+	if err := decoder.expect(`"`); err != nil {
+		return "", err
+	}
+	if decoder.pos+n > len(decoder.data) {
+		return "", decoder.errorf("string length %d exceeds data", n)
+	}
+	text := decoder.data[decoder.pos : decoder.pos+n]
+	decoder.pos += n
+	return text, decoder.expect(`"`)
+}
+
+func (decoder *phpDecoder) value() (interface{}, error) {
+	if decoder.pos+1 >= len(decoder.data) {
+		return nil, decoder.errorf("unexpected end of data")
+	}
+	kind := decoder.data[decoder.pos]
+	if kind == 'N' {
+		return nil, decoder.expect("N;")
+	}
+	if err := decoder.expect(string(kind) + ":"); err != nil {
+		return nil, err
+	}
+	switch kind {
+	case 'b':
+		text, err := decoder.until(';')
+		if err != nil {
+			return nil, err
+		}
+		return text == "1", nil
+	case 'i':
+		text, err := decoder.until(';')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, decoder.errorf("invalid integer %q", text)
+		}
+		return n, nil
+	case 'd':
+		text, err := decoder.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch text {
+		case "INF":
+			return math.Inf(1), nil
+		case "-INF":
+			return math.Inf(-1), nil
+		case "NAN":
+			return math.NaN(), nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, decoder.errorf("invalid float %q", text)
+		}
+		return f, nil
+	case 's':
+		n, err := decoder.length()
+		if err != nil {
+			return nil, err
+		}
+		text, err := decoder.quoted(n)
+		if err != nil {
+			return nil, err
+		}
+		return text, decoder.expect(";")
+	case 'a':
+		n, err := decoder.length()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.members(n, false)
+	case 'O':
+		n, err := decoder.length()
+		if err != nil {
+			return nil, err
+		}
+		class, err := decoder.quoted(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := decoder.expect(":"); err != nil {
+			return nil, err
+		}
+		if n, err = decoder.length(); err != nil {
+			return nil, err
+		}
+		props, err := decoder.members(n, true)
+		if err != nil {
+			return nil, err
+		}
+		return &phpObject{class: class, props: props}, nil
+	}
+	return nil, decoder.errorf("unsupported type %q", kind)
+}
+
+// members 读取 {键;值...}，对象属性去掉 "\0*\0" 与 "\0类名\0" 前缀
+func (decoder *phpDecoder) members(n int, object bool) (*phpArray, error) {
+	if err := decoder.expect("{"); err != nil {
+		return nil, err
+	}
+	array := &phpArray{}
+	for i := 0; i < n; i++ {
+		key, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case string:
+			if object && strings.HasPrefix(k, "\x00") {
+				if j := strings.IndexByte(k[1:], 0); j >= 0 {
+					key = k[j+2:]
+				}
+			}
+		case int64:
+		default:
+			return nil, decoder.errorf("invalid key %v", key)
+		}
+		value, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		array.set(key, value)
+	}
+	return array, decoder.expect("}")
+}
@@ -0,0 +1,148 @@
+package gorbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFile 声明式策略文件，支持 YAML 与 JSON：
+//
+//	rules:
+//	  - {name: owner, executor: expression, data: "params.owner_id == user.id"}
+//	roles:
+//	  - {name: admin, description: 管理员}
+//	permissions:
+//	  - {name: edit_post, rule: owner}
+//	children:
+//	  admin: [edit_post]
+//	default_roles: [guest]
+//	assignments:
+//	  - {user_id: 1, items: [admin]}
+type PolicyFile struct {
+	Rules       []PolicyRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Roles       []PolicyItem `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Permissions []PolicyItem `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	// Children 父级 => 子级列表
+	Children     map[string][]string `json:"children,omitempty" yaml:"children,omitempty"`
+	DefaultRoles []string            `json:"default_roles,omitempty" yaml:"default_roles,omitempty"`
+	// Assignments 为 nil（文件中未出现）时不管理分配
+	Assignments []PolicyAssignment `json:"assignments,omitempty" yaml:"assignments,omitempty"`
+}
+
+type PolicyRule struct {
+	Name     string `json:"name" yaml:"name"`
+	Executor string `json:"executor,omitempty" yaml:"executor,omitempty"`
+	Data     string `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+type PolicyItem struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Rule        string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	Executor    string `json:"executor,omitempty" yaml:"executor,omitempty"`
+}
+
+type PolicyAssignment struct {
+	UserId interface{} `json:"user_id" yaml:"user_id"`
+	Items  []string    `json:"items" yaml:"items"`
+}
+
+// LoadPolicyFile 读取策略文件，扩展名为 .json 时按 JSON 解析，否则按 YAML 解析
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	return ParsePolicy(data, format)
+}
+
+// ParsePolicy 解析策略，format 为 "json" 或 "yaml"
+func ParsePolicy(data []byte, format string) (*PolicyFile, error) {
+	policy := &PolicyFile{}
+	switch strings.ToLower(format) {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(policy); err != nil {
+			return nil, fmt.Errorf("parse policy: %v", err)
+		}
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(policy); err != nil {
+			return nil, fmt.Errorf("parse policy: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown policy format %q", format)
+	}
+	for i := range policy.Assignments {
+		policy.Assignments[i].UserId = NormalizeUserId(policy.Assignments[i].UserId)
+	}
+	return policy, nil
+}
+
+// Marshal 将策略编码为 "json" 或 "yaml"
+func (policy *PolicyFile) Marshal(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return json.MarshalIndent(policy, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(policy)
+	}
+	return nil, fmt.Errorf("unknown policy format %q", format)
+}
+
+// ExportPolicy 将仓库中的规则、item、继承关系与默认角色导出为策略文件，withAssignments 为 true 时包含分配
+func (manager *DefaultManager) ExportPolicy(withAssignments bool) (*PolicyFile, error) {
+	state, err := manager.readPolicyState()
+	if err != nil {
+		return nil, err
+	}
+	policy := &PolicyFile{Children: make(map[string][]string)}
+	for _, name := range state.ruleNames() {
+		rule := state.rules[name]
+		policy.Rules = append(policy.Rules, PolicyRule{Name: rule.Name, Executor: rule.ExecuteName, Data: rule.Data})
+	}
+	for _, name := range state.itemNames() {
+		item := state.items[name]
+		entry := PolicyItem{Name: name, Description: item.GetDescription(), Rule: item.GetRuleName(), Executor: item.GetExecuteName()}
+		if item.GetType() == RoleType {
+			policy.Roles = append(policy.Roles, entry)
+		} else {
+			policy.Permissions = append(policy.Permissions, entry)
+		}
+	}
+	for _, edge := range state.edges {
+		policy.Children[edge.Parent] = append(policy.Children[edge.Parent], edge.Child)
+	}
+	for _, role := range manager.GetDefaultRoles() {
+		policy.DefaultRoles = append(policy.DefaultRoles, role.GetName())
+	}
+	policy.DefaultRoles = sortedNames(policy.DefaultRoles)
+	if withAssignments {
+		policy.Assignments = make([]PolicyAssignment, 0)
+		index := make(map[string]int)
+		for _, assignment := range state.assignments {
+			key := fmt.Sprint(assignment.UserId)
+			i, ok := index[key]
+			if !ok {
+				i = len(policy.Assignments)
+				index[key] = i
+				policy.Assignments = append(policy.Assignments, PolicyAssignment{UserId: assignment.UserId})
+			}
+			policy.Assignments[i].Items = append(policy.Assignments[i].Items, assignment.ItemName)
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,545 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TransactionalRepository 支持事务的仓库，ApplyPolicy 在一个事务中写入全部变更。
+// 仓库未实现该接口时按顺序写入，失败时已写入的变更不会回滚。
+type TransactionalRepository interface {
+	AuthRepository
+	Transaction(fn func(repo AuthRepository) error) error
+}
+
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+)
+
+// 变更对象
+const (
+	PlanKindRule        = "rule"
+	PlanKindRole        = "role"
+	PlanKindPermission  = "permission"
+	PlanKindChild       = "child"
+	PlanKindAssignment  = "assignment"
+	PlanKindDefaultRole = "default-role"
+)
+
+// PlanChange 一项变更，按 Changes 中的顺序执行
+type PlanChange struct {
+	Action PlanAction `json:"action"`
+	Kind   string     `json:"kind"`
+	Name   string     `json:"name"`
+	Detail string     `json:"detail,omitempty"`
+
+	apply func(repo AuthRepository) error
+}
+
+// PolicyPlan 策略文件与仓库的差异
+type PolicyPlan struct {
+	Changes []PlanChange `json:"changes"`
+	// Applied 变更已写入仓库
+	Applied bool `json:"applied"`
+
+	defaultRoles []*Role
+}
+
+func (plan *PolicyPlan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+// String 可读的变更计划，每行一项：+ 创建、~ 更新、- 删除
+func (plan *PolicyPlan) String() string {
+	if plan.Empty() {
+		return "No changes.\n"
+	}
+	var sb strings.Builder
+	counts := make(map[PlanAction]int)
+	for _, change := range plan.Changes {
+		sign := "+"
+		switch change.Action {
+		case PlanUpdate:
+			sign = "~"
+		case PlanDelete:
+			sign = "-"
+		}
+		counts[change.Action]++
+		fmt.Fprintf(&sb, "%s %s %s", sign, change.Kind, change.Name)
+		if change.Detail != "" {
+			fmt.Fprintf(&sb, " (%s)", change.Detail)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "Plan: %d to create, %d to update, %d to delete.\n", counts[PlanCreate], counts[PlanUpdate], counts[PlanDelete])
+	return sb.String()
+}
+
+type SyncOptions struct {
+	// DryRun 只生成计划，不写入仓库
+	DryRun bool
+	// KeepUnmanaged 不删除策略文件中未声明的规则、item、继承关系、分配与默认角色
+	KeepUnmanaged bool
+}
+
+// policyState 仓库当前的策略数据
+type policyState struct {
+	items       map[string]Item
+	rules       map[string]*Rule
+	edges       []*ItemChild
+	assignments []*Assignment
+}
+
+func (state *policyState) itemNames() []string {
+	names := make([]string, 0, len(state.items))
+	for name := range state.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (state *policyState) ruleNames() []string {
+	names := make([]string, 0, len(state.rules))
+	for name := range state.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (manager *DefaultManager) readPolicyState() (*policyState, error) {
+	state := &policyState{items: make(map[string]Item), rules: make(map[string]*Rule)}
+	items, err := manager.mapper.FindAllItems()
+	if err != nil {
+		return nil, fmt.Errorf("find all items: %v", err)
+	}
+	for _, item := range items {
+		state.items[item.GetName()] = item
+	}
+	rules, err := manager.mapper.GetRules()
+	if err != nil {
+		return nil, fmt.Errorf("get rules: %v", err)
+	}
+	for _, rule := range rules {
+		state.rules[rule.Name] = rule
+	}
+	if state.edges, err = manager.mapper.FindChildrenList(); err != nil {
+		return nil, fmt.Errorf("find children list: %v", err)
+	}
+	sort.Slice(state.edges, func(i, j int) bool {
+		if state.edges[i].Parent != state.edges[j].Parent {
+			return state.edges[i].Parent < state.edges[j].Parent
+		}
+		return state.edges[i].Child < state.edges[j].Child
+	})
+	if state.assignments, err = manager.mapper.GetAllAssignment(); err != nil {
+		return nil, fmt.Errorf("get all assignment: %v", err)
+	}
+	return state, nil
+}
+
+// PlanPolicy 校验策略文件并与仓库比较，返回变更计划
+func (manager *DefaultManager) PlanPolicy(policy *PolicyFile, options SyncOptions) (*PolicyPlan, error) {
+	state, err := manager.readPolicyState()
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.validatePolicy(policy, state, options); err != nil {
+		return nil, err
+	}
+
+	plan := &PolicyPlan{Changes: make([]PlanChange, 0)}
+	add := func(change PlanChange) {
+		plan.Changes = append(plan.Changes, change)
+	}
+	now := time.Now()
+
+	// 规则
+	declaredRules := make(map[string]bool)
+	for _, r := range policy.Rules {
+		declaredRules[r.Name] = true
+		rule := Rule{Name: r.Name, ExecuteName: r.Executor, Data: r.Data, CreateTime: now, UpdateTime: now}
+		current := state.rules[r.Name]
+		if current == nil {
+			add(PlanChange{Action: PlanCreate, Kind: PlanKindRule, Name: r.Name, Detail: ruleDetail(rule),
+				apply: func(repo AuthRepository) error { return repo.AddRule(rule) }})
+			continue
+		}
+		if current.ExecuteName != rule.ExecuteName || current.Data != rule.Data {
+			rule.CreateTime = current.CreateTime
+			add(PlanChange{Action: PlanUpdate, Kind: PlanKindRule, Name: r.Name, Detail: ruleDetail(*current) + " -> " + ruleDetail(rule),
+				apply: func(repo AuthRepository) error { return repo.UpdateRule(rule.Name, rule) }})
+		}
+	}
+
+	// item
+	declaredItems := make(map[string]bool)
+	declare := func(entry PolicyItem, itemType ItemType) {
+		declaredItems[entry.Name] = true
+		kind := PlanKindPermission
+		if itemType == RoleType {
+			kind = PlanKindRole
+		}
+		current := state.items[entry.Name]
+		createTime := now
+		if current != nil {
+			if current.GetType() == itemType && current.GetDescription() == entry.Description &&
+				current.GetRuleName() == entry.Rule && current.GetExecuteName() == entry.Executor {
+				return
+			}
+			createTime = current.GetCreateTime()
+		}
+		var item Item = NewPermission(entry.Name, entry.Description, entry.Rule, entry.Executor, createTime, now)
+		if itemType == RoleType {
+			item = NewRole(entry.Name, entry.Description, entry.Rule, entry.Executor, createTime, now)
+		}
+		if current == nil {
+			add(PlanChange{Action: PlanCreate, Kind: kind, Name: entry.Name, Detail: itemDetail(item),
+				apply: func(repo AuthRepository) error { return repo.AddItem(item) }})
+			return
+		}
+		add(PlanChange{Action: PlanUpdate, Kind: kind, Name: entry.Name, Detail: itemChanges(current, item),
+			apply: func(repo AuthRepository) error { return repo.UpdateItem(item.GetName(), item) }})
+	}
+	for _, entry := range policy.Roles {
+		declare(entry, RoleType)
+	}
+	for _, entry := range policy.Permissions {
+		declare(entry, PermissionType)
+	}
+	deletedItems := make(map[string]bool)
+	if !options.KeepUnmanaged {
+		for _, name := range state.itemNames() {
+			if !declaredItems[name] {
+				deletedItems[name] = true
+			}
+		}
+	}
+
+	// 继承关系
+	declaredEdges := make(map[string]bool)
+	for _, parent := range sortedNames(mapKeys(policy.Children)) {
+		for _, child := range policy.Children[parent] {
+			declaredEdges[parent+"\x00"+child] = true
+		}
+	}
+	currentEdges := make(map[string]bool)
+	for _, edge := range state.edges {
+		key := edge.Parent + "\x00" + edge.Child
+		currentEdges[key] = true
+		if !declaredEdges[key] && !options.KeepUnmanaged {
+			parent, child := edge.Parent, edge.Child
+			add(PlanChange{Action: PlanDelete, Kind: PlanKindChild, Name: parent + " -> " + child,
+				apply: func(repo AuthRepository) error { return repo.RemoveChild(parent, child) }})
+		}
+	}
+	for _, parent := range sortedNames(mapKeys(policy.Children)) {
+		for _, child := range policy.Children[parent] {
+			if currentEdges[parent+"\x00"+child] {
+				continue
+			}
+			itemChild := ItemChild{Parent: parent, Child: child}
+			add(PlanChange{Action: PlanCreate, Kind: PlanKindChild, Name: parent + " -> " + child,
+				apply: func(repo AuthRepository) error { return repo.AddItemChild(itemChild) }})
+		}
+	}
+
+	// 分配：文件声明了分配时按文件同步，否则只删除被删 item 的分配
+	declaredAssignments := make(map[string]bool)
+	for _, a := range policy.Assignments {
+		for _, name := range a.Items {
+			declaredAssignments[fmt.Sprint(a.UserId)+"\x00"+name] = true
+		}
+	}
+	currentAssignments := make(map[string]bool)
+	for _, assignment := range state.assignments {
+		key := fmt.Sprint(assignment.UserId) + "\x00" + assignment.ItemName
+		currentAssignments[key] = true
+		managed := policy.Assignments != nil && !declaredAssignments[key] && !options.KeepUnmanaged
+		if managed || deletedItems[assignment.ItemName] {
+			userId, name := assignment.UserId, assignment.ItemName
+			add(PlanChange{Action: PlanDelete, Kind: PlanKindAssignment, Name: fmt.Sprintf("%v -> %s", userId, name),
+				apply: func(repo AuthRepository) error { return repo.RemoveAssignment(userId, name) }})
+		}
+	}
+	for _, a := range policy.Assignments {
+		for _, name := range a.Items {
+			if currentAssignments[fmt.Sprint(a.UserId)+"\x00"+name] {
+				continue
+			}
+			assignment := *NewAssignment(manager.userId(a.UserId), name)
+			add(PlanChange{Action: PlanCreate, Kind: PlanKindAssignment, Name: fmt.Sprintf("%v -> %s", a.UserId, name),
+				apply: func(repo AuthRepository) error { return repo.Assign(assignment) }})
+		}
+	}
+
+	// 删除未声明的 item 与规则
+	for _, name := range state.itemNames() {
+		if !deletedItems[name] {
+			continue
+		}
+		kind := PlanKindPermission
+		if state.items[name].GetType() == RoleType {
+			kind = PlanKindRole
+		}
+		itemName := name
+		add(PlanChange{Action: PlanDelete, Kind: kind, Name: name,
+			apply: func(repo AuthRepository) error { return repo.RemoveItem(itemName) }})
+	}
+	if !options.KeepUnmanaged {
+		for _, name := range state.ruleNames() {
+			if declaredRules[name] {
+				continue
+			}
+			ruleName := name
+			add(PlanChange{Action: PlanDelete, Kind: PlanKindRule, Name: name,
+				apply: func(repo AuthRepository) error { return repo.RemoveRule(ruleName) }})
+		}
+	}
+
+	// 默认角色保存在管理器中，仓库事务提交后再替换
+	currentDefaults := make(map[string]bool)
+	for _, role := range manager.GetDefaultRoles() {
+		currentDefaults[role.GetName()] = true
+	}
+	declaredDefaults := make(map[string]bool)
+	for _, name := range policy.DefaultRoles {
+		declaredDefaults[name] = true
+		if !currentDefaults[name] {
+			add(PlanChange{Action: PlanCreate, Kind: PlanKindDefaultRole, Name: name})
+		}
+	}
+	for _, name := range sortedNames(boolKeys(currentDefaults)) {
+		if declaredDefaults[name] {
+			continue
+		}
+		if options.KeepUnmanaged {
+			plan.defaultRoles = append(plan.defaultRoles, manager.cache.snapshot().defaultRoles[name])
+			continue
+		}
+		add(PlanChange{Action: PlanDelete, Kind: PlanKindDefaultRole, Name: name})
+	}
+	for _, entry := range policy.Roles {
+		if declaredDefaults[entry.Name] {
+			plan.defaultRoles = append(plan.defaultRoles, NewRole(entry.Name, entry.Description, entry.Rule, entry.Executor, now, now))
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyPolicy 生成计划并写入仓库，DryRun 时只返回计划。
+// 仓库实现 TransactionalRepository 时所有变更在同一事务中执行。
+func (manager *DefaultManager) ApplyPolicy(policy *PolicyFile, options SyncOptions) (*PolicyPlan, error) {
+	return manager.applyPolicy(context.Background(), policy, options)
+}
+
+func (manager *DefaultManager) applyPolicy(ctx context.Context, policy *PolicyFile, options SyncOptions) (*PolicyPlan, error) {
+	plan, err := manager.PlanPolicy(policy, options)
+	if err != nil || options.DryRun || plan.Empty() {
+		return plan, err
+	}
+
+	run := func(repo AuthRepository) error {
+		for _, change := range plan.Changes {
+			if change.apply == nil {
+				continue
+			}
+			if err := change.apply(repo); err != nil {
+				return fmt.Errorf("%s %s %s: %v", change.Action, change.Kind, change.Name, err)
+			}
+		}
+		return nil
+	}
+	if tx, ok := manager.mapper.(TransactionalRepository); ok {
+		err = tx.Transaction(run)
+	} else {
+		err = run(manager.mapper)
+	}
+	manager.resetAllCache()
+	if err != nil {
+		return plan, err
+	}
+
+	manager.cache.setDefaultRoles(plan.defaultRoles, true)
+	plan.Applied = true
+	manager.audit(ctx, AuditEntry{Operation: AuditApplyPolicy}, nil, plan)
+	return plan, nil
+}
+
+// validatePolicy 检查名称重复、引用是否存在、继承关系是否成环以及规则数据是否有效
+func (manager *DefaultManager) validatePolicy(policy *PolicyFile, state *policyState, options SyncOptions) error {
+	rules := make(map[string]bool)
+	for _, r := range policy.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule without name")
+		}
+		if rules[r.Name] {
+			return fmt.Errorf("duplicate rule '%s'", r.Name)
+		}
+		rules[r.Name] = true
+	}
+	items := make(map[string]ItemType)
+	for _, group := range []struct {
+		entries  []PolicyItem
+		itemType ItemType
+	}{{policy.Roles, RoleType}, {policy.Permissions, PermissionType}} {
+		for _, entry := range group.entries {
+			if entry.Name == "" {
+				return fmt.Errorf("item without name")
+			}
+			if _, ok := items[entry.Name]; ok {
+				return fmt.Errorf("duplicate item '%s'", entry.Name)
+			}
+			items[entry.Name] = group.itemType
+		}
+	}
+
+	// KeepUnmanaged 时允许引用仓库中已有的规则与 item
+	ruleExists := func(name string) bool {
+		return rules[name] || (options.KeepUnmanaged && state.rules[name] != nil)
+	}
+	itemExists := func(name string) bool {
+		_, ok := items[name]
+		return ok || (options.KeepUnmanaged && state.items[name] != nil)
+	}
+
+	registry := manager.GetExecutorRegistry()
+	for _, r := range policy.Rules {
+		rule := Rule{Name: r.Name, ExecuteName: r.Executor, Data: r.Data}
+		if IsCompositeRule(&rule) {
+			operands, err := ParseCompositeOperands(rule.Data)
+			if err != nil {
+				return fmt.Errorf("rule '%s': %v", rule.Name, err)
+			}
+			for _, operand := range operands {
+				if operand.Rule && !ruleExists(operand.Name) {
+					return fmt.Errorf("rule '%s' references unknown rule '%s'", rule.Name, operand.Name)
+				}
+			}
+			continue
+		}
+		if validator, ok := registry.GetExecutor(rule.ExecuteName).(RuleValidator); ok {
+			if err := validator.ValidateRule(rule); err != nil {
+				return fmt.Errorf("rule '%s': %v", rule.Name, err)
+			}
+		}
+	}
+	for _, entry := range append(append([]PolicyItem(nil), policy.Roles...), policy.Permissions...) {
+		if entry.Rule != "" && !ruleExists(entry.Rule) {
+			return fmt.Errorf("item '%s' references unknown rule '%s'", entry.Name, entry.Rule)
+		}
+	}
+	for parent, children := range policy.Children {
+		if !itemExists(parent) {
+			return fmt.Errorf("children of unknown item '%s'", parent)
+		}
+		for _, child := range children {
+			if !itemExists(child) {
+				return fmt.Errorf("unknown child '%s' of '%s'", child, parent)
+			}
+		}
+	}
+	for _, name := range policy.DefaultRoles {
+		if items[name] != RoleType {
+			return fmt.Errorf("default role '%s' is not a declared role", name)
+		}
+	}
+	for _, a := range policy.Assignments {
+		if a.UserId == nil {
+			return fmt.Errorf("assignment without user_id")
+		}
+		for _, name := range a.Items {
+			if !itemExists(name) {
+				return fmt.Errorf("user %v is assigned to unknown item '%s'", a.UserId, name)
+			}
+		}
+	}
+
+	// 继承关系成环检测，KeepUnmanaged 时包含仓库中保留的关系
+	children := make(map[string][]string)
+	for parent, list := range policy.Children {
+		children[parent] = append(children[parent], list...)
+	}
+	if options.KeepUnmanaged {
+		for _, edge := range state.edges {
+			children[edge.Parent] = append(children[edge.Parent], edge.Child)
+		}
+	}
+	visiting := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch visiting[name] {
+		case 1:
+			return fmt.Errorf("children cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		visiting[name] = 1
+		for _, child := range children[name] {
+			if err := visit(child, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = 2
+		return nil
+	}
+	for _, parent := range sortedNames(mapKeys(children)) {
+		if err := visit(parent, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ruleDetail(rule Rule) string {
+	if rule.Data == "" {
+		return fmt.Sprintf("executor=%q", rule.ExecuteName)
+	}
+	return fmt.Sprintf("executor=%q data=%q", rule.ExecuteName, rule.Data)
+}
+
+func itemDetail(item Item) string {
+	return fmt.Sprintf("description=%q rule=%q executor=%q", item.GetDescription(), item.GetRuleName(), item.GetExecuteName())
+}
+
+// itemChanges 只列出发生变化的字段
+func itemChanges(current Item, item Item) string {
+	changes := make([]string, 0, 4)
+	if current.GetType() != item.GetType() {
+		changes = append(changes, "type: "+itemKind(current)+" -> "+itemKind(item))
+	}
+	for _, field := range []struct{ name, from, to string }{
+		{"description", current.GetDescription(), item.GetDescription()},
+		{"rule", current.GetRuleName(), item.GetRuleName()},
+		{"executor", current.GetExecuteName(), item.GetExecuteName()},
+	} {
+		if field.from != field.to {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", field.name, field.from, field.to))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+func itemKind(item Item) string {
+	if item.GetType() == RoleType {
+		return "role"
+	}
+	return "permission"
+}
+
+func boolKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
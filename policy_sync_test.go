@@ -0,0 +1,204 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testPolicyYAML = `
+rules:
+  - {name: owner, executor: expression, data: "params.owner_id == user.id"}
+roles:
+  - {name: admin}
+  - {name: editor, description: Editor}
+permissions:
+  - {name: "posts:edit", rule: owner}
+  - {name: "posts:delete"}
+children:
+  admin: [editor]
+  editor: ["posts:edit", "posts:delete"]
+default_roles: [editor]
+`
+
+func planLines(plan *PolicyPlan) []string {
+	lines := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		lines = append(lines, fmt.Sprintf("%s %s %s", change.Action, change.Kind, change.Name))
+	}
+	return lines
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+		err    string
+		check  func(policy *PolicyFile) error
+	}{
+		{"yaml", testPolicyYAML, "yaml", "", func(p *PolicyFile) error {
+			if len(p.Rules) != 1 || len(p.Roles) != 2 || len(p.Permissions) != 2 || len(p.Children["editor"]) != 2 {
+				return fmt.Errorf("unexpected policy %+v", p)
+			}
+			if p.Assignments != nil {
+				return fmt.Errorf("assignments = %v, want nil when absent", p.Assignments)
+			}
+			return nil
+		}},
+		{"json user id", `{"roles": [{"name": "admin"}], "assignments": [{"user_id": 5, "items": ["admin"]}]}`, "json", "", func(p *PolicyFile) error {
+			if p.Assignments[0].UserId != 5 {
+				return fmt.Errorf("user id = %#v, want int 5", p.Assignments[0].UserId)
+			}
+			return nil
+		}},
+		{"yaml unknown field", "roles:\n  - {name: admin, colour: red}\n", "yml", "colour", nil},
+		{"json unknown field", `{"groups": []}`, "json", "groups", nil},
+		{"unknown format", "", "toml", "unknown policy format", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tt.data), tt.format)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParsePolicy error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.check(policy); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPlanPolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicyYAML), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		options SyncOptions
+		want    []string
+	}{
+		{"full sync", SyncOptions{}, []string{
+			"create rule owner",
+			"update role editor",
+			"update permission posts:edit",
+			"create permission posts:delete",
+			"delete child admin -> viewer",
+			"delete child viewer -> posts:view",
+			"create child editor -> posts:delete",
+			"delete permission posts:view",
+			"delete role viewer",
+			"create default-role editor",
+		}},
+		{"keep unmanaged", SyncOptions{KeepUnmanaged: true}, []string{
+			"create rule owner",
+			"update role editor",
+			"update permission posts:edit",
+			"create permission posts:delete",
+			"create child editor -> posts:delete",
+			"create default-role editor",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTestManager(t, true)
+			plan, err := manager.PlanPolicy(policy, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(planLines(plan), "\n"); got != strings.Join(tt.want, "\n") {
+				t.Errorf("plan:\n%s\nwant:\n%s", got, strings.Join(tt.want, "\n"))
+			}
+
+			dry, err := manager.ApplyPolicy(policy, SyncOptions{DryRun: true, KeepUnmanaged: tt.options.KeepUnmanaged})
+			if err != nil || dry.Applied || manager.GetPermission("posts:delete") != nil {
+				t.Fatalf("dry run applied changes: %v, %+v", err, dry)
+			}
+
+			applied, err := manager.ApplyPolicy(policy, tt.options)
+			if err != nil || !applied.Applied {
+				t.Fatalf("ApplyPolicy = %+v, %v", applied, err)
+			}
+			again, err := manager.PlanPolicy(policy, tt.options)
+			if err != nil || !again.Empty() {
+				t.Errorf("plan after apply = %v, %v, want no changes", planLines(again), err)
+			}
+
+			ctx := WithParams(context.Background(), map[string]interface{}{"owner_id": 1})
+			if !manager.CheckAccess(ctx, 1, "posts:delete") || !manager.CheckAccess(ctx, 1, "posts:edit") {
+				t.Error("user 1 lost access granted by the policy")
+			}
+			if manager.CheckAccess(WithParams(context.Background(), map[string]interface{}{"owner_id": 2}), 1, "posts:edit") {
+				t.Error("posts:edit allowed although the owner rule fails")
+			}
+			if got := manager.CheckAccess(ctx, 1, "posts:view"); got != tt.options.KeepUnmanaged {
+				t.Errorf("posts:view allowed = %v, want %v", got, tt.options.KeepUnmanaged)
+			}
+		})
+	}
+}
+
+func TestPlanPolicyAssignments(t *testing.T) {
+	manager := newTestManager(t, true)
+	manager.Assign(manager.GetRole("viewer"), 2)
+	policy, err := ParsePolicy([]byte(`{
+		"roles": [{"name": "admin"}, {"name": "editor"}, {"name": "viewer"}],
+		"permissions": [{"name": "posts:edit"}, {"name": "posts:view"}],
+		"children": {"admin": ["editor", "viewer"], "editor": ["posts:edit"], "viewer": ["posts:view"]},
+		"assignments": [{"user_id": 1, "items": ["admin"]}, {"user_id": 3, "items": ["editor"]}]
+	}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := manager.ApplyPolicy(policy, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[delete assignment 2 -> viewer create assignment 3 -> editor]"
+	if got := fmt.Sprint(planLines(plan)); got != want {
+		t.Errorf("plan = %s, want %s", got, want)
+	}
+	if manager.CheckAccess(context.Background(), 2, "posts:view") || !manager.CheckAccess(context.Background(), 3, "posts:edit") {
+		t.Error("assignments were not synced")
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{"duplicate rule", `{"rules": [{"name": "a", "executor": "x"}, {"name": "a", "executor": "x"}]}`, "duplicate rule"},
+		{"duplicate item", `{"roles": [{"name": "a"}], "permissions": [{"name": "a"}]}`, "duplicate item"},
+		{"unknown rule", `{"roles": [{"name": "a", "rule": "ghost"}]}`, "unknown rule"},
+		{"composite unknown rule", `{"rules": [{"name": "c", "executor": "and", "data": "rule:ghost"}]}`, "unknown rule 'ghost'"},
+		{"invalid expression", `{"rules": [{"name": "e", "executor": "expression", "data": "params.x =="}]}`, "rule 'e'"},
+		{"unknown child", `{"roles": [{"name": "a"}], "children": {"a": ["b"]}}`, "unknown child"},
+		{"unknown parent", `{"roles": [{"name": "a"}], "children": {"b": ["a"]}}`, "unknown item"},
+		{"cycle", `{"roles": [{"name": "a"}, {"name": "b"}], "children": {"a": ["b"], "b": ["a"]}}`, "cycle"},
+		{"default role not declared", `{"permissions": [{"name": "a"}], "default_roles": ["a"]}`, "not a declared role"},
+		{"assignment without user", `{"roles": [{"name": "a"}], "assignments": [{"items": ["a"]}]}`, "without user_id"},
+		{"assignment to unknown item", `{"assignments": [{"user_id": 1, "items": ["ghost"]}]}`, "unknown item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tt.policy), "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			manager := newTestManager(t, true)
+			if _, err := manager.PlanPolicy(policy, SyncOptions{}); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("PlanPolicy error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package gorbac
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -14,6 +16,13 @@ func NewRbacService(repos AuthRepository, cache bool) *RbacService {
 	return NewRbacServiceWithManager(manager)
 }
 
+// NewRbacServiceWithRegistry 创建使用独立执行器注册表的服务
+func NewRbacServiceWithRegistry(repos AuthRepository, cache bool, registry *ExecutorRegistry) *RbacService {
+	manager := NewDefaultManager(repos, cache)
+	manager.SetExecutorRegistry(registry)
+	return NewRbacServiceWithManager(manager)
+}
+
 func NewRbacServiceWithManager(mgr AuthManager) *RbacService {
 	return &RbacService{mgr: mgr}
 }
@@ -115,11 +124,48 @@ func (s RbacService) AddRule(name string, executeName string) bool {
 	return s.mgr.AddRule(*rule)
 }
 
+// AddExpressionRule 添加由内置表达式执行器求值的规则，表达式无效时返回解析/类型错误
+func (s RbacService) AddExpressionRule(name string, expression string) error {
+	rule := NewExpressionRule(name, expression, time.Now(), time.Now())
+	if err := s.mgr.ValidateRule(*rule); err != nil {
+		return err
+	}
+	if !s.mgr.AddRule(*rule) {
+		return fmt.Errorf("add rule %s failed", name)
+	}
+	return nil
+}
+
 func (s RbacService) UpdateRule(name string, newName string, executeName string) bool {
 	rule := NewRule(newName, executeName, time.Now(), time.Now())
 	return s.mgr.UpdateRule(name, *rule)
 }
 
+// AddCompositeRule 添加组合规则，operator 为 CompositeAnd/CompositeOr/CompositeNot，
+// operands 形如 "rule:owner"、"executor:business_hours"
+func (s RbacService) AddCompositeRule(name string, operator string, operands ...string) error {
+	rule := NewCompositeRule(name, operator, operands, time.Now(), time.Now())
+	if err := s.mgr.ValidateRule(*rule); err != nil {
+		return err
+	}
+	if !s.mgr.AddRule(*rule) {
+		return fmt.Errorf("add rule %s failed", name)
+	}
+	return nil
+}
+
+// UpdateExpressionRule 更新表达式规则，表达式无效时返回解析/类型错误
+func (s RbacService) UpdateExpressionRule(name string, newName string, expression string) error {
+	rule := NewExpressionRule(newName, expression, time.Now(), time.Now())
+	if err := s.mgr.ValidateRule(*rule); err != nil {
+		return err
+	}
+	if !s.mgr.UpdateRule(name, *rule) {
+		return fmt.Errorf("update rule %s failed", name)
+	}
+	return nil
+}
+
 func (s RbacService) DeleteRule(name string) bool {
 	rule := s.mgr.GetRule(name)
 	if rule == nil {
@@ -128,6 +174,36 @@ func (s RbacService) DeleteRule(name string) bool {
 	return s.mgr.RemoveRule(*rule)
 }
 
+// ---------------------- Executor ---------------------------
+
+// executorRegistryProvider 持有执行器注册表的管理器，DefaultManager 及嵌入它的管理器均实现该接口
+type executorRegistryProvider interface {
+	GetExecutorRegistry() *ExecutorRegistry
+}
+
+// executors 管理器的执行器注册表，管理器未提供注册表时使用全局 ExecuteManager
+func (s RbacService) executors() *ExecutorRegistry {
+	if provider, ok := s.mgr.(executorRegistryProvider); ok {
+		return provider.GetExecutorRegistry()
+	}
+	return ExecuteManager
+}
+
+func (s RbacService) Executors() []Executor {
+	return s.executors().Executors()
+}
+
+func (s RbacService) RegisterExecutor(executors ...Executor) {
+	registry := s.executors()
+	for _, executor := range executors {
+		registry.AddExecutor(executor)
+	}
+}
+
+func (s RbacService) RemoveExecutor(name string) {
+	s.executors().RemoveExecutor(name)
+}
+
 // ------------------- Assign --------------------------
 
 func (s RbacService) Assign(userId interface{}, name string) bool {
@@ -157,3 +233,83 @@ func (s RbacService) Assigns(userId interface{}, names ...string) {
 func (s RbacService) GetChildren(name string) []Item {
 	return s.mgr.GetChildren(name)
 }
+
+func (s RbacService) GetParents(name string) []Item {
+	return s.mgr.GetParents(name)
+}
+
+func (s RbacService) GetAncestors(name string) []HierarchyNode {
+	return s.mgr.GetAncestors(name)
+}
+
+func (s RbacService) GetDescendants(name string, itemType ItemType, maxDepth int) []HierarchyNode {
+	return s.mgr.GetDescendants(name, itemType, maxDepth)
+}
+
+func (s RbacService) PathsBetween(ancestor string, descendant string) [][]string {
+	return s.mgr.PathsBetween(ancestor, descendant)
+}
+
+// ExportDOT 导出 Graphviz DOT 层级图
+func (s RbacService) ExportDOT(w io.Writer, options GraphOptions) error {
+	return s.mgr.ExportDOT(w, options)
+}
+
+// ExportMermaid 导出 Mermaid 层级图
+func (s RbacService) ExportMermaid(w io.Writer, options GraphOptions) error {
+	return s.mgr.ExportMermaid(w, options)
+}
+
+// Lint 检查策略，fix 为 true 时自动修复安全的问题
+func (s RbacService) Lint(fix bool) ([]LintFinding, error) {
+	return s.mgr.Lint(LintOptions{Fix: fix})
+}
+
+// ApplyPolicyFile 读取 YAML/JSON 策略文件并同步到仓库，返回变更计划
+func (s RbacService) ApplyPolicyFile(path string, options SyncOptions) (*PolicyPlan, error) {
+	policy, err := LoadPolicyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.mgr.ApplyPolicy(policy, options)
+}
+
+func (s RbacService) Explain(ctx context.Context, userId interface{}, permission string) *Decision {
+	return s.mgr.Explain(ctx, userId, permission)
+}
+
+// WhoCan 反查拥有权限的用户
+func (s RbacService) WhoCan(ctx context.Context, permission string, options WhoCanOptions) *WhoCanResult {
+	return s.mgr.WhoCan(ctx, permission, options)
+}
+
+// ---------------------- Audit ---------------------------
+
+// contextBinder 可返回绑定 ctx 视图的管理器，DefaultManager 及嵌入它的管理器均实现该接口
+type contextBinder interface {
+	WithContext(ctx context.Context) *AuditedManager
+}
+
+// WithContext 返回绑定 ctx 的服务，写操作的审计记录取 ctx 中的操作人与元数据；
+// 管理器不支持绑定 ctx 时返回使用原管理器的服务
+func (s RbacService) WithContext(ctx context.Context) *RbacService {
+	if binder, ok := s.mgr.(contextBinder); ok {
+		return NewRbacServiceWithManager(binder.WithContext(ctx))
+	}
+	return NewRbacServiceWithManager(s.mgr)
+}
+
+// AuditByActor 操作人的审计记录，按时间倒序，limit 为 0 时不限制条数
+func (s RbacService) AuditByActor(actor string, limit int) ([]*AuditEntry, error) {
+	return s.mgr.QueryAudit(AuditFilter{Actor: actor, Limit: limit})
+}
+
+// AuditByItem 涉及 item 或规则的审计记录，包括以其为父级或子级的继承关系变更
+func (s RbacService) AuditByItem(name string, limit int) ([]*AuditEntry, error) {
+	return s.mgr.QueryAudit(AuditFilter{Item: name, Limit: limit})
+}
+
+// AuditByUser 用户分配的审计记录
+func (s RbacService) AuditByUser(userId interface{}, limit int) ([]*AuditEntry, error) {
+	return s.mgr.QueryAudit(AuditFilter{UserId: userId, Limit: limit})
+}
@@ -0,0 +1,40 @@
+package gorbac
+
+import "sync"
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+}
+
+// flightGroup 合并同一 key 的并发加载，只有第一个调用者真正执行 fn，其余等待并共享结果
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[interface{}]*flightCall
+}
+
+func (g *flightGroup) do(key interface{}, fn func() interface{}) interface{} {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[interface{}]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val
+	}
+	call := new(flightCall)
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.val = fn()
+	return call.val
+}
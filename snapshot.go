@@ -0,0 +1,416 @@
+package gorbac
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion 快照格式版本。快照为 JSON Lines：首行 header，随后依次为 rule、item、child、assignment 记录，末行 end 记录各类记录数。
+const SnapshotVersion = 1
+
+const (
+	snapshotHeader     = "header"
+	snapshotRule       = "rule"
+	snapshotItem       = "item"
+	snapshotChild      = "child"
+	snapshotAssignment = "assignment"
+	snapshotEnd        = "end"
+)
+
+// ImportMode 快照导入方式
+type ImportMode int
+
+const (
+	// ImportMerge 保留仓库中已有的数据，同名的 item 与规则以快照为准
+	ImportMerge ImportMode = iota
+	// ImportReplace 清空仓库后写入快照
+	ImportReplace
+)
+
+type ImportOptions struct {
+	Mode ImportMode
+}
+
+// SnapshotStats 快照中各类记录数
+type SnapshotStats struct {
+	Rules       int `json:"rules"`
+	Items       int `json:"items"`
+	Children    int `json:"children"`
+	Assignments int `json:"assignments"`
+}
+
+type snapshotRecord struct {
+	Kind       string              `json:"kind"`
+	Version    int                 `json:"version,omitempty"`
+	CreatedAt  *time.Time          `json:"created_at,omitempty"`
+	Rule       *Rule               `json:"rule,omitempty"`
+	Item       *snapshotItemRecord `json:"item,omitempty"`
+	Child      *ItemChild          `json:"child,omitempty"`
+	Assignment *Assignment         `json:"assignment,omitempty"`
+	Stats      *SnapshotStats      `json:"stats,omitempty"`
+}
+
+type snapshotItemRecord struct {
+	Type        ItemType  `json:"type"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	RuleName    string    `json:"rule_name"`
+	ExecuteName string    `json:"execute_name"`
+	CreateTime  time.Time `json:"create_time"`
+	UpdateTime  time.Time `json:"update_time"`
+}
+
+func (record *snapshotItemRecord) item() Item {
+	if record.Type == RoleType {
+		return NewRole(record.Name, record.Description, record.RuleName, record.ExecuteName, record.CreateTime, record.UpdateTime)
+	}
+	return NewPermission(record.Name, record.Description, record.RuleName, record.ExecuteName, record.CreateTime, record.UpdateTime)
+}
+
+// ExportSnapshot 先读取仓库中的规则、item、继承关系与分配，再逐条编码写入 w，不在内存中缓冲输出。
+// 仓库实现 TransactionalRepository 时四类数据在一个事务中读取；写出期间不再访问仓库，较慢的 w 不会扩大读取之间的间隔。
+// 读取失败时不写出任何内容；写出中途出错时 w 中的快照缺少 end 记录，ImportSnapshot 会拒绝导入。
+func ExportSnapshot(repo AuthRepository, w io.Writer) (*SnapshotStats, error) {
+	snapshot, err := loadSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := json.NewEncoder(w)
+	stats := &SnapshotStats{}
+	now := time.Now()
+	if err := encoder.Encode(snapshotRecord{Kind: snapshotHeader, Version: SnapshotVersion, CreatedAt: &now}); err != nil {
+		return nil, err
+	}
+	for _, rule := range snapshot.rules {
+		if err := encoder.Encode(snapshotRecord{Kind: snapshotRule, Rule: rule}); err != nil {
+			return nil, err
+		}
+		stats.Rules++
+	}
+
+	for _, item := range snapshot.items {
+		record := &snapshotItemRecord{
+			Type:        item.GetType(),
+			Name:        item.GetName(),
+			Description: item.GetDescription(),
+			RuleName:    item.GetRuleName(),
+			ExecuteName: item.GetExecuteName(),
+			CreateTime:  item.GetCreateTime(),
+			UpdateTime:  item.GetUpdateTime(),
+		}
+		if err := encoder.Encode(snapshotRecord{Kind: snapshotItem, Item: record}); err != nil {
+			return nil, err
+		}
+		stats.Items++
+	}
+
+	for _, child := range snapshot.children {
+		if err := encoder.Encode(snapshotRecord{Kind: snapshotChild, Child: child}); err != nil {
+			return nil, err
+		}
+		stats.Children++
+	}
+
+	for _, assignment := range snapshot.assignments {
+		if err := encoder.Encode(snapshotRecord{Kind: snapshotAssignment, Assignment: assignment}); err != nil {
+			return nil, err
+		}
+		stats.Assignments++
+	}
+	if err := encoder.Encode(snapshotRecord{Kind: snapshotEnd, Stats: stats}); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// loadSnapshot 读取导出所需的全部数据，仓库支持事务时在同一事务中读取
+func loadSnapshot(repo AuthRepository) (*snapshotData, error) {
+	snapshot := &snapshotData{}
+	load := func(repo AuthRepository) error {
+		var err error
+		if snapshot.rules, err = repo.GetRules(); err != nil {
+			return fmt.Errorf("get rules: %v", err)
+		}
+		if snapshot.items, err = repo.FindAllItems(); err != nil {
+			return fmt.Errorf("find all items: %v", err)
+		}
+		if snapshot.children, err = repo.FindChildrenList(); err != nil {
+			return fmt.Errorf("find children list: %v", err)
+		}
+		if snapshot.assignments, err = repo.GetAllAssignment(); err != nil {
+			return fmt.Errorf("get all assignment: %v", err)
+		}
+		return nil
+	}
+	var err error
+	if tx, ok := repo.(TransactionalRepository); ok {
+		err = tx.Transaction(load)
+	} else {
+		err = load(repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ImportSnapshot 读取完整快照并校验（版本、记录数、引用与继承环）后写入仓库，
+// 仓库实现 TransactionalRepository 时在一个事务中写入。
+func ImportSnapshot(repo AuthRepository, r io.Reader, options ImportOptions) (*SnapshotStats, error) {
+	snapshot, err := readSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.importTo(repo, options)
+}
+
+type snapshotData struct {
+	rules       []*Rule
+	items       []Item
+	children    []*ItemChild
+	assignments []*Assignment
+	stats       *SnapshotStats
+}
+
+func readSnapshot(r io.Reader) (*snapshotData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	snapshot := &snapshotData{stats: &SnapshotStats{}}
+	line := 0
+	ended := false
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if ended {
+			return nil, fmt.Errorf("snapshot line %d: record after end", line)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		var record snapshotRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("snapshot line %d: %v", line, err)
+		}
+		if line == 1 {
+			if record.Kind != snapshotHeader {
+				return nil, fmt.Errorf("snapshot line 1: missing header")
+			}
+			if record.Version != SnapshotVersion {
+				return nil, fmt.Errorf("unsupported snapshot version %d", record.Version)
+			}
+			continue
+		}
+		switch {
+		case record.Kind == snapshotRule && record.Rule != nil:
+			snapshot.rules = append(snapshot.rules, record.Rule)
+			snapshot.stats.Rules++
+		case record.Kind == snapshotItem && record.Item != nil:
+			snapshot.items = append(snapshot.items, record.Item.item())
+			snapshot.stats.Items++
+		case record.Kind == snapshotChild && record.Child != nil:
+			snapshot.children = append(snapshot.children, record.Child)
+			snapshot.stats.Children++
+		case record.Kind == snapshotAssignment && record.Assignment != nil:
+			record.Assignment.UserId = NormalizeUserId(record.Assignment.UserId)
+			snapshot.assignments = append(snapshot.assignments, record.Assignment)
+			snapshot.stats.Assignments++
+		case record.Kind == snapshotEnd && record.Stats != nil:
+			if *record.Stats != *snapshot.stats {
+				return nil, fmt.Errorf("snapshot is incomplete: expected %+v, read %+v", *record.Stats, *snapshot.stats)
+			}
+			ended = true
+		default:
+			return nil, fmt.Errorf("snapshot line %d: invalid %q record", line, record.Kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("empty snapshot")
+	}
+	if !ended {
+		return nil, fmt.Errorf("snapshot is truncated: missing end record")
+	}
+	return snapshot, nil
+}
+
+// importTo 校验后写入仓库，仓库实现 TransactionalRepository 时在一个事务中写入
+func (snapshot *snapshotData) importTo(repo AuthRepository, options ImportOptions) (*SnapshotStats, error) {
+	if err := snapshot.validate(repo, options); err != nil {
+		return nil, err
+	}
+
+	run := func(repo AuthRepository) error {
+		return snapshot.write(repo, options)
+	}
+	var err error
+	if tx, ok := repo.(TransactionalRepository); ok {
+		err = tx.Transaction(run)
+	} else {
+		err = run(repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.stats, nil
+}
+
+// validate 检查引用与继承环，合并导入时包含仓库中已有的数据
+func (snapshot *snapshotData) validate(repo AuthRepository, options ImportOptions) error {
+	items := make(map[string]bool)
+	rules := make(map[string]bool)
+	children := make(map[string][]string)
+	if options.Mode == ImportMerge {
+		existing, err := repo.FindAllItems()
+		if err != nil {
+			return fmt.Errorf("find all items: %v", err)
+		}
+		for _, item := range existing {
+			items[item.GetName()] = true
+		}
+		existingRules, err := repo.GetRules()
+		if err != nil {
+			return fmt.Errorf("get rules: %v", err)
+		}
+		for _, rule := range existingRules {
+			rules[rule.Name] = true
+		}
+		edges, err := repo.FindChildrenList()
+		if err != nil {
+			return fmt.Errorf("find children list: %v", err)
+		}
+		for _, edge := range edges {
+			children[edge.Parent] = append(children[edge.Parent], edge.Child)
+		}
+	}
+
+	for _, rule := range snapshot.rules {
+		rules[rule.Name] = true
+	}
+	for _, item := range snapshot.items {
+		items[item.GetName()] = true
+	}
+	for _, item := range snapshot.items {
+		if item.GetRuleName() != "" && !rules[item.GetRuleName()] {
+			return fmt.Errorf("item '%s' references unknown rule '%s'", item.GetName(), item.GetRuleName())
+		}
+	}
+	for _, child := range snapshot.children {
+		if !items[child.Parent] || !items[child.Child] {
+			return fmt.Errorf("child relation %s -> %s references an unknown item", child.Parent, child.Child)
+		}
+		children[child.Parent] = append(children[child.Parent], child.Child)
+	}
+	for _, assignment := range snapshot.assignments {
+		if !items[assignment.ItemName] {
+			return fmt.Errorf("user %v is assigned to unknown item '%s'", assignment.UserId, assignment.ItemName)
+		}
+	}
+
+	// 0 = unvisited, 1 = visiting, 2 = done
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("children cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, child := range children[name] {
+			if err := visit(child, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for _, parent := range sortedNames(mapKeys(children)) {
+		if err := visit(parent, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write 合并导入时同名的规则与 item 以快照为准，已存在的继承关系与分配跳过
+func (snapshot *snapshotData) write(repo AuthRepository, options ImportOptions) error {
+	if options.Mode == ImportReplace {
+		if err := repo.RemoveAll(); err != nil {
+			return fmt.Errorf("remove all: %v", err)
+		}
+	}
+	merge := options.Mode == ImportMerge
+
+	for _, rule := range snapshot.rules {
+		var err error
+		if existing, _ := repo.GetRule(rule.Name); merge && existing != nil {
+			err = repo.UpdateRule(rule.Name, *rule)
+		} else {
+			err = repo.AddRule(*rule)
+		}
+		if err != nil {
+			return fmt.Errorf("rule '%s': %v", rule.Name, err)
+		}
+	}
+	for _, item := range snapshot.items {
+		var err error
+		if existing, _ := repo.GetItem(item.GetName()); merge && existing != nil {
+			err = repo.UpdateItem(item.GetName(), item)
+		} else {
+			err = repo.AddItem(item)
+		}
+		if err != nil {
+			return fmt.Errorf("item '%s': %v", item.GetName(), err)
+		}
+	}
+	for _, child := range snapshot.children {
+		if merge && repo.HasChild(child.Parent, child.Child) {
+			continue
+		}
+		if err := repo.AddItemChild(*child); err != nil {
+			return fmt.Errorf("child %s -> %s: %v", child.Parent, child.Child, err)
+		}
+	}
+	for _, assignment := range snapshot.assignments {
+		if merge {
+			if existing, _ := repo.GetAssignment(assignment.UserId, assignment.ItemName); existing != nil {
+				continue
+			}
+		}
+		if err := repo.Assign(*assignment); err != nil {
+			return fmt.Errorf("assignment '%s' of user %v: %v", assignment.ItemName, assignment.UserId, err)
+		}
+	}
+	return nil
+}
+
+// ExportSnapshot 导出管理器所用仓库的快照
+func (manager *DefaultManager) ExportSnapshot(w io.Writer) (*SnapshotStats, error) {
+	return ExportSnapshot(manager.mapper, w)
+}
+
+// ImportSnapshot 向管理器所用仓库导入快照并清除缓存
+func (manager *DefaultManager) ImportSnapshot(r io.Reader, options ImportOptions) (*SnapshotStats, error) {
+	return manager.importSnapshot(context.Background(), r, options)
+}
+
+func (manager *DefaultManager) importSnapshot(ctx context.Context, r io.Reader, options ImportOptions) (*SnapshotStats, error) {
+	stats, err := ImportSnapshot(manager.mapper, r, options)
+	manager.resetAllCache()
+	if err == nil {
+		manager.audit(ctx, AuditEntry{Operation: AuditImportSnapshot}, nil, stats)
+	}
+	return stats, err
+}
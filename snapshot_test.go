@@ -0,0 +1,214 @@
+package gorbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportTestSnapshot(t *testing.T) []byte {
+	t.Helper()
+	manager := newTestManager(t, false)
+	if !manager.AddRule(*NewRule("owner", "yes", time.Now(), time.Now())) {
+		t.Fatal("AddRule failed")
+	}
+	var buf bytes.Buffer
+	stats, err := manager.ExportSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SnapshotStats{Rules: 1, Items: 5, Children: 4, Assignments: 1}); *stats != want {
+		t.Fatalf("export stats = %+v, want %+v", *stats, want)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	data := exportTestSnapshot(t)
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		mode  ImportMode
+		items int
+	}{
+		{"merge keeps existing", ImportMerge, 6},
+		{"replace clears existing", ImportReplace, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			if err := repo.AddItem(NewRole("guest", "", "", "", now, now)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ImportSnapshot(repo, bytes.NewReader(data), ImportOptions{Mode: tt.mode}); err != nil {
+				t.Fatal(err)
+			}
+			items, _ := repo.FindAllItems()
+			if len(items) != tt.items {
+				t.Errorf("items = %d, want %d", len(items), tt.items)
+			}
+			manager := NewDefaultManager(repo, false)
+			if !manager.CheckAccess(context.Background(), 1, "posts:edit") {
+				t.Error("posts:edit denied after import")
+			}
+			if manager.GetRule("owner") == nil {
+				t.Error("rule owner missing after import")
+			}
+		})
+	}
+}
+
+func TestSnapshotImportRejects(t *testing.T) {
+	data := string(exportTestSnapshot(t))
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"empty", "", "empty snapshot"},
+		{"missing header", strings.Join(lines[1:], "\n"), "missing header"},
+		{"wrong version", strings.Replace(data, `"version":1`, `"version":9`, 1), "unsupported snapshot version 9"},
+		{"truncated", strings.Join(lines[:len(lines)-1], "\n"), "missing end record"},
+		{"stats mismatch", strings.Join(append(lines[:3:3], lines[4:]...), "\n"), "snapshot is incomplete"},
+		{"record after end", data + lines[1] + "\n", "record after end"},
+		{"invalid record", strings.Join([]string{lines[0], `{"kind":"bogus"}`}, "\n"), `invalid "bogus" record`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			_, err := ImportSnapshot(repo, strings.NewReader(tt.input), ImportOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("ImportSnapshot error = %v, want it to contain %q", err, tt.err)
+			}
+			if items, _ := repo.FindAllItems(); len(items) != 0 {
+				t.Errorf("rejected snapshot wrote %d items", len(items))
+			}
+		})
+	}
+}
+
+// failingAssignmentRepository 在读取分配时失败，不支持事务
+type failingAssignmentRepository struct {
+	AuthRepository
+}
+
+func (repo failingAssignmentRepository) GetAllAssignment() ([]*Assignment, error) {
+	return nil, errors.New("connection lost")
+}
+
+// failingWriter 写出 limit 字节后失败，模拟连接中断
+type failingWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errors.New("connection reset")
+	}
+	return w.buf.Write(p)
+}
+
+func TestSnapshotExportFailure(t *testing.T) {
+	repo := failingAssignmentRepository{NewMemoryRepository()}
+	newTestManagerWith(t, repo, false)
+	var buf bytes.Buffer
+	if _, err := ExportSnapshot(repo, &buf); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("ExportSnapshot error = %v, want connection lost", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("read failure wrote %d bytes", buf.Len())
+	}
+
+	manager := newTestManager(t, false)
+	w := &failingWriter{limit: 300}
+	if _, err := ExportSnapshot(manager.mapper, w); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("ExportSnapshot error = %v, want connection reset", err)
+	}
+	if w.buf.Len() == 0 {
+		t.Fatal("records before the failure were not written")
+	}
+	if _, err := ImportSnapshot(NewMemoryRepository(), &w.buf, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "missing end record") {
+		t.Errorf("ImportSnapshot error = %v, want missing end record", err)
+	}
+}
+
+// transactionRecordingRepository 记录每次读取是否在事务内
+type transactionRecordingRepository struct {
+	*MemoryRepository
+	inTransaction bool
+	reads         []string
+}
+
+func (repo *transactionRecordingRepository) Transaction(fn func(repo AuthRepository) error) error {
+	return repo.MemoryRepository.Transaction(func(AuthRepository) error {
+		repo.inTransaction = true
+		defer func() { repo.inTransaction = false }()
+		return fn(repo)
+	})
+}
+
+func (repo *transactionRecordingRepository) read(name string) {
+	if repo.inTransaction {
+		name += " in transaction"
+	}
+	repo.reads = append(repo.reads, name)
+}
+
+func (repo *transactionRecordingRepository) GetRules() ([]*Rule, error) {
+	repo.read("rules")
+	return repo.MemoryRepository.GetRules()
+}
+
+func (repo *transactionRecordingRepository) FindAllItems() ([]Item, error) {
+	repo.read("items")
+	return repo.MemoryRepository.FindAllItems()
+}
+
+func (repo *transactionRecordingRepository) FindChildrenList() ([]*ItemChild, error) {
+	repo.read("children")
+	return repo.MemoryRepository.FindChildrenList()
+}
+
+func (repo *transactionRecordingRepository) GetAllAssignment() ([]*Assignment, error) {
+	repo.read("assignments")
+	return repo.MemoryRepository.GetAllAssignment()
+}
+
+// readsBeforeWriter 记录第一次写出时已经完成的读取
+type readsBeforeWriter struct {
+	repo  *transactionRecordingRepository
+	reads []string
+}
+
+func (w *readsBeforeWriter) Write(p []byte) (int, error) {
+	if w.reads == nil {
+		w.reads = append([]string{}, w.repo.reads...)
+	}
+	return len(p), nil
+}
+
+func TestSnapshotExportReadsInOneTransaction(t *testing.T) {
+	repo := &transactionRecordingRepository{MemoryRepository: NewMemoryRepository()}
+	newTestManagerWith(t, repo, false)
+	repo.reads = nil
+
+	w := &readsBeforeWriter{repo: repo}
+	if _, err := ExportSnapshot(repo, w); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"rules in transaction", "items in transaction", "children in transaction", "assignments in transaction"}
+	if !reflect.DeepEqual(w.reads, want) {
+		t.Errorf("reads before the first write = %v, want %v", w.reads, want)
+	}
+	if !reflect.DeepEqual(repo.reads, want) {
+		t.Errorf("reads = %v, want %v", repo.reads, want)
+	}
+}
@@ -0,0 +1,526 @@
+package gorbac
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect SQL 方言，决定占位符风格
+type SQLDialect int
+
+const (
+	// DialectMySQL 使用 ? 占位符
+	DialectMySQL SQLDialect = iota
+	// DialectPostgres 使用 $1、$2 占位符
+	DialectPostgres
+	// DialectSQLite 使用 ? 占位符
+	DialectSQLite
+)
+
+type SQLOptions struct {
+	Dialect SQLDialect
+	// UserId 将 user_id 列还原为用户 ID，默认十进制整数转为 int，其余保持字符串
+	UserId func(id string) interface{}
+}
+
+// sqlQuerier *sql.DB 与 *sql.Tx 的公共方法
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SQLRepository 基于 database/sql 的 AuthRepository，表名取 GetTableName，建表见 CreateTables。
+// 用户 ID 以字符串保存，时间以 Unix 秒保存。
+type SQLRepository struct {
+	db      *sql.DB
+	q       sqlQuerier
+	inTx    bool
+	options SQLOptions
+}
+
+func NewSQLRepository(db *sql.DB, options SQLOptions) *SQLRepository {
+	return &SQLRepository{db: db, q: db, options: options}
+}
+
+// CreateTables 创建不存在的表
+func (repo *SQLRepository) CreateTables() error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(64) NOT NULL PRIMARY KEY,
+	execute_name VARCHAR(64) NOT NULL,
+	data TEXT NOT NULL,
+	create_time BIGINT NOT NULL,
+	update_time BIGINT NOT NULL
+)`, GetTableName("rule")),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(64) NOT NULL PRIMARY KEY,
+	type INTEGER NOT NULL,
+	description TEXT NOT NULL,
+	rule_name VARCHAR(64) NOT NULL,
+	execute_name VARCHAR(64) NOT NULL,
+	create_time BIGINT NOT NULL,
+	update_time BIGINT NOT NULL
+)`, GetTableName("item")),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	parent VARCHAR(64) NOT NULL,
+	child VARCHAR(64) NOT NULL,
+	PRIMARY KEY (parent, child)
+)`, GetTableName("item-child")),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	item_name VARCHAR(64) NOT NULL,
+	user_id VARCHAR(64) NOT NULL,
+	create_time BIGINT NOT NULL,
+	PRIMARY KEY (item_name, user_id)
+)`, GetTableName("assignment")),
+	}
+	for _, statement := range statements {
+		if _, err := repo.q.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transaction 在数据库事务中执行 fn，fn 返回错误时回滚；在事务内再次调用时直接执行 fn
+func (repo *SQLRepository) Transaction(fn func(repo AuthRepository) error) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		return fn(tx)
+	})
+}
+
+func (repo *SQLRepository) atomic(fn func(tx *SQLRepository) error) error {
+	if repo.inTx {
+		return fn(repo)
+	}
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&SQLRepository{db: repo.db, q: tx, inTx: true, options: repo.options}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind 将 ? 占位符转换为方言的占位符
+func (repo *SQLRepository) rebind(query string) string {
+	if repo.options.Dialect != DialectPostgres {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func (repo *SQLRepository) exec(query string, args ...interface{}) error {
+	_, err := repo.q.Exec(repo.rebind(query), args...)
+	return err
+}
+
+func (repo *SQLRepository) table(key string) string {
+	return GetTableName(key)
+}
+
+func (repo *SQLRepository) encodeUserId(userId interface{}) string {
+	return fmt.Sprint(userId)
+}
+
+func (repo *SQLRepository) decodeUserId(id string) interface{} {
+	if repo.options.UserId != nil {
+		return repo.options.UserId(id)
+	}
+	if n, err := strconv.Atoi(id); err == nil && strconv.Itoa(n) == id {
+		return n
+	}
+	return id
+}
+
+func encodeTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func decodeTime(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+// in 生成 IN 子句的占位符与参数
+func in(names []string) (string, []interface{}) {
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(names)), ","), args
+}
+
+// ---------------------- Items ---------------------------
+
+const sqlItemColumns = "name, type, description, rule_name, execute_name, create_time, update_time"
+
+func (repo *SQLRepository) queryItems(query string, args ...interface{}) ([]Item, error) {
+	rows, err := repo.q.Query(repo.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0)
+	err = scanRows(rows, func() error {
+		var name, description, ruleName, executeName string
+		var itemType int32
+		var createTime, updateTime int64
+		if err := rows.Scan(&name, &itemType, &description, &ruleName, &executeName, &createTime, &updateTime); err != nil {
+			return err
+		}
+		if ItemType(itemType) == RoleType {
+			items = append(items, NewRole(name, description, ruleName, executeName, decodeTime(createTime), decodeTime(updateTime)))
+		} else {
+			items = append(items, NewPermission(name, description, ruleName, executeName, decodeTime(createTime), decodeTime(updateTime)))
+		}
+		return nil
+	})
+	return items, err
+}
+
+func (repo *SQLRepository) AddItem(item Item) error {
+	return repo.exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?)", repo.table("item"), sqlItemColumns),
+		item.GetName(), item.GetType().Value(), item.GetDescription(), item.GetRuleName(), item.GetExecuteName(),
+		encodeTime(item.GetCreateTime()), encodeTime(item.GetUpdateTime()))
+}
+
+func (repo *SQLRepository) GetItem(name string) (Item, error) {
+	items, err := repo.queryItems(fmt.Sprintf("SELECT %s FROM %s WHERE name = ?", sqlItemColumns, repo.table("item")), name)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("item '%s' not found", name)
+	}
+	return items[0], nil
+}
+
+func (repo *SQLRepository) GetItemsByType(itemType ItemType) ([]Item, error) {
+	return repo.queryItems(fmt.Sprintf("SELECT %s FROM %s WHERE type = ? ORDER BY name", sqlItemColumns, repo.table("item")), itemType.Value())
+}
+
+func (repo *SQLRepository) RemoveItemByType(itemType ItemType) error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE type = ?", repo.table("item")), itemType.Value())
+}
+
+func (repo *SQLRepository) FindAllItems() ([]Item, error) {
+	return repo.queryItems(fmt.Sprintf("SELECT %s FROM %s ORDER BY name", sqlItemColumns, repo.table("item")))
+}
+
+func (repo *SQLRepository) GetItemList(t int32, names []string) ([]Item, error) {
+	if len(names) == 0 {
+		return make([]Item, 0), nil
+	}
+	placeholders, args := in(names)
+	return repo.queryItems(fmt.Sprintf("SELECT %s FROM %s WHERE type = ? AND name IN (%s) ORDER BY name", sqlItemColumns, repo.table("item"), placeholders), append([]interface{}{t}, args...)...)
+}
+
+// RemoveItem 同时删除 item 的继承关系与分配
+func (repo *SQLRepository) RemoveItem(name string) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		if err := tx.exec(fmt.Sprintf("DELETE FROM %s WHERE parent = ? OR child = ?", tx.table("item-child")), name, name); err != nil {
+			return err
+		}
+		if err := tx.exec(fmt.Sprintf("DELETE FROM %s WHERE item_name = ?", tx.table("assignment")), name); err != nil {
+			return err
+		}
+		return tx.exec(fmt.Sprintf("DELETE FROM %s WHERE name = ?", tx.table("item")), name)
+	})
+}
+
+// UpdateItem 改名时同步更新继承关系与分配
+func (repo *SQLRepository) UpdateItem(itemName string, item Item) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		if _, err := tx.GetItem(itemName); err != nil {
+			return err
+		}
+		newName := item.GetName()
+		if newName != itemName {
+			if _, err := tx.GetItem(newName); err == nil {
+				return fmt.Errorf("item '%s' already exists", newName)
+			}
+		}
+		err := tx.exec(fmt.Sprintf("UPDATE %s SET name = ?, type = ?, description = ?, rule_name = ?, execute_name = ?, create_time = ?, update_time = ? WHERE name = ?", tx.table("item")),
+			newName, item.GetType().Value(), item.GetDescription(), item.GetRuleName(), item.GetExecuteName(),
+			encodeTime(item.GetCreateTime()), encodeTime(item.GetUpdateTime()), itemName)
+		if err != nil || newName == itemName {
+			return err
+		}
+		if err := tx.exec(fmt.Sprintf("UPDATE %s SET parent = ? WHERE parent = ?", tx.table("item-child")), newName, itemName); err != nil {
+			return err
+		}
+		if err := tx.exec(fmt.Sprintf("UPDATE %s SET child = ? WHERE child = ?", tx.table("item-child")), newName, itemName); err != nil {
+			return err
+		}
+		return tx.exec(fmt.Sprintf("UPDATE %s SET item_name = ? WHERE item_name = ?", tx.table("assignment")), newName, itemName)
+	})
+}
+
+// ---------------------- Rules ---------------------------
+
+func (repo *SQLRepository) queryRules(query string, args ...interface{}) ([]*Rule, error) {
+	rows, err := repo.q.Query(repo.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0)
+	err = scanRows(rows, func() error {
+		rule := &Rule{}
+		var createTime, updateTime int64
+		if err := rows.Scan(&rule.Name, &rule.ExecuteName, &rule.Data, &createTime, &updateTime); err != nil {
+			return err
+		}
+		rule.CreateTime, rule.UpdateTime = decodeTime(createTime), decodeTime(updateTime)
+		rules = append(rules, rule)
+		return nil
+	})
+	return rules, err
+}
+
+func (repo *SQLRepository) AddRule(rule Rule) error {
+	return repo.exec(fmt.Sprintf("INSERT INTO %s (name, execute_name, data, create_time, update_time) VALUES (?, ?, ?, ?, ?)", repo.table("rule")),
+		rule.Name, rule.ExecuteName, rule.Data, encodeTime(rule.CreateTime), encodeTime(rule.UpdateTime))
+}
+
+func (repo *SQLRepository) GetRule(name string) (*Rule, error) {
+	rules, err := repo.queryRules(fmt.Sprintf("SELECT name, execute_name, data, create_time, update_time FROM %s WHERE name = ?", repo.table("rule")), name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rule '%s' not found", name)
+	}
+	return rules[0], nil
+}
+
+func (repo *SQLRepository) GetRules() ([]*Rule, error) {
+	return repo.queryRules(fmt.Sprintf("SELECT name, execute_name, data, create_time, update_time FROM %s ORDER BY name", repo.table("rule")))
+}
+
+// RemoveRule 同时清除引用该规则的 item 的规则名
+func (repo *SQLRepository) RemoveRule(ruleName string) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		if err := tx.exec(fmt.Sprintf("UPDATE %s SET rule_name = '' WHERE rule_name = ?", tx.table("item")), ruleName); err != nil {
+			return err
+		}
+		return tx.exec(fmt.Sprintf("DELETE FROM %s WHERE name = ?", tx.table("rule")), ruleName)
+	})
+}
+
+// UpdateRule 改名时同步更新引用该规则的 item
+func (repo *SQLRepository) UpdateRule(ruleName string, rule Rule) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		if _, err := tx.GetRule(ruleName); err != nil {
+			return err
+		}
+		if rule.Name != ruleName {
+			if _, err := tx.GetRule(rule.Name); err == nil {
+				return fmt.Errorf("rule '%s' already exists", rule.Name)
+			}
+		}
+		err := tx.exec(fmt.Sprintf("UPDATE %s SET name = ?, execute_name = ?, data = ?, create_time = ?, update_time = ? WHERE name = ?", tx.table("rule")),
+			rule.Name, rule.ExecuteName, rule.Data, encodeTime(rule.CreateTime), encodeTime(rule.UpdateTime), ruleName)
+		if err != nil || rule.Name == ruleName {
+			return err
+		}
+		return tx.exec(fmt.Sprintf("UPDATE %s SET rule_name = ? WHERE rule_name = ?", tx.table("item")), rule.Name, ruleName)
+	})
+}
+
+func (repo *SQLRepository) RemoveAllRules() error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		if err := tx.exec(fmt.Sprintf("UPDATE %s SET rule_name = '' WHERE rule_name <> ''", tx.table("item"))); err != nil {
+			return err
+		}
+		return tx.exec(fmt.Sprintf("DELETE FROM %s", tx.table("rule")))
+	})
+}
+
+// ---------------------- Children ---------------------------
+
+func (repo *SQLRepository) queryChildren(query string, args ...interface{}) ([]*ItemChild, error) {
+	rows, err := repo.q.Query(repo.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ItemChild, 0)
+	err = scanRows(rows, func() error {
+		itemChild := &ItemChild{}
+		if err := rows.Scan(&itemChild.Parent, &itemChild.Child); err != nil {
+			return err
+		}
+		list = append(list, itemChild)
+		return nil
+	})
+	return list, err
+}
+
+func (repo *SQLRepository) FindChildrenList() ([]*ItemChild, error) {
+	return repo.queryChildren(fmt.Sprintf("SELECT parent, child FROM %s ORDER BY parent, child", repo.table("item-child")))
+}
+
+func (repo *SQLRepository) FindChildrenFormChild(child string) ([]*ItemChild, error) {
+	return repo.queryChildren(fmt.Sprintf("SELECT parent, child FROM %s WHERE child = ? ORDER BY parent", repo.table("item-child")), child)
+}
+
+func (repo *SQLRepository) AddItemChild(itemChild ItemChild) error {
+	if repo.HasChild(itemChild.Parent, itemChild.Child) {
+		return fmt.Errorf("child '%s' of '%s' already exists", itemChild.Child, itemChild.Parent)
+	}
+	return repo.exec(fmt.Sprintf("INSERT INTO %s (parent, child) VALUES (?, ?)", repo.table("item-child")), itemChild.Parent, itemChild.Child)
+}
+
+func (repo *SQLRepository) RemoveChild(parent string, child string) error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE parent = ? AND child = ?", repo.table("item-child")), parent, child)
+}
+
+func (repo *SQLRepository) RemoveChildren(parent string) error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE parent = ?", repo.table("item-child")), parent)
+}
+
+func (repo *SQLRepository) HasChild(parent string, child string) bool {
+	var n int
+	err := repo.q.QueryRow(repo.rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE parent = ? AND child = ?", repo.table("item-child"))), parent, child).Scan(&n)
+	return err == nil && n > 0
+}
+
+func (repo *SQLRepository) FindChildren(name string) ([]Item, error) {
+	return repo.queryItems(fmt.Sprintf("SELECT i.%s FROM %s i JOIN %s c ON c.child = i.name WHERE c.parent = ? ORDER BY i.name",
+		strings.ReplaceAll(sqlItemColumns, ", ", ", i."), repo.table("item"), repo.table("item-child")), name)
+}
+
+// RemoveChildByNames 删除父级或子级在 names 中的继承关系
+func (repo *SQLRepository) RemoveChildByNames(t ItemType, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	placeholders, args := in(names)
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE parent IN (%s) OR child IN (%s)", repo.table("item-child"), placeholders, placeholders), append(args, args...)...)
+}
+
+// ---------------------- Assignments ---------------------------
+
+func (repo *SQLRepository) queryAssignments(query string, args ...interface{}) ([]*Assignment, error) {
+	rows, err := repo.q.Query(repo.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	assignments := make([]*Assignment, 0)
+	err = scanRows(rows, func() error {
+		var itemName, userId string
+		var createTime int64
+		if err := rows.Scan(&itemName, &userId, &createTime); err != nil {
+			return err
+		}
+		assignments = append(assignments, &Assignment{UserId: repo.decodeUserId(userId), ItemName: itemName, CreateTime: decodeTime(createTime)})
+		return nil
+	})
+	return assignments, err
+}
+
+func (repo *SQLRepository) findItemsByUser(userId interface{}, itemType ItemType) ([]Item, error) {
+	return repo.queryItems(fmt.Sprintf("SELECT i.%s FROM %s i JOIN %s a ON a.item_name = i.name WHERE a.user_id = ? AND i.type = ? ORDER BY i.name",
+		strings.ReplaceAll(sqlItemColumns, ", ", ", i."), repo.table("item"), repo.table("assignment")), repo.encodeUserId(userId), itemType.Value())
+}
+
+func (repo *SQLRepository) FindRolesByUser(userId interface{}) ([]Item, error) {
+	return repo.findItemsByUser(userId, RoleType)
+}
+
+func (repo *SQLRepository) FindPermissionsByUser(userId interface{}) ([]Item, error) {
+	return repo.findItemsByUser(userId, PermissionType)
+}
+
+func (repo *SQLRepository) FindAssignmentsByUser(userId interface{}) ([]*Assignment, error) {
+	return repo.GetAssignments(userId)
+}
+
+func (repo *SQLRepository) Assign(assignment Assignment) error {
+	return repo.exec(fmt.Sprintf("INSERT INTO %s (item_name, user_id, create_time) VALUES (?, ?, ?)", repo.table("assignment")),
+		assignment.ItemName, repo.encodeUserId(assignment.UserId), encodeTime(assignment.CreateTime))
+}
+
+func (repo *SQLRepository) Assigns(assignments ...*Assignment) error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		for _, assignment := range assignments {
+			if err := tx.Assign(*assignment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (repo *SQLRepository) RemoveAssignment(userId interface{}, name string) error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND item_name = ?", repo.table("assignment")), repo.encodeUserId(userId), name)
+}
+
+func (repo *SQLRepository) RemoveAllAssignmentByUser(userId interface{}) error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", repo.table("assignment")), repo.encodeUserId(userId))
+}
+
+func (repo *SQLRepository) RemoveAllAssignments() error {
+	return repo.exec(fmt.Sprintf("DELETE FROM %s", repo.table("assignment")))
+}
+
+func (repo *SQLRepository) RemoveAssignmentByNames(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	placeholders, args := in(names)
+	return repo.exec(fmt.Sprintf("DELETE FROM %s WHERE item_name IN (%s)", repo.table("assignment"), placeholders), args...)
+}
+
+func (repo *SQLRepository) GetAssignment(userId interface{}, name string) (*Assignment, error) {
+	assignments, err := repo.queryAssignments(fmt.Sprintf("SELECT item_name, user_id, create_time FROM %s WHERE user_id = ? AND item_name = ?", repo.table("assignment")), repo.encodeUserId(userId), name)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, fmt.Errorf("assignment '%s' of user %v not found", name, userId)
+	}
+	return assignments[0], nil
+}
+
+func (repo *SQLRepository) GetAssignmentsByItem(name string) ([]*Assignment, error) {
+	return repo.queryAssignments(fmt.Sprintf("SELECT item_name, user_id, create_time FROM %s WHERE item_name = ? ORDER BY user_id", repo.table("assignment")), name)
+}
+
+func (repo *SQLRepository) GetAssignments(userId interface{}) ([]*Assignment, error) {
+	return repo.queryAssignments(fmt.Sprintf("SELECT item_name, user_id, create_time FROM %s WHERE user_id = ? ORDER BY item_name", repo.table("assignment")), repo.encodeUserId(userId))
+}
+
+func (repo *SQLRepository) GetAllAssignment() ([]*Assignment, error) {
+	return repo.queryAssignments(fmt.Sprintf("SELECT item_name, user_id, create_time FROM %s ORDER BY user_id, item_name", repo.table("assignment")))
+}
+
+func (repo *SQLRepository) RemoveAll() error {
+	return repo.atomic(func(tx *SQLRepository) error {
+		for _, key := range []string{"assignment", "item-child", "item", "rule"} {
+			if err := tx.exec(fmt.Sprintf("DELETE FROM %s", tx.table(key))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package gorbac
+
+import "testing"
+
+func TestSQLRepositoryRebind(t *testing.T) {
+	tests := []struct {
+		dialect SQLDialect
+		query   string
+		want    string
+	}{
+		{DialectMySQL, "SELECT a FROM t WHERE x = ? AND y IN (?,?)", "SELECT a FROM t WHERE x = ? AND y IN (?,?)"},
+		{DialectSQLite, "DELETE FROM t WHERE x = ?", "DELETE FROM t WHERE x = ?"},
+		{DialectPostgres, "SELECT a FROM t WHERE x = ? AND y IN (?,?)", "SELECT a FROM t WHERE x = $1 AND y IN ($2,$3)"},
+		{DialectPostgres, "DELETE FROM t", "DELETE FROM t"},
+	}
+	for _, tt := range tests {
+		repo := NewSQLRepository(nil, SQLOptions{Dialect: tt.dialect})
+		if got := repo.rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) with dialect %d = %q, want %q", tt.query, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestSQLRepositoryUserId(t *testing.T) {
+	tests := []struct {
+		options SQLOptions
+		id      string
+		want    interface{}
+	}{
+		{SQLOptions{}, "7", 7},
+		{SQLOptions{}, "007", "007"},
+		{SQLOptions{}, "bob", "bob"},
+		{SQLOptions{UserId: func(id string) interface{} { return "u-" + id }}, "7", "u-7"},
+	}
+	for _, tt := range tests {
+		repo := NewSQLRepository(nil, tt.options)
+		if got := repo.decodeUserId(tt.id); got != tt.want {
+			t.Errorf("decodeUserId(%q) = %#v, want %#v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ var tableNames = map[string]string{
 	"item":       "auth_item",
 	"item-child": "auth_item_child",
 	"assignment": "auth_assignment",
+	"audit":      "auth_audit",
 }
 
 // SetTableName 配置覆盖默认表名
@@ -0,0 +1,300 @@
+package gorbac
+
+import (
+	"context"
+)
+
+// ---------------------- TypedRepository ---------------------------
+
+// TypedRepository 以 U 为用户 ID 类型包装仓库：写入与查询前将用户 ID 转为 U（见 ConvertUserId），
+// 无法转换时返回错误；返回的分配中的用户 ID 同样转为 U，
+// 使 SQL 仓库按字符串还原的 "5" 与调用方的 int64(5) 一致。
+// repo 实现 TransactionalRepository 时，事务内的仓库同样被包装。
+type TypedRepository[U comparable] struct {
+	AuthRepository
+}
+
+// NewTypedRepository 包装 repo，repo 实现 TransactionalRepository 时返回值同样实现
+func NewTypedRepository[U comparable](repo AuthRepository) AuthRepository {
+	typed := &TypedRepository[U]{AuthRepository: repo}
+	if _, ok := repo.(TransactionalRepository); ok {
+		return &typedTransactionalRepository[U]{typed}
+	}
+	return typed
+}
+
+type typedTransactionalRepository[U comparable] struct {
+	*TypedRepository[U]
+}
+
+func (repo *typedTransactionalRepository[U]) Transaction(fn func(repo AuthRepository) error) error {
+	return repo.AuthRepository.(TransactionalRepository).Transaction(func(tx AuthRepository) error {
+		return fn(&TypedRepository[U]{AuthRepository: tx})
+	})
+}
+
+func (repo *TypedRepository[U]) FindRolesByUser(userId interface{}) ([]Item, error) {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return nil, err
+	}
+	return repo.AuthRepository.FindRolesByUser(id)
+}
+
+func (repo *TypedRepository[U]) FindPermissionsByUser(userId interface{}) ([]Item, error) {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return nil, err
+	}
+	return repo.AuthRepository.FindPermissionsByUser(id)
+}
+
+func (repo *TypedRepository[U]) FindAssignmentsByUser(userId interface{}) ([]*Assignment, error) {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return nil, err
+	}
+	return typedAssignments[U](repo.AuthRepository.FindAssignmentsByUser(id))
+}
+
+func (repo *TypedRepository[U]) Assign(assignment Assignment) error {
+	id, err := ConvertUserId[U](assignment.UserId)
+	if err != nil {
+		return err
+	}
+	assignment.UserId = id
+	return repo.AuthRepository.Assign(assignment)
+}
+
+func (repo *TypedRepository[U]) Assigns(assignments ...*Assignment) error {
+	converted := make([]*Assignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		id, err := ConvertUserId[U](assignment.UserId)
+		if err != nil {
+			return err
+		}
+		copied := *assignment
+		copied.UserId = id
+		converted = append(converted, &copied)
+	}
+	return repo.AuthRepository.Assigns(converted...)
+}
+
+func (repo *TypedRepository[U]) RemoveAssignment(userId interface{}, name string) error {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return err
+	}
+	return repo.AuthRepository.RemoveAssignment(id, name)
+}
+
+func (repo *TypedRepository[U]) RemoveAllAssignmentByUser(userId interface{}) error {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return err
+	}
+	return repo.AuthRepository.RemoveAllAssignmentByUser(id)
+}
+
+func (repo *TypedRepository[U]) GetAssignment(userId interface{}, name string) (*Assignment, error) {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := repo.AuthRepository.GetAssignment(id, name)
+	if err != nil || assignment == nil {
+		return assignment, err
+	}
+	copied := *assignment
+	copied.UserId = id
+	return &copied, nil
+}
+
+func (repo *TypedRepository[U]) GetAssignmentsByItem(name string) ([]*Assignment, error) {
+	return typedAssignments[U](repo.AuthRepository.GetAssignmentsByItem(name))
+}
+
+func (repo *TypedRepository[U]) GetAssignments(userId interface{}) ([]*Assignment, error) {
+	id, err := ConvertUserId[U](userId)
+	if err != nil {
+		return nil, err
+	}
+	return typedAssignments[U](repo.AuthRepository.GetAssignments(id))
+}
+
+func (repo *TypedRepository[U]) GetAllAssignment() ([]*Assignment, error) {
+	return typedAssignments[U](repo.AuthRepository.GetAllAssignment())
+}
+
+// typedAssignments 返回用户 ID 转为 U 的副本，无法转换的保持原值
+func typedAssignments[U comparable](assignments []*Assignment, err error) ([]*Assignment, error) {
+	if err != nil {
+		return assignments, err
+	}
+	converted := make([]*Assignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		copied := *assignment
+		if id, err := ConvertUserId[U](assignment.UserId); err == nil {
+			copied.UserId = id
+		}
+		converted = append(converted, &copied)
+	}
+	return converted, nil
+}
+
+// ---------------------- TypedManager ---------------------------
+
+// TypedManager 以 U 为用户 ID 类型的管理器，方法与 DefaultManager 相同，仍满足 AuthManager 与 Access；
+// 以 U 接收用户 ID 的方法在 User(userId) 返回的视图上，以 U 返回用户 ID 的方法为 RoleUsers 与 PermissionUsers。
+// 底层管理器的用户 ID 归一化为 U（见 SetUserIdNormalizer），以 interface{} 调用时 int(5)、"5" 等同样转为 U。
+//
+//	manager := gorbac.NewTypedManager[int64](repo, true)
+//	manager.User(42).CheckAccess(ctx, "posts:edit")
+type TypedManager[U comparable] struct {
+	*DefaultManager
+}
+
+// NewTypedManager 创建管理器，repo 以 NewTypedRepository 包装
+func NewTypedManager[U comparable](repo AuthRepository, cache bool) *TypedManager[U] {
+	return Typed[U](NewDefaultManager(NewTypedRepository[U](repo), cache))
+}
+
+// Typed 返回 manager 的 U 类型视图，并将其用户 ID 归一化为 U。
+// 仓库未经 NewTypedRepository 包装时，仓库返回的用户 ID 仍由 RoleUsers 等方法转换。
+func Typed[U comparable](manager *DefaultManager) *TypedManager[U] {
+	manager.SetUserIdNormalizer(userIdNormalizer[U]())
+	return &TypedManager[U]{DefaultManager: manager}
+}
+
+// User 返回用户 userId 的视图
+func (manager *TypedManager[U]) User(userId U) TypedUser[U] {
+	return TypedUser[U]{manager: manager.DefaultManager, userId: userId}
+}
+
+// RoleUsers 分配了角色的用户，无法转换为 U 的用户 ID 被跳过
+func (manager *TypedManager[U]) RoleUsers(roleName string) []U {
+	return convertUserIds[U](manager.DefaultManager.GetUserIdsByRole(roleName))
+}
+
+// PermissionUsers 拥有权限的用户，无法转换为 U 的用户 ID 被跳过
+func (manager *TypedManager[U]) PermissionUsers(permissionName string) []U {
+	return convertUserIds[U](manager.DefaultManager.GetUserIdsByPermission(permissionName))
+}
+
+// TypedUser TypedManager 中单个用户的视图，各方法即 DefaultManager 中以该用户调用的同名方法
+type TypedUser[U comparable] struct {
+	manager *DefaultManager
+	userId  U
+}
+
+func (user TypedUser[U]) Id() U {
+	return user.userId
+}
+
+func (user TypedUser[U]) CheckAccess(ctx context.Context, permissionName string) bool {
+	return user.manager.CheckAccess(ctx, user.userId, permissionName)
+}
+
+func (user TypedUser[U]) Explain(ctx context.Context, permissionName string) *Decision {
+	return user.manager.Explain(ctx, user.userId, permissionName)
+}
+
+func (user TypedUser[U]) GetRoles() []*Role {
+	return user.manager.GetRolesByUser(user.userId)
+}
+
+func (user TypedUser[U]) GetPermissions() []*Permission {
+	return user.manager.GetPermissionsByUser(user.userId)
+}
+
+func (user TypedUser[U]) Assign(item Item) *Assignment {
+	return user.manager.Assign(item, user.userId)
+}
+
+func (user TypedUser[U]) Assigns(name ...string) []*Assignment {
+	return user.manager.Assigns(user.userId, name...)
+}
+
+func (user TypedUser[U]) Revoke(item Item) bool {
+	return user.manager.Revoke(item, user.userId)
+}
+
+func (user TypedUser[U]) RevokeAll() bool {
+	return user.manager.RevokeAll(user.userId)
+}
+
+func (user TypedUser[U]) RemoveAllAssignments() error {
+	return user.manager.RemoveAllAssignmentByUser(user.userId)
+}
+
+func (user TypedUser[U]) GetAssignment(roleName string) *Assignment {
+	return user.manager.GetAssignment(roleName, user.userId)
+}
+
+func (user TypedUser[U]) GetAssignments() map[string]*Assignment {
+	return user.manager.GetAssignments(user.userId)
+}
+
+// ---------------------- TypedService ---------------------------
+
+// TypedService 以 U 为用户 ID 类型的 RbacService，涉及用户的方法改为接收 U
+type TypedService[U comparable] struct {
+	*RbacService
+	manager *TypedManager[U]
+}
+
+func NewTypedService[U comparable](repos AuthRepository, cache bool) *TypedService[U] {
+	return NewTypedServiceWithManager(NewTypedManager[U](repos, cache))
+}
+
+func NewTypedServiceWithManager[U comparable](manager *TypedManager[U]) *TypedService[U] {
+	return &TypedService[U]{RbacService: NewRbacServiceWithManager(manager.DefaultManager), manager: manager}
+}
+
+// GetTypedManager 返回服务使用的 TypedManager
+func (s TypedService[U]) GetTypedManager() *TypedManager[U] {
+	return s.manager
+}
+
+func (s TypedService[U]) CheckAccess(ctx context.Context, userId U, permission string) bool {
+	return s.GetAuthManager().CheckAccess(ctx, userId, permission)
+}
+
+func (s TypedService[U]) GetRolesByUser(userId U) []*Role {
+	return s.RbacService.GetRolesByUser(userId)
+}
+
+func (s TypedService[U]) GetPermissionsByUser(userId U) []*Permission {
+	return s.RbacService.GetPermissionsByUser(userId)
+}
+
+func (s TypedService[U]) Assign(userId U, name string) bool {
+	return s.RbacService.Assign(userId, name)
+}
+
+func (s TypedService[U]) CleanAssigns(userId U) {
+	s.RbacService.CleanAssigns(userId)
+}
+
+func (s TypedService[U]) Assigns(userId U, names ...string) {
+	s.RbacService.Assigns(userId, names...)
+}
+
+func (s TypedService[U]) Explain(ctx context.Context, userId U, permission string) *Decision {
+	return s.RbacService.Explain(ctx, userId, permission)
+}
+
+// WhoCanUsers 反查拥有权限的用户，返回当前页转换为 U 的用户 ID
+func (s TypedService[U]) WhoCanUsers(ctx context.Context, permission string, options WhoCanOptions) ([]U, *WhoCanResult) {
+	result := s.RbacService.WhoCan(ctx, permission, options)
+	return convertUserIds[U](result.UserIds), result
+}
+
+// WithContext 返回绑定 ctx 的服务，写操作的审计记录取 ctx 中的操作人与元数据
+func (s TypedService[U]) WithContext(ctx context.Context) *TypedService[U] {
+	return &TypedService[U]{RbacService: s.RbacService.WithContext(ctx), manager: s.manager}
+}
+
+func (s TypedService[U]) AuditByUser(userId U, limit int) ([]*AuditEntry, error) {
+	return s.RbacService.AuditByUser(userId, limit)
+}
@@ -0,0 +1,107 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testUserId int64
+
+type testUUID [2]byte
+
+func (id *testUUID) UnmarshalText(text []byte) error {
+	if len(text) != 2 {
+		return fmt.Errorf("invalid id %q", text)
+	}
+	copy(id[:], text)
+	return nil
+}
+
+func TestTypedManagerSatisfiesAuthManager(t *testing.T) {
+	manager := NewTypedManager[int64](NewMemoryRepository(), true)
+	var authManager AuthManager = manager
+	var access Access = manager
+
+	editor := manager.CreateRole("editor")
+	manager.Add(editor)
+	manager.User(5).Assign(editor)
+
+	ctx := context.Background()
+	if !authManager.CheckAccess(ctx, 5, "editor") || !access.CheckAccess(ctx, "5", "editor") {
+		t.Error("interface CheckAccess denied a normalized user id")
+	}
+}
+
+func TestTypedUser(t *testing.T) {
+	ctx := context.Background()
+	manager := NewTypedManager[int64](NewMemoryRepository(), true)
+	editor := manager.CreateRole("editor")
+	edit := manager.CreatePermission("posts:edit")
+	manager.Add(editor)
+	manager.Add(edit)
+	_ = manager.AddChild(editor, edit)
+
+	user := manager.User(42)
+	if user.Id() != 42 {
+		t.Errorf("Id = %d, want 42", user.Id())
+	}
+	if user.Assign(editor) == nil {
+		t.Fatal("Assign failed")
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"CheckAccess", user.CheckAccess(ctx, "posts:edit"), true},
+		{"Explain", user.Explain(ctx, "posts:edit").Allowed, true},
+		{"other user", manager.User(43).CheckAccess(ctx, "posts:edit"), false},
+		{"GetRoles", len(user.GetRoles()), 1},
+		{"GetPermissions", len(user.GetPermissions()), 1},
+		{"GetAssignment", user.GetAssignment("editor").UserId, int64(42)},
+		{"GetAssignments", len(user.GetAssignments()), 1},
+		{"RoleUsers", manager.RoleUsers("editor"), []int64{42}},
+		{"PermissionUsers", manager.PermissionUsers("posts:edit"), []int64{42}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if !user.Revoke(editor) || user.CheckAccess(ctx, "posts:edit") {
+		t.Error("access remains after Revoke")
+	}
+	user.Assigns("editor")
+	if !user.RevokeAll() || len(user.GetAssignments()) != 0 {
+		t.Error("assignments remain after RevokeAll")
+	}
+}
+
+func TestConvertUserId(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func() (interface{}, error)
+		want    interface{}
+		err     bool
+	}{
+		{"int8 from string", func() (interface{}, error) { return ConvertUserId[int8]("12") }, int8(12), false},
+		{"int8 overflow", func() (interface{}, error) { return ConvertUserId[int8](300) }, int8(0), true},
+		{"int8 from float", func() (interface{}, error) { return ConvertUserId[int8](1.5) }, int8(0), true},
+		{"custom int", func() (interface{}, error) { return ConvertUserId[testUserId](int8(3)) }, testUserId(3), false},
+		{"custom int from text", func() (interface{}, error) { return ConvertUserId[testUserId]("x") }, testUserId(0), true},
+		{"uint overflow", func() (interface{}, error) { return ConvertUserId[testUserId](uint64(1 << 63)) }, testUserId(0), true},
+		{"string from int", func() (interface{}, error) { return ConvertUserId[string](42) }, "42", false},
+		{"text unmarshaler", func() (interface{}, error) { return ConvertUserId[testUUID]("ab") }, testUUID{'a', 'b'}, false},
+		{"text unmarshaler error", func() (interface{}, error) { return ConvertUserId[testUUID]("abc") }, testUUID{}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.convert()
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("%s = (%#v, %v), want (%#v, error %v)", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
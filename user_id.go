@@ -0,0 +1,158 @@
+package gorbac
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// NormalizeUserId 将整数形式的用户 ID（各整数类型、整数值的浮点数、整数 json.Number）统一为 int，
+// 与 CheckAccess(ctx, 1, ...) 的常见写法一致；非整数的 json.Number 转为字符串，字符串与其他类型保持不变
+func NormalizeUserId(userId interface{}) interface{} {
+	switch v := userId.(type) {
+	case nil, string:
+		return userId
+	case json.Number:
+		if n, err := v.Int64(); err == nil && int64(int(n)) == n {
+			return int(n)
+		}
+		return v.String()
+	}
+	if reflect.ValueOf(userId).Kind() == reflect.String {
+		return userId
+	}
+	if n, ok := userIdInt64(userId); ok && int64(int(n)) == n {
+		return int(n)
+	}
+	return userId
+}
+
+// NormalizeIntUserId 在 NormalizeUserId 的基础上把十进制整数字符串也转为 int，
+// 使 int(5)、int64(5) 与 "5" 视为同一用户
+func NormalizeIntUserId(userId interface{}) interface{} {
+	if n, ok := userIdInt64(userId); ok && int64(int(n)) == n {
+		return int(n)
+	}
+	return NormalizeUserId(userId)
+}
+
+// NormalizeStringUserId 将用户 ID 统一为字符串，整数形式先经 NormalizeUserId（5.0 记为 "5"）
+func NormalizeStringUserId(userId interface{}) interface{} {
+	if userId == nil {
+		return nil
+	}
+	return fmt.Sprint(NormalizeUserId(userId))
+}
+
+// userIdInt64 解析整数形式的用户 ID：各整数类型、整数值的浮点数、json.Number 与十进制字符串
+func userIdInt64(userId interface{}) (int64, bool) {
+	if number, ok := userId.(json.Number); ok {
+		n, err := number.Int64()
+		return n, err == nil
+	}
+	value := reflect.ValueOf(userId)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := value.Uint(); n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := value.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), true
+		}
+	case reflect.String:
+		n, err := strconv.ParseInt(value.String(), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// ConvertUserId 将用户 ID 转为 U：类型相同时直接返回；U 为整数类型时接受 userIdInt64 支持的形式并检查溢出；
+// U 为字符串类型时按 NormalizeStringUserId 转换；U 实现 encoding.TextUnmarshaler（如 UUID）时从字符串解析
+func ConvertUserId[U comparable](userId interface{}) (U, error) {
+	var id U
+	if typed, ok := userId.(U); ok {
+		return typed, nil
+	}
+	if userId == nil {
+		return id, fmt.Errorf("user id is nil")
+	}
+	if text, ok := userId.(string); ok {
+		if unmarshaler, ok := interface{}(&id).(encoding.TextUnmarshaler); ok {
+			if err := unmarshaler.UnmarshalText([]byte(text)); err != nil {
+				return id, fmt.Errorf("user id %q: %v", text, err)
+			}
+			return id, nil
+		}
+	}
+
+	value := reflect.ValueOf(&id).Elem()
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := userIdInt64(userId); ok && !value.OverflowInt(n) {
+			value.SetInt(n)
+			return id, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := userIdInt64(userId); ok && n >= 0 && !value.OverflowUint(uint64(n)) {
+			value.SetUint(uint64(n))
+			return id, nil
+		}
+	case reflect.String:
+		value.SetString(NormalizeStringUserId(userId).(string))
+		return id, nil
+	}
+	return id, fmt.Errorf("cannot convert user id %v (%T) to %T", userId, userId, id)
+}
+
+// userIdNormalizer 返回转换为 U 的归一化函数，无法转换时保持原值
+func userIdNormalizer[U comparable]() func(userId interface{}) interface{} {
+	return func(userId interface{}) interface{} {
+		if id, err := ConvertUserId[U](userId); err == nil {
+			return id
+		}
+		return userId
+	}
+}
+
+// convertUserIds 将仓库返回的用户 ID 转为 U，无法转换的跳过
+func convertUserIds[U comparable](userIds []interface{}) []U {
+	ids := make([]U, 0, len(userIds))
+	for _, userId := range userIds {
+		if id, err := ConvertUserId[U](userId); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ---------------------- Manager ---------------------------
+
+type userIdNormalizerHolder struct {
+	normalize func(userId interface{}) interface{}
+}
+
+// SetUserIdNormalizer 设置用户 ID 归一化函数（如 NormalizeIntUserId），nil（默认）不做转换。
+// CheckAccess、Explain、用户角色/权限查询、分配的增删查与策略文件中的分配先经过它，
+// GetUserIdsByRole、GetUserIdsByPermission 与 WhoCan 返回的用户 ID 同样归一化，
+// 使 int(5)、int64(5) 与 "5" 共用同一个判定缓存。设置后清空判定缓存。
+func (manager *DefaultManager) SetUserIdNormalizer(normalizer func(userId interface{}) interface{}) {
+	manager.userIdNormalizer.Store(&userIdNormalizerHolder{normalize: normalizer})
+	manager.mu.Lock()
+	manager._checkAccessAssignments = make(map[interface{}]map[string]*Assignment)
+	manager.assignmentVersion++
+	manager.mu.Unlock()
+}
+
+// userId 按 SetUserIdNormalizer 归一化用户 ID
+func (manager *DefaultManager) userId(userId interface{}) interface{} {
+	if holder, ok := manager.userIdNormalizer.Load().(*userIdNormalizerHolder); ok && holder.normalize != nil {
+		return holder.normalize(userId)
+	}
+	return userId
+}
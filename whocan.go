@@ -0,0 +1,84 @@
+package gorbac
+
+import (
+	"context"
+)
+
+// WhoCanOptions 反查拥有权限的用户时的选项
+type WhoCanOptions struct {
+	// EvaluateRules 为 true 时对每个候选用户按 ctx 执行 CheckAccess（规则参数见 WithParams），只保留校验通过的用户
+	EvaluateRules bool
+	// Offset 与 Limit 对结果分页，Limit <= 0 表示不限
+	Offset int
+	Limit  int
+}
+
+// WhoCanResult 反查结果
+type WhoCanResult struct {
+	Permission string `json:"permission"`
+	// Everyone 权限可经默认角色获得，未出现在 UserIds 中的用户同样拥有该权限
+	Everyone bool `json:"everyone"`
+	// Items 可授予该权限的 item，包括权限本身及其所有祖先
+	Items []string `json:"items"`
+	// UserIds 当前页的用户，按 Items 的顺序收集并去重
+	UserIds []interface{} `json:"user_ids"`
+	// Total 分页前的用户总数
+	Total int `json:"total"`
+}
+
+// GetUserIdsByPermission 返回经分配或继承可获得权限的所有用户，不执行规则
+func (manager *DefaultManager) GetUserIdsByPermission(permissionName string) []interface{} {
+	return manager.WhoCan(context.Background(), permissionName, WhoCanOptions{}).UserIds
+}
+
+// WhoCan 沿父级关系收集权限的所有祖先，返回分配到这些 item 的用户
+func (manager *DefaultManager) WhoCan(ctx context.Context, permissionName string, options WhoCanOptions) *WhoCanResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result := &WhoCanResult{Permission: permissionName, Items: make([]string, 0), UserIds: make([]interface{}, 0)}
+
+	snapshot := manager.loadFromCache()
+	if manager.lookupItem(snapshot, permissionName) == nil {
+		return result
+	}
+	result.Items = manager.collectAncestors(snapshot, permissionName)
+
+	users := make([]interface{}, 0)
+	seen := make(map[interface{}]bool)
+	for _, name := range result.Items {
+		if snapshot.defaultRoles[name] != nil {
+			result.Everyone = true
+		}
+		assignments, err := manager.mapper.GetAssignmentsByItem(name)
+		if err != nil {
+			manager.log().Error("[rbac] get assignments by item failed", "item", name, "err", err)
+			continue
+		}
+		for _, assignment := range assignments {
+			userId := manager.userId(assignment.UserId)
+			if seen[userId] {
+				continue
+			}
+			seen[userId] = true
+			if options.EvaluateRules && !manager.CheckAccess(ctx, userId, permissionName) {
+				continue
+			}
+			users = append(users, userId)
+		}
+	}
+
+	result.Total = len(users)
+	if options.Offset > 0 {
+		if options.Offset >= len(users) {
+			users = users[:0]
+		} else {
+			users = users[options.Offset:]
+		}
+	}
+	if options.Limit > 0 && options.Limit < len(users) {
+		users = users[:options.Limit]
+	}
+	result.UserIds = users
+	return result
+}
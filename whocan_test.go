@@ -0,0 +1,94 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func sortedUserIds(userIds []interface{}) []string {
+	ids := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		ids = append(ids, fmt.Sprint(userId))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestWhoCan(t *testing.T) {
+	manager := newTestManager(t, true)
+	manager.Assign(manager.GetRole("editor"), 2)
+	manager.Assign(manager.GetRole("viewer"), 3)
+	manager.Assign(manager.GetPermission("posts:edit"), 4)
+	// 经 admin 与 posts:edit 两条路径获得，只出现一次
+	manager.Assign(manager.GetPermission("posts:edit"), 1)
+
+	tests := []struct {
+		name       string
+		permission string
+		options    WhoCanOptions
+		users      []string
+		total      int
+		items      int
+	}{
+		{"edit", "posts:edit", WhoCanOptions{}, []string{"1", "2", "4"}, 3, 3},
+		{"view", "posts:view", WhoCanOptions{}, []string{"1", "3"}, 2, 3},
+		{"role", "editor", WhoCanOptions{}, []string{"1", "2"}, 2, 2},
+		{"limit", "posts:edit", WhoCanOptions{Limit: 2}, nil, 3, 3},
+		{"offset past end", "posts:edit", WhoCanOptions{Offset: 5}, []string{}, 3, 3},
+		{"unknown", "posts:delete", WhoCanOptions{}, []string{}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := manager.WhoCan(context.Background(), tt.permission, tt.options)
+			if result.Total != tt.total || len(result.Items) != tt.items {
+				t.Errorf("total = %d, items = %v, want %d and %d items", result.Total, result.Items, tt.total, tt.items)
+			}
+			if tt.options.Limit > 0 {
+				if len(result.UserIds) != tt.options.Limit {
+					t.Errorf("page = %v, want %d users", result.UserIds, tt.options.Limit)
+				}
+				return
+			}
+			if got := sortedUserIds(result.UserIds); fmt.Sprint(got) != fmt.Sprint(tt.users) {
+				t.Errorf("users = %v, want %v", got, tt.users)
+			}
+		})
+	}
+
+	if got := sortedUserIds(manager.GetUserIdsByPermission("posts:view")); fmt.Sprint(got) != "[1 3]" {
+		t.Errorf("GetUserIdsByPermission = %v, want [1 3]", got)
+	}
+}
+
+func TestWhoCanEveryoneAndRules(t *testing.T) {
+	now := time.Now()
+	manager := newTestManager(t, true)
+	manager.SetDefaultRoles(manager.GetRole("viewer"))
+	if result := manager.WhoCan(context.Background(), "posts:view", WhoCanOptions{}); !result.Everyone {
+		t.Error("posts:view via default role, want Everyone")
+	}
+	if result := manager.WhoCan(context.Background(), "posts:edit", WhoCanOptions{}); result.Everyone {
+		t.Error("posts:edit not via default role, want !Everyone")
+	}
+
+	rules := newRuleManager(t, true, NewExpressionRule("check", "params.owner_id == user.id", now, now))
+	rules.Assign(rules.GetPermission("doc:read"), 2)
+	ctx := WithParams(context.Background(), map[string]interface{}{"owner_id": 2})
+	tests := []struct {
+		name     string
+		evaluate bool
+		users    string
+	}{
+		{"assignments only", false, "[1 2]"},
+		{"evaluate rules", true, "[2]"},
+	}
+	for _, tt := range tests {
+		result := rules.WhoCan(ctx, "doc:read", WhoCanOptions{EvaluateRules: tt.evaluate})
+		if got := fmt.Sprint(sortedUserIds(result.UserIds)); got != tt.users {
+			t.Errorf("%s: users = %s, want %s", tt.name, got, tt.users)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package gorbac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Yii2 RBAC 数据与 gorbac 的对应关系：
+//
+//	PhpManager  items.php / assignments.php / rules.php
+//	DbManager   auth_item / auth_item_child / auth_assignment / auth_rule（表名取 GetTableName）
+//
+// 规则对象按 PHP 类名映射到执行器，除 name、createdAt、updatedAt 外的属性以 JSON 存入 Rule.Data；
+// 未映射的类以类名作为执行器名，未注册同名执行器前该规则拒绝访问（Lint 报告 unregistered-executor）。
+//
+// Yii2 在分配与继承关系可达的前提下再执行 item 的规则，gorbac 则以规则结果代替分配判定：
+// 规则通过即允许，未分配也一样。带 ruleName 的 item 照原样导入，并逐个列入 Issues，导入后需人工复核。
+type Yii2Options struct {
+	// Executors PHP 规则类名（如 "app\rbac\AuthorRule"）到执行器名的映射
+	Executors map[string]string
+	// UserId 转换用户 ID，默认十进制整数转为 int，其余保持字符串
+	UserId func(id string) interface{}
+}
+
+// Yii2Issue 无法无损转换的内容
+type Yii2Issue struct {
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+func (issue Yii2Issue) String() string {
+	return fmt.Sprintf("%s: %s", issue.Subject, issue.Message)
+}
+
+// Yii2Data 从 Yii2 读取的 RBAC 数据，通过 Import 写入仓库
+type Yii2Data struct {
+	Rules       []*Rule
+	Items       []Item
+	Children    []*ItemChild
+	Assignments []*Assignment
+	Issues      []Yii2Issue
+}
+
+func (options Yii2Options) userId(id string) interface{} {
+	if options.UserId != nil {
+		return options.UserId(id)
+	}
+	if n, err := strconv.Atoi(id); err == nil && strconv.Itoa(n) == id {
+		return n
+	}
+	return id
+}
+
+func (data *Yii2Data) issue(subject string, format string, args ...interface{}) {
+	data.Issues = append(data.Issues, Yii2Issue{Subject: subject, Message: fmt.Sprintf(format, args...)})
+}
+
+// LoadYii2PhpFiles 读取 dir 下 PhpManager 的 items.php、assignments.php 与 rules.php，缺少的文件视为空；
+// PhpManager 不保存时间戳，以文件修改时间作为创建与更新时间。
+func LoadYii2PhpFiles(dir string, options Yii2Options) (*Yii2Data, error) {
+	var sources [3]string
+	var times [3]time.Time
+	for i, name := range []string{"items.php", "assignments.php", "rules.php"} {
+		path := filepath.Join(dir, name)
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = string(content)
+		if info, err := os.Stat(path); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return parseYii2Php(sources, times, options)
+}
+
+// ParseYii2PhpFiles 解析 PhpManager 三个文件的内容，空内容视为空文件；创建与更新时间取当前时间
+func ParseYii2PhpFiles(items []byte, assignments []byte, rules []byte, options Yii2Options) (*Yii2Data, error) {
+	now := time.Now()
+	return parseYii2Php([3]string{string(items), string(assignments), string(rules)}, [3]time.Time{now, now, now}, options)
+}
+
+func parseYii2Php(sources [3]string, times [3]time.Time, options Yii2Options) (*Yii2Data, error) {
+	var arrays [3]*phpArray
+	for i, name := range []string{"items.php", "assignments.php", "rules.php"} {
+		arrays[i] = &phpArray{}
+		if strings.TrimSpace(sources[i]) == "" {
+			continue
+		}
+		value, err := parsePHPReturn(sources[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if value == nil {
+			continue
+		}
+		array, ok := value.(*phpArray)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an array", name)
+		}
+		arrays[i] = array
+	}
+
+	data := &Yii2Data{}
+	for i, key := range arrays[2].keys {
+		name := fmt.Sprint(key)
+		serialized, ok := arrays[2].values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("rules.php: rule '%s' is not a serialized string", name)
+		}
+		rule, err := data.rule(name, serialized, times[2], times[2], options)
+		if err != nil {
+			return nil, fmt.Errorf("rules.php: %v", err)
+		}
+		data.Rules = append(data.Rules, rule)
+	}
+
+	for i, key := range arrays[0].keys {
+		name := fmt.Sprint(key)
+		entry, ok := arrays[0].values[i].(*phpArray)
+		if !ok {
+			return nil, fmt.Errorf("items.php: item '%s' is not an array", name)
+		}
+		itemType, _ := entry.get("type").(int64)
+		description, _ := entry.get("description").(string)
+		ruleName, _ := entry.get("ruleName").(string)
+		if entry.get("data") != nil {
+			data.issue(name, "item data is not supported and was dropped")
+		}
+		item, err := data.item(name, itemType, description, ruleName, times[0], times[0])
+		if err != nil {
+			return nil, fmt.Errorf("items.php: %v", err)
+		}
+		data.Items = append(data.Items, item)
+		if children, ok := entry.get("children").(*phpArray); ok {
+			for _, child := range children.values {
+				data.Children = append(data.Children, NewItemChild(name, fmt.Sprint(child)))
+			}
+		}
+	}
+
+	for i, key := range arrays[1].keys {
+		userId := options.userId(fmt.Sprint(key))
+		names, ok := arrays[1].values[i].(*phpArray)
+		if !ok {
+			return nil, fmt.Errorf("assignments.php: assignments of user %v are not an array", userId)
+		}
+		for _, name := range names.values {
+			data.Assignments = append(data.Assignments, &Assignment{UserId: userId, ItemName: fmt.Sprint(name), CreateTime: times[1]})
+		}
+	}
+	return data, nil
+}
+
+// LoadYii2Database 读取 DbManager 的四张表
+func LoadYii2Database(db *sql.DB, options Yii2Options) (*Yii2Data, error) {
+	data := &Yii2Data{}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT name, data, created_at, updated_at FROM %s ORDER BY name", GetTableName("rule")))
+	if err != nil {
+		return nil, fmt.Errorf("query rules: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		var name string
+		var serialized []byte
+		var createdAt, updatedAt sql.NullInt64
+		if err := rows.Scan(&name, &serialized, &createdAt, &updatedAt); err != nil {
+			return err
+		}
+		rule, err := data.rule(name, string(serialized), unixTime(createdAt), unixTime(updatedAt), options)
+		if err != nil {
+			return err
+		}
+		data.Rules = append(data.Rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %v", err)
+	}
+
+	rows, err = db.Query(fmt.Sprintf("SELECT name, type, description, rule_name, data, created_at, updated_at FROM %s ORDER BY name", GetTableName("item")))
+	if err != nil {
+		return nil, fmt.Errorf("query items: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		var name string
+		var itemType int64
+		var description, ruleName sql.NullString
+		var serialized []byte
+		var createdAt, updatedAt sql.NullInt64
+		if err := rows.Scan(&name, &itemType, &description, &ruleName, &serialized, &createdAt, &updatedAt); err != nil {
+			return err
+		}
+		if len(serialized) > 0 {
+			if value, err := phpUnserialize(string(serialized)); err != nil || value != nil {
+				data.issue(name, "item data is not supported and was dropped")
+			}
+		}
+		item, err := data.item(name, itemType, description.String, ruleName.String, unixTime(createdAt), unixTime(updatedAt))
+		if err != nil {
+			return err
+		}
+		data.Items = append(data.Items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read items: %v", err)
+	}
+
+	rows, err = db.Query(fmt.Sprintf("SELECT parent, child FROM %s ORDER BY parent, child", GetTableName("item-child")))
+	if err != nil {
+		return nil, fmt.Errorf("query children: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		child := &ItemChild{}
+		if err := rows.Scan(&child.Parent, &child.Child); err != nil {
+			return err
+		}
+		data.Children = append(data.Children, child)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read children: %v", err)
+	}
+
+	rows, err = db.Query(fmt.Sprintf("SELECT item_name, user_id, created_at FROM %s ORDER BY user_id, item_name", GetTableName("assignment")))
+	if err != nil {
+		return nil, fmt.Errorf("query assignments: %v", err)
+	}
+	err = scanRows(rows, func() error {
+		var itemName, userId string
+		var createdAt sql.NullInt64
+		if err := rows.Scan(&itemName, &userId, &createdAt); err != nil {
+			return err
+		}
+		data.Assignments = append(data.Assignments, &Assignment{UserId: options.userId(userId), ItemName: itemName, CreateTime: unixTime(createdAt)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read assignments: %v", err)
+	}
+	return data, nil
+}
+
+func scanRows(rows *sql.Rows, scan func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func unixTime(value sql.NullInt64) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+	return time.Unix(value.Int64, 0)
+}
+
+func (data *Yii2Data) item(name string, itemType int64, description string, ruleName string, createTime time.Time, updateTime time.Time) (Item, error) {
+	if ruleName != "" {
+		data.issue(name, "Yii2 checks rule '%s' in addition to the assignment, gorbac allows whenever the rule passes even without an assignment", ruleName)
+	}
+	switch ItemType(itemType) {
+	case RoleType:
+		return NewRole(name, description, ruleName, "", createTime, updateTime), nil
+	case PermissionType:
+		return NewPermission(name, description, ruleName, "", createTime, updateTime), nil
+	}
+	return nil, fmt.Errorf("item '%s' has unknown type %d", name, itemType)
+}
+
+// rule 反序列化规则对象并按类名映射执行器
+func (data *Yii2Data) rule(name string, serialized string, createTime time.Time, updateTime time.Time, options Yii2Options) (*Rule, error) {
+	value, err := phpUnserialize(serialized)
+	if err != nil {
+		return nil, fmt.Errorf("rule '%s': %v", name, err)
+	}
+	object, ok := value.(*phpObject)
+	if !ok {
+		return nil, fmt.Errorf("rule '%s' is not a serialized object", name)
+	}
+
+	class := strings.TrimPrefix(object.class, `\`)
+	executeName, ok := options.Executors[class]
+	if !ok {
+		executeName = class
+		data.issue(name, "rule class %s has no mapped executor, register an executor named '%s'", class, class)
+	}
+
+	props := make(map[string]interface{})
+	for i, key := range object.props.keys {
+		prop := fmt.Sprint(key)
+		switch prop {
+		case "name", "createdAt", "updatedAt":
+			continue
+		}
+		props[prop] = phpNative(object.props.values[i])
+	}
+	rule := &Rule{Name: name, ExecuteName: executeName, CreateTime: createTime, UpdateTime: updateTime}
+	if len(props) > 0 {
+		encoded, err := json.Marshal(props)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s' data: %v", name, err)
+		}
+		rule.Data = string(encoded)
+	}
+	// 序列化对象自带的时间戳优先于表中的列（PhpManager 无时间戳列）
+	if t, ok := object.props.get("createdAt").(int64); ok {
+		rule.CreateTime = time.Unix(t, 0)
+	}
+	if t, ok := object.props.get("updatedAt").(int64); ok {
+		rule.UpdateTime = time.Unix(t, 0)
+	}
+	return rule, nil
+}
+
+// Import 校验后写入仓库，与 ImportSnapshot 相同：检查引用与继承环，
+// 仓库实现 TransactionalRepository 时在一个事务中写入。
+func (data *Yii2Data) Import(repo AuthRepository, options ImportOptions) (*SnapshotStats, error) {
+	snapshot := &snapshotData{
+		rules:       data.Rules,
+		items:       data.Items,
+		children:    data.Children,
+		assignments: data.Assignments,
+		stats: &SnapshotStats{
+			Rules:       len(data.Rules),
+			Items:       len(data.Items),
+			Children:    len(data.Children),
+			Assignments: len(data.Assignments),
+		},
+	}
+	return snapshot.importTo(repo, options)
+}
+
+// ImportYii2 向管理器所用仓库导入 Yii2 数据并清除缓存
+func (manager *DefaultManager) ImportYii2(data *Yii2Data, options ImportOptions) (*SnapshotStats, error) {
+	return manager.importYii2(context.Background(), data, options)
+}
+
+func (manager *DefaultManager) importYii2(ctx context.Context, data *Yii2Data, options ImportOptions) (*SnapshotStats, error) {
+	stats, err := data.Import(manager.mapper, options)
+	manager.resetAllCache()
+	if err == nil {
+		manager.audit(ctx, AuditEntry{Operation: AuditImportYii2}, nil, stats)
+	}
+	return stats, err
+}
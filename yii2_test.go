@@ -0,0 +1,174 @@
+package gorbac
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testYii2Items = `<?php
+return [
+    'createPost' => [
+        'type' => 2,
+        'description' => 'Create a post',
+    ],
+    'updateOwnPost' => [
+        'type' => 2,
+        'description' => 'Update own post',
+        'ruleName' => 'isAuthor',
+        'children' => [
+            'updatePost',
+        ],
+    ],
+    'updatePost' => array('type' => 2, 'description' => "Update \"post\"\n",),
+    'author' => [
+        'type' => 1,
+        'data' => ['x' => 1.5],
+        'children' => ['createPost', 'updateOwnPost'],
+    ],
+    'admin' => ['type' => 1, 'children' => ['author', 'updatePost']], // comment
+];
+`
+
+const testYii2Assignments = "<?php\nreturn [\n    1 => ['admin'],\n    'bob' => ['author'],\n    '007' => ['author'],\n];\n"
+
+// testYii2Rules rules.php，isAuthor 为序列化的 app\rbac\AuthorRule 对象，带一个 protected 属性
+func testYii2Rules() string {
+	serialized := `O:19:"app\rbac\AuthorRule":4:{s:4:"name";s:8:"isAuthor";s:9:"createdAt";i:1600000000;s:9:"updatedAt";i:1600000001;s:8:"` + "\x00*\x00" + `limit";i:3;}`
+	escaped := strings.ReplaceAll(strings.ReplaceAll(serialized, `\`, `\\`), "'", `\'`)
+	return "<?php\nreturn [\n    'isAuthor' => '" + escaped + "',\n];\n"
+}
+
+func TestParseYii2PhpFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Yii2Options
+		executor string
+		issues   []string
+	}{
+		{
+			name:     "unmapped rule class",
+			executor: `app\rbac\AuthorRule`,
+			issues: []string{
+				`author: item data is not supported and was dropped`,
+				`isAuthor: rule class app\rbac\AuthorRule has no mapped executor, register an executor named 'app\rbac\AuthorRule'`,
+				`updateOwnPost: Yii2 checks rule 'isAuthor' in addition to the assignment, gorbac allows whenever the rule passes even without an assignment`,
+			},
+		},
+		{
+			name:     "mapped rule class",
+			options:  Yii2Options{Executors: map[string]string{`app\rbac\AuthorRule`: "author"}},
+			executor: "author",
+			issues: []string{
+				`author: item data is not supported and was dropped`,
+				`updateOwnPost: Yii2 checks rule 'isAuthor' in addition to the assignment, gorbac allows whenever the rule passes even without an assignment`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseYii2PhpFiles([]byte(testYii2Items), []byte(testYii2Assignments), []byte(testYii2Rules()), tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(data.Rules) != 1 {
+				t.Fatalf("rules = %+v, want one rule", data.Rules)
+			}
+			rule := data.Rules[0]
+			if rule.Name != "isAuthor" || rule.ExecuteName != tt.executor || rule.Data != `{"limit":3}` || rule.CreateTime.Unix() != 1600000000 {
+				t.Errorf("rule = %+v", rule)
+			}
+
+			items := make(map[string]Item)
+			for _, item := range data.Items {
+				items[item.GetName()] = item
+			}
+			if len(items) != 5 || items["admin"].GetType() != RoleType || items["createPost"].GetType() != PermissionType {
+				t.Errorf("items = %v", items)
+			}
+			if got := items["updatePost"].GetDescription(); got != "Update \"post\"\n" {
+				t.Errorf("updatePost description = %q", got)
+			}
+			if got := items["updateOwnPost"].GetRuleName(); got != "isAuthor" {
+				t.Errorf("updateOwnPost rule = %q, want isAuthor", got)
+			}
+			if len(data.Children) != 5 {
+				t.Errorf("children = %d, want 5", len(data.Children))
+			}
+
+			assignments := make([]string, 0)
+			for _, assignment := range data.Assignments {
+				assignments = append(assignments, strings.Join([]string{reflect.TypeOf(assignment.UserId).String(), assignment.ItemName}, " "))
+			}
+			if want := []string{"int admin", "string author", "string author"}; !reflect.DeepEqual(assignments, want) {
+				t.Errorf("assignments = %q, want %q", assignments, want)
+			}
+
+			issues := make([]string, 0)
+			for _, issue := range data.Issues {
+				issues = append(issues, issue.String())
+			}
+			sort.Strings(issues)
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues = %q, want %q", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestParseYii2PhpFilesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		items string
+		err   string
+	}{
+		{"unterminated array", "<?php return [ 'a' => ", "items.php"},
+		{"not an array", "<?php return 'x';", "expected an array"},
+		{"item not an array", "<?php return ['a' => 1];", "item 'a' is not an array"},
+		{"unknown type", "<?php return ['a' => ['type' => 3]];", "unknown type 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYii2PhpFiles([]byte(tt.items), nil, nil, Yii2Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseYii2PhpFiles error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestImportYii2(t *testing.T) {
+	data, err := ParseYii2PhpFiles([]byte(testYii2Items), []byte(testYii2Assignments), []byte(testYii2Rules()), Yii2Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewDefaultManager(NewMemoryRepository(), false)
+	stats, err := manager.ImportYii2(data, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SnapshotStats{Rules: 1, Items: 5, Children: 5, Assignments: 3}); *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		userId     interface{}
+		permission string
+		allowed    bool
+	}{
+		{1, "updatePost", true},
+		{1, "createPost", true},
+		{"bob", "createPost", true},
+		{"bob", "updatePost", false},
+		{2, "createPost", false},
+	}
+	for _, tt := range tests {
+		if got := manager.CheckAccess(ctx, tt.userId, tt.permission); got != tt.allowed {
+			t.Errorf("CheckAccess(%v, %s) = %v, want %v", tt.userId, tt.permission, got, tt.allowed)
+		}
+	}
+}